@@ -0,0 +1,222 @@
+package main
+
+import (
+	"aggregator/internal/access"
+	"aggregator/internal/aggregatorpb"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// accessMiddleware authenticates requests by API key and limits the request
+// rate of every client. Requests without a key get the anonymous tier, with
+// a quota per client address; requests with an unknown key are rejected.
+func accessMiddleware(config *access.Config, limiter *access.Limiter, proxies trustedProxies, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, tier := "address:"+proxies.clientAddr(r), access.AnonymousTier
+		if apiKey := requestApiKey(r); apiKey != "" {
+			key, ok := config.Authenticate(apiKey)
+			if !ok {
+				writeProblem(w, r, problem{Status: http.StatusUnauthorized, Code: codeInvalidApiKey, Detail: "Unknown API key"})
+				return
+			}
+			client, tier = "key:"+key.Hash, key.Tier
+		}
+
+		d := limiter.Allow(client, config.Tiers[tier])
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		w.Header().Set("X-RateLimit-Reset", ceilSeconds(d.Reset))
+		if !d.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(d.RetryAfter))
+			writeProblem(w, r, problem{
+				Status: http.StatusTooManyRequests,
+				Code:   codeRateLimited,
+				Detail: fmt.Sprintf("Request rate of the %s tier exceeded, retry in %s seconds", tier, ceilSeconds(d.RetryAfter)),
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestApiKey returns the API key sent in the X-API-Key header or as a
+// bearer token.
+func requestApiKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// grpcAccess authenticates the calls of the Aggregator gRPC service and
+// limits their rate like accessMiddleware does. Keys are sent in the
+// x-api-key or authorization metadata. Health and reflection calls are let
+// through.
+func grpcAccess(ctx context.Context, fullMethod string, config *access.Config, limiter *access.Limiter) error {
+	if !strings.HasPrefix(fullMethod, "/"+aggregatorpb.Aggregator_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+	client, tier := "address:"+grpcClientAddr(ctx), access.AnonymousTier
+	if apiKey := grpcApiKey(ctx); apiKey != "" {
+		key, ok := config.Authenticate(apiKey)
+		if !ok {
+			return status.Error(codes.Unauthenticated, "Unknown API key")
+		}
+		client, tier = "key:"+key.Hash, key.Tier
+	}
+
+	d := limiter.Allow(client, config.Tiers[tier])
+	header := metadata.Pairs(
+		"x-ratelimit-limit", strconv.Itoa(d.Limit),
+		"x-ratelimit-remaining", strconv.Itoa(d.Remaining),
+		"x-ratelimit-reset", ceilSeconds(d.Reset),
+	)
+	if !d.Allowed {
+		header.Set("retry-after", ceilSeconds(d.RetryAfter))
+	}
+	if err := grpc.SetHeader(ctx, header); err != nil {
+		slog.Debug("Setting rate limit headers failed", "error", err)
+	}
+	if !d.Allowed {
+		return status.Errorf(codes.ResourceExhausted, "Request rate of the %s tier exceeded, retry in %s seconds", tier, ceilSeconds(d.RetryAfter))
+	}
+	return nil
+}
+
+func unaryAccessInterceptor(config *access.Config, limiter *access.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := grpcAccess(ctx, info.FullMethod, config, limiter); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamAccessInterceptor limits the rate streams are opened at. Messages of
+// open streams are not limited.
+func streamAccessInterceptor(config *access.Config, limiter *access.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := grpcAccess(ss.Context(), info.FullMethod, config, limiter); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// grpcApiKey returns the API key sent in the x-api-key metadata or as a
+// bearer token.
+func grpcApiKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(apiKeyHeader); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// grpcClientAddr returns the address of the peer of a call. gRPC clients
+// connect directly, so no proxies are taken into account.
+func grpcClientAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	addr, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return addr
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// trustedProxies are the proxies, such as the nginx of the frontend, whose
+// X-Forwarded-For header names the client.
+type trustedProxies []netip.Prefix
+
+// trustedProxiesFromEnv reads the comma separated addresses and CIDR ranges
+// of TRUSTED_PROXIES.
+func trustedProxiesFromEnv() (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, v := range splitQueryList(os.Getenv("TRUSTED_PROXIES")) {
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(v)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry: %s", v)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix)
+	}
+	return proxies, nil
+}
+
+func (p trustedProxies) trusts(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	return slices.ContainsFunc(p, func(prefix netip.Prefix) bool { return prefix.Contains(ip) })
+}
+
+// clientAddr returns the address of the client. Going from the connection
+// back through X-Forwarded-For, it is the first address that is not a
+// trusted proxy.
+func (p trustedProxies) clientAddr(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	forwarded := splitQueryList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+	for i := len(forwarded) - 1; i >= 0 && p.trusts(addr); i-- {
+		addr = forwarded[i]
+	}
+	return addr
+}
+
+// corsOrigins are the origins allowed to call the API from a browser, set by
+// CORS_ALLOWED_ORIGINS. An empty list or "*" allows any origin.
+type corsOrigins []string
+
+func corsOriginsFromEnv() corsOrigins {
+	return corsOrigins(splitQueryList(os.Getenv("CORS_ALLOWED_ORIGINS")))
+}
+
+func (o corsOrigins) allowOrigin(origin string) string {
+	if len(o) == 0 || slices.Contains(o, "*") {
+		return "*"
+	}
+	if slices.Contains(o, origin) {
+		return origin
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"aggregator/internal/access"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAccessConfig(t *testing.T) *access.Config {
+	path := filepath.Join(t.TempDir(), "access.json")
+	os.WriteFile(path, []byte(`{
+		"tiers": {"anonymous": {"requestsPerMinute": 1, "burst": 1}, "standard": {"requestsPerMinute": 60, "burst": 3}},
+		"keys": [
+			{"name": "frontend", "sha256": "`+access.HashKey("secret")+`", "tier": "standard"},
+			{"name": "ops", "sha256": "`+access.HashKey("root")+`", "tier": "standard", "admin": true}
+		]
+	}`), 0o644)
+	config, err := access.LoadConfig(path)
+	assert.NoError(t, err)
+	return config
+}
+
+func TestAccessMiddleware(t *testing.T) {
+	handler := requestIdMiddleware(accessMiddleware(testAccessConfig(t), access.NewLimiter(), nil,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })))
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.Header{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("X-RateLimit-Reset"))
+
+	w = serve(http.Header{})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	var p problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, codeRateLimited, p.Code)
+	assert.NotEmpty(t, p.RequestId)
+
+	w = serve(http.Header{"X-Api-Key": {"secret"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	w = serve(http.Header{"Authorization": {"Bearer secret"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = serve(http.Header{"X-Api-Key": {"guess"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, codeInvalidApiKey, p.Code)
+}
+
+func TestClientAddr(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+	proxies, err := trustedProxiesFromEnv()
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.168.1.1:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 5.6.7.8, 10.1.1.1")
+	assert.Equal(t, "5.6.7.8", proxies.clientAddr(r))
+
+	r.RemoteAddr = "8.8.8.8:5000"
+	assert.Equal(t, "8.8.8.8", proxies.clientAddr(r))
+
+	t.Setenv("TRUSTED_PROXIES", "nginx")
+	_, err = trustedProxiesFromEnv()
+	assert.EqualError(t, err, "invalid TRUSTED_PROXIES entry: nginx")
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := corsMiddleware(corsOrigins{"https://atmo.example"}, next)
+
+	r := httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("Origin", "https://atmo.example")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "https://atmo.example", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "X-RateLimit-Remaining")
+
+	r.Header.Set("Origin", "https://other.example")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	r = httptest.NewRequest(http.MethodOptions, "/aggregatedData", nil)
+	r.Header.Set("Origin", "https://atmo.example")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), apiKeyHeader)
+
+	w = httptest.NewRecorder()
+	corsMiddleware(nil, next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"aggregator/internal/access"
+	"aggregator/internal/aggregator"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// logLevel is the level of the default logger, which can be changed at
+// runtime through the admin API.
+var logLevel slog.LevelVar
+
+// setLogLevel sets the level of the default logger.
+func setLogLevel(level slog.Level) {
+	logLevel.Set(level)
+}
+
+// logLevelFromEnv installs the default logger with the level of logLevel and
+// sets the initial level from LOG_LEVEL.
+func logLevelFromEnv() error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel})))
+	value := os.Getenv("LOG_LEVEL")
+	if value == "" {
+		return nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %s", value)
+	}
+	setLogLevel(level)
+	return nil
+}
+
+// requireAdmin serves only requests sent with an admin API key.
+func requireAdmin(config *access.Config, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := requestApiKey(r)
+		if apiKey == "" {
+			writeProblem(w, r, problem{Status: http.StatusUnauthorized, Code: codeInvalidApiKey, Detail: "The admin API needs an API key"})
+			return
+		}
+		key, ok := config.Authenticate(apiKey)
+		if !ok || !key.Admin {
+			writeProblem(w, r, problem{Status: http.StatusForbidden, Code: codeForbidden, Detail: "The API key has no admin access"})
+			return
+		}
+		slog.Info("Admin request", "key", key.Name, "method", r.Method, "path", r.URL.Path)
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		writeError(w, r, err, "Encoding json response failed")
+	}
+}
+
+func getAdminStatus(rs *regionServices) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sets := rs.regionSets()
+		statuses := make([]aggregator.Status, 0, len(sets))
+		for _, set := range sets {
+			statuses = append(statuses, rs.services[set.Id].Status())
+		}
+		writeJSON(w, r, statuses)
+	}
+}
+
+func postRefresh(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		if err := service.Refresh(ctx); err != nil {
+			writeError(w, r, err, "Refreshing stations failed")
+			return
+		}
+		writeJSON(w, r, service.Status())
+	}
+}
+
+// deleteCaches drops the station and parameter lists shared by the region
+// sets and the measurements fetched by a collection in progress.
+func deleteCaches(rs *regionServices) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rs.sources.DropCaches()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// postRebuildHistory replaces the hourly history with the one the sources
+// still hold. As older hours are lost, the set id has to be repeated in the
+// confirm query parameter.
+func postRebuildHistory(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		set := service.RegionSet().Id
+		if r.URL.Query().Get("confirm") != set {
+			writeBadRequest(w, r, "Rebuilding drops the hours the sources no longer hold; confirm with ?confirm="+set)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
+		defer cancel()
+
+		if err := service.RebuildHistory(ctx); err != nil {
+			writeError(w, r, err, "Rebuilding history failed")
+			return
+		}
+		writeJSON(w, r, service.Status())
+	}
+}
+
+func getAssignments(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assignments, err := service.StationAssignments()
+		if err != nil {
+			writeError(w, r, err, "Listing station assignments failed")
+			return
+		}
+		writeJSON(w, r, assignments)
+	}
+}
+
+type logLevelBody struct {
+	Level string `json:"level"`
+}
+
+func getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, logLevelBody{Level: logLevel.Level().String()})
+}
+
+func putLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body logLevelBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&body); err != nil {
+		writeBadRequest(w, r, "Invalid request body: "+err.Error())
+		return
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+		writeBadRequest(w, r, "Unknown log level: "+body.Level)
+		return
+	}
+	previous := logLevel.Level()
+	setLogLevel(level)
+	slog.Warn("Log level changed", "from", previous, "to", level)
+	writeJSON(w, r, logLevelBody{Level: level.String()})
+}
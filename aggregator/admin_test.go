@@ -0,0 +1,73 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	config := testAccessConfig(t)
+	handler := requireAdmin(config, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	serve := func(key string) int {
+		r := httptest.NewRequest(http.MethodGet, "/admin/status", nil)
+		if key != "" {
+			r.Header.Set(apiKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(""))
+	assert.Equal(t, http.StatusForbidden, serve("secret"))
+	assert.Equal(t, http.StatusForbidden, serve("guess"))
+	assert.Equal(t, http.StatusNoContent, serve("root"))
+}
+
+func TestLogLevel(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer setLogLevel(slog.LevelInfo)
+	assert.NoError(t, logLevelFromEnv())
+	assert.False(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+
+	w := httptest.NewRecorder()
+	putLogLevel(w, httptest.NewRequest(http.MethodPut, "/admin/logLevel", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+
+	w = httptest.NewRecorder()
+	getLogLevel(w, httptest.NewRequest(http.MethodGet, "/admin/logLevel", nil))
+	assert.JSONEq(t, `{"level":"DEBUG"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	putLogLevel(w, httptest.NewRequest(http.MethodPut, "/admin/logLevel", strings.NewReader(`{"level":"verbose"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown log level: verbose")
+}
+
+func TestRebuildHistoryNeedsConfirmation(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	service, err := aggregator.NewService(t.Context(), aggregator.NewSources(), api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{api.Malopolskie: {}}))
+	assert.NoError(t, err)
+	handler := postRebuildHistory(service)
+
+	for _, target := range []string{"/admin/regionSets/pl/history/rebuild", "/admin/regionSets/pl/history/rebuild?confirm=de"} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "confirm with ?confirm=pl")
+	}
+}
+
+func TestDeleteCaches(t *testing.T) {
+	w := httptest.NewRecorder()
+	deleteCaches(&regionServices{sources: aggregator.NewSources()})(w, httptest.NewRequest(http.MethodDelete, "/admin/caches", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
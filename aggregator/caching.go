@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// measurementRefreshInterval mirrors the schedule of the upstream services,
+// which both pull new measurements at the top of every hour.
+const measurementRefreshInterval = time.Hour
+
+// writeCachedJSON writes v as JSON with validators derived from its content.
+// Conditional requests (If-None-Match, If-Modified-Since) are answered with
+// 304 Not Modified by http.ServeContent. Range requests are served the whole
+// document, as a fragment of JSON is of no use.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v any, lastModified time.Time) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding json response: %w", err)
+	}
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(body))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge(now)))
+	if lastModified.After(now) {
+		lastModified = now
+	}
+	r.Header.Del("Range")
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+	return nil
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// maxAge returns the number of seconds until the upstream services are
+// expected to have new measurements.
+func maxAge(now time.Time) int {
+	next := now.Truncate(measurementRefreshInterval).Add(measurementRefreshInterval)
+	return max(int(next.Sub(now).Seconds()), 1)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCachedJSON(t *testing.T) {
+	lastModified := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	body := map[string]string{"voivodeship": "malopolskie"}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	assert.NoError(t, writeCachedJSON(w, r, body, lastModified))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, lastModified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+	assert.Contains(t, w.Header().Get("Cache-Control"), "max-age=")
+	assert.JSONEq(t, `{"voivodeship":"malopolskie"}`, w.Body.String())
+	tag := w.Header().Get("ETag")
+	assert.NotEmpty(t, tag)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("If-None-Match", tag)
+	assert.NoError(t, writeCachedJSON(w, r, body, lastModified))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("Range", "bytes=0-4")
+	r.Header.Set("If-Range", tag)
+	assert.NoError(t, writeCachedJSON(w, r, body, lastModified))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"voivodeship":"malopolskie"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+	assert.NoError(t, writeCachedJSON(w, r, body, lastModified))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("If-None-Match", `"stale"`)
+	assert.NoError(t, writeCachedJSON(w, r, map[string]string{"voivodeship": "slaskie"}, lastModified))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, tag, w.Header().Get("ETag"))
+}
+
+func TestMaxAge(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 45, 0, 0, time.UTC)
+	assert.Equal(t, 15*60, maxAge(now))
+	assert.Equal(t, 60*60, maxAge(now.Truncate(time.Hour)))
+}
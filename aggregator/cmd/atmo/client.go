@@ -0,0 +1,127 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultURL = "http://localhost:8082"
+
+// errNotModified is returned by a conditional request when the data hasn't
+// changed since the given ETag.
+var errNotModified = errors.New("not modified")
+
+// client queries the aggregator HTTP API. Without a region set it uses the
+// routes of the aggregator's default set.
+type client struct {
+	baseURL   string
+	regionSet string
+	apiKey    string
+	http      *http.Client
+}
+
+func newClient(baseURL, regionSet string) *client {
+	return &client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		regionSet: regionSet,
+		http:      &http.Client{Timeout: 90 * time.Second},
+	}
+}
+
+// path returns the path of a region set resource, namespaced by the region
+// set if there is one and the legacy path otherwise.
+func (c *client) path(legacy, namespaced string) string {
+	if c.regionSet == "" {
+		return legacy
+	}
+	return "/regionSets/" + url.PathEscape(c.regionSet) + namespaced
+}
+
+// problem holds the fields of an aggregator error response shown to the user.
+type problem struct {
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+	RequestId string `json:"requestId"`
+}
+
+// get decodes the JSON response of the path into v and returns its ETag. When
+// etag is set and the data hasn't changed errNotModified is returned.
+func (c *client) get(ctx context.Context, path string, query url.Values, etag string, v any) (string, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request %s: %w", u, err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	response, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request %s failed: %w", u, err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotModified:
+		return etag, errNotModified
+	case response.StatusCode != http.StatusOK:
+		var p problem
+		if json.NewDecoder(response.Body).Decode(&p) != nil || p.Detail == "" {
+			return "", fmt.Errorf("request %s returned status %d", u, response.StatusCode)
+		}
+		return "", fmt.Errorf("%s: %s (request ID %s)", p.Title, p.Detail, p.RequestId)
+	}
+	if err = json.NewDecoder(response.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("decoding response of %s: %w", u, err)
+	}
+	return response.Header.Get("ETag"), nil
+}
+
+func paramsQuery(params []string) url.Values {
+	query := url.Values{}
+	if len(params) > 0 {
+		query.Set("params", strings.Join(params, ","))
+	}
+	return query
+}
+
+// aggregatedData returns the aggregated values of the regions, all of them
+// when none are given.
+func (c *client) aggregatedData(ctx context.Context, regions []string, params []string) ([]api.AggregatedData, error) {
+	query := paramsQuery(params)
+	if len(regions) > 0 {
+		query.Set("regions", strings.Join(regions, ","))
+	}
+	var results []api.AggregatedData
+	_, err := c.get(ctx, c.path("/aggregatedData", "/aggregatedData"), query, "", &results)
+	return results, err
+}
+
+// aggregatedDataFor returns the aggregated values of one region. It is a
+// conditional request when etag is set.
+func (c *client) aggregatedDataFor(ctx context.Context, region string, params []string, etag string) (api.AggregatedData, string, error) {
+	var result api.AggregatedData
+	path := "/aggregatedData/" + url.PathEscape(region)
+	etag, err := c.get(ctx, c.path(path, path), paramsQuery(params), etag, &result)
+	return result, etag, err
+}
+
+func (c *client) stations(ctx context.Context, region string) ([]api.Station, error) {
+	var stations []api.Station
+	path := c.path("/voivodeships/"+url.PathEscape(region)+"/stations", "/regions/"+url.PathEscape(region)+"/stations")
+	_, err := c.get(ctx, path, nil, "", &stations)
+	return stations, err
+}
@@ -0,0 +1,245 @@
+// Command atmo queries the atmo-check aggregator from the terminal.
+package main
+
+import (
+	"aggregator/internal/api"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const usage = `usage: atmo [-url URL] [-set ID] [-key KEY] command [flags]
+
+The aggregator URL defaults to $ATMO_URL or ` + defaultURL + `. The region
+set defaults to $ATMO_REGION_SET or the default set of the aggregator. The
+API key defaults to $ATMO_API_KEY; without one the anonymous quota applies.
+
+commands:
+  summary [-params LIST] [-format table|json|csv] [-threshold LIST] [REGION...]
+        show the aggregated values of regions, all by default
+  watch [-params LIST] [-interval DURATION] [-count N] [-format table|json|csv] [-threshold LIST] REGION
+        print the values of a region whenever they change
+  stations [-format table|json|csv] REGION
+        list the stations of a region
+
+A threshold LIST such as PM10=50,NO2=200 makes atmo exit with status 1 as
+soon as a value exceeds its threshold. Other errors exit with status 2.`
+
+var (
+	errUsage             = errors.New(usage)
+	errThresholdExceeded = errors.New("threshold exceeded")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errThresholdExceeded) {
+			os.Exit(1)
+		}
+		os.Exit(2)
+	}
+}
+
+// run runs the command line and writes its output to out.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("atmo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	baseURL := fs.String("url", os.Getenv("ATMO_URL"), "aggregator URL")
+	regionSet := fs.String("set", os.Getenv("ATMO_REGION_SET"), "region set")
+	apiKey := fs.String("key", os.Getenv("ATMO_API_KEY"), "API key")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+	if *baseURL == "" {
+		*baseURL = defaultURL
+	}
+	c := newClient(*baseURL, *regionSet)
+	c.apiKey = *apiKey
+
+	command, args := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "summary":
+		return runSummary(ctx, c, args, out)
+	case "watch":
+		return runWatch(ctx, c, args, out)
+	case "stations":
+		return runStations(ctx, c, args, out)
+	default:
+		return errUsage
+	}
+}
+
+// commandFlags holds the flags shared by the commands.
+type commandFlags struct {
+	fs        *flag.FlagSet
+	format    *string
+	params    *string
+	threshold *string
+}
+
+func newCommandFlags(name string) commandFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return commandFlags{
+		fs:     fs,
+		format: fs.String("format", formatTable, "output format: table, json or csv"),
+	}
+}
+
+func (f *commandFlags) withSelection() {
+	f.params = f.fs.String("params", "", "comma separated parameters, all by default")
+	f.threshold = f.fs.String("threshold", "", "comma separated PARAM=VALUE thresholds")
+}
+
+func (f commandFlags) parse(args []string) error {
+	if err := f.fs.Parse(args); err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	return validFormat(*f.format)
+}
+
+func runSummary(ctx context.Context, c *client, args []string, out io.Writer) error {
+	f := newCommandFlags("summary")
+	f.withSelection()
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	thresholds, err := parseThresholds(*f.threshold)
+	if err != nil {
+		return err
+	}
+	results, err := c.aggregatedData(ctx, f.fs.Args(), splitList(*f.params))
+	if err != nil {
+		return err
+	}
+	if err = writeSummaries(out, *f.format, results, true); err != nil {
+		return err
+	}
+	return thresholds.check(results)
+}
+
+func runWatch(ctx context.Context, c *client, args []string, out io.Writer) error {
+	f := newCommandFlags("watch")
+	f.withSelection()
+	interval := f.fs.Duration("interval", 5*time.Minute, "polling interval")
+	count := f.fs.Int("count", 0, "stop after this many updates, never by default")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	thresholds, err := parseThresholds(*f.threshold)
+	if err != nil {
+		return err
+	}
+	if f.fs.NArg() != 1 {
+		return errUsage
+	}
+	region := f.fs.Arg(0)
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval: %s", *interval)
+	}
+
+	params := splitList(*f.params)
+	var etag string
+	for updates := 0; ; {
+		result, newEtag, err := c.aggregatedDataFor(ctx, region, params, etag)
+		switch {
+		case errors.Is(err, errNotModified):
+		case err != nil:
+			return err
+		default:
+			etag = newEtag
+			results := []api.AggregatedData{result}
+			if err = writeSummaries(out, *f.format, results, updates == 0 || *f.format == formatTable); err != nil {
+				return err
+			}
+			if *f.format == formatTable {
+				fmt.Fprintln(out)
+			}
+			if err = thresholds.check(results); err != nil {
+				return err
+			}
+			updates++
+			if *count != 0 && updates == *count {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+func runStations(ctx context.Context, c *client, args []string, out io.Writer) error {
+	f := newCommandFlags("stations")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+	if f.fs.NArg() != 1 {
+		return errUsage
+	}
+	stations, err := c.stations(ctx, f.fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeStations(out, *f.format, stations)
+}
+
+// thresholds maps upper-cased parameter names to the values they may not exceed.
+type thresholds map[string]float32
+
+func parseThresholds(value string) (thresholds, error) {
+	t := make(thresholds)
+	for _, entry := range splitList(value) {
+		name, limit, ok := strings.Cut(entry, "=")
+		parsed, err := strconv.ParseFloat(limit, 32)
+		if !ok || name == "" || err != nil {
+			return nil, fmt.Errorf("invalid threshold: %s", entry)
+		}
+		t[strings.ToUpper(name)] = float32(parsed)
+	}
+	return t, nil
+}
+
+// check returns errThresholdExceeded listing every value above its threshold.
+func (t thresholds) check(results []api.AggregatedData) error {
+	var exceeded []string
+	for _, r := range results {
+		for _, p := range r.Parameters {
+			limit, exists := t[strings.ToUpper(string(p.Type))]
+			if exists && p.Value != nil && *p.Value > limit {
+				exceeded = append(exceeded, fmt.Sprintf("%s %s %s > %g", r.Region, p.Type, formatValue(p.Value, ""), limit))
+			}
+		}
+	}
+	if len(exceeded) > 0 {
+		return fmt.Errorf("%w: %s", errThresholdExceeded, strings.Join(exceeded, ", "))
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(t *testing.T) *httptest.Server {
+	value := float32(62.5)
+	malopolskie := api.AggregatedData{
+		Region: api.Malopolskie,
+		Hour:   "2025-10-01T10:00:00Z",
+		Parameters: []api.Parameter{
+			{Type: api.PM10, Unit: "µg/m³", Value: &value, Status: api.Available,
+				Changes: []api.Change{{Period: "1h", Trend: api.Rising}}},
+			{Type: api.NO2, Unit: "µg/m³", Status: api.NoData},
+		},
+	}
+	lastMeasurement := time.Date(2025, 10, 1, 10, 15, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/aggregatedData", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "malopolskie", r.URL.Query().Get("regions"))
+		json.NewEncoder(w).Encode([]api.AggregatedData{malopolskie})
+	})
+	mux.HandleFunc("/aggregatedData/{region}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("region") != "malopolskie" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"title":"Bad Request","detail":"Unknown region: mars","requestId":"abc"}`))
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(malopolskie)
+	})
+	mux.HandleFunc("/voivodeships/malopolskie/stations", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]api.Station{
+			{Source: api.OpenAq, Id: 7, Name: "Tarnów", Locality: "Tarnów", Parameters: []api.ParamType{api.PM10, api.NO2}, LastMeasurement: &lastMeasurement},
+		})
+	})
+	mux.HandleFunc("/regionSets/de/regions/bayern/stations", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		json.NewEncoder(w).Encode([]api.Station{{Source: api.OpenMeteo, Id: 3, Name: "München"}})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSummary(t *testing.T) {
+	server := testServer(t)
+
+	var out bytes.Buffer
+	err := run(t.Context(), []string{"-url", server.URL, "summary", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	assert.Regexp(t, `malopolskie\s+PM10\s+62.5\s+µg/m³\s+available\s+rising\s+2025-10-01T10:00:00Z`, out.String())
+	assert.Regexp(t, `malopolskie\s+NO2\s+-\s+µg/m³\s+no_data\s+-`, out.String())
+
+	out.Reset()
+	err = run(t.Context(), []string{"-url", server.URL, "summary", "-format", "csv", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "region,parameter,value,unit,status,trend,hour\n"+
+		"malopolskie,PM10,62.5,µg/m³,available,rising,2025-10-01T10:00:00Z\n"+
+		"malopolskie,NO2,,µg/m³,no_data,,2025-10-01T10:00:00Z\n", out.String())
+
+	out.Reset()
+	err = run(t.Context(), []string{"-url", server.URL, "summary", "-format", "json", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	var results []api.AggregatedData
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+	assert.Equal(t, api.Malopolskie, results[0].Region)
+}
+
+func TestSummaryThreshold(t *testing.T) {
+	server := testServer(t)
+
+	var out bytes.Buffer
+	err := run(t.Context(), []string{"-url", server.URL, "summary", "-threshold", "pm10=50,NO2=10", "malopolskie"}, &out)
+	assert.ErrorIs(t, err, errThresholdExceeded)
+	assert.ErrorContains(t, err, "malopolskie PM10 62.5 > 50")
+	assert.NotContains(t, err.Error(), "NO2")
+
+	err = run(t.Context(), []string{"-url", server.URL, "summary", "-threshold", "PM10=100", "malopolskie"}, &out)
+	assert.NoError(t, err)
+
+	err = run(t.Context(), []string{"-url", server.URL, "summary", "-threshold", "PM10", "malopolskie"}, &out)
+	assert.ErrorContains(t, err, "invalid threshold: PM10")
+}
+
+func TestWatch(t *testing.T) {
+	server := testServer(t)
+
+	var out bytes.Buffer
+	err := run(t.Context(), []string{"-url", server.URL, "watch", "-interval", "1ms", "-count", "1", "-format", "csv", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(out.String(), "\n"))
+
+	err = run(t.Context(), []string{"-url", server.URL, "watch", "-threshold", "PM10=50", "malopolskie"}, &out)
+	assert.ErrorIs(t, err, errThresholdExceeded)
+
+	err = run(t.Context(), []string{"-url", server.URL, "watch", "mars"}, &out)
+	assert.EqualError(t, err, "Bad Request: Unknown region: mars (request ID abc)")
+}
+
+func TestStations(t *testing.T) {
+	server := testServer(t)
+
+	var out bytes.Buffer
+	err := run(t.Context(), []string{"-url", server.URL, "stations", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	assert.Regexp(t, `openaq\s+7\s+Tarnów\s+Tarnów\s+PM10,NO2\s+2025-10-01T10:15:00Z`, out.String())
+
+	out.Reset()
+	err = run(t.Context(), []string{"-url", server.URL, "stations", "-format", "csv", "malopolskie"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "openaq,7,Tarnów,Tarnów,0,0,PM10 NO2,2025-10-01T10:15:00Z")
+
+	out.Reset()
+	err = run(t.Context(), []string{"-url", server.URL, "-set", "de", "-key", "secret", "stations", "bayern"}, &out)
+	assert.NoError(t, err)
+	assert.Regexp(t, `openmeteo\s+3\s+München`, out.String())
+}
+
+func TestClientProblem(t *testing.T) {
+	server := testServer(t)
+
+	_, _, err := newClient(server.URL, "").aggregatedDataFor(t.Context(), "mars", nil, "")
+	assert.EqualError(t, err, "Bad Request: Unknown region: mars (request ID abc)")
+}
+
+func TestUsage(t *testing.T) {
+	var out bytes.Buffer
+	assert.ErrorIs(t, run(t.Context(), nil, &out), errUsage)
+	assert.ErrorIs(t, run(t.Context(), []string{"forecast"}, &out), errUsage)
+	assert.ErrorContains(t, run(t.Context(), []string{"stations", "-format", "xml", "malopolskie"}, &out), "unknown format")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+func validFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// writeSummaries writes the aggregated values in the format. The header is
+// left out when header is false, so that watch can append rows.
+func writeSummaries(out io.Writer, format string, results []api.AggregatedData, header bool) error {
+	switch format {
+	case formatJSON:
+		if !header {
+			// Watch prints one document per line, so the output can be streamed.
+			return json.NewEncoder(out).Encode(results)
+		}
+		return writeJSON(out, results)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		if header {
+			w.Write([]string{"region", "parameter", "value", "unit", "status", "trend", "hour"})
+		}
+		for _, r := range results {
+			for _, p := range r.Parameters {
+				w.Write([]string{string(r.Region), string(p.Type), formatValue(p.Value, ""), p.Unit, string(p.Status), hourlyTrend(p, ""), r.Hour})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		if header {
+			fmt.Fprintln(tw, "REGION\tPARAMETER\tVALUE\tUNIT\tSTATUS\tTREND\tHOUR")
+		}
+		for _, r := range results {
+			for _, p := range r.Parameters {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					r.Region, p.Type, formatValue(p.Value, "-"), p.Unit, p.Status, hourlyTrend(p, "-"), orDash(r.Hour))
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+func writeStations(out io.Writer, format string, stations []api.Station) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(out, stations)
+	case formatCSV:
+		w := csv.NewWriter(out)
+		w.Write([]string{"source", "id", "name", "locality", "latitude", "longitude", "parameters", "lastMeasurement"})
+		for _, s := range stations {
+			w.Write([]string{string(s.Source), strconv.Itoa(s.Id), s.Name, s.Locality,
+				strconv.FormatFloat(s.Latitude, 'f', -1, 64), strconv.FormatFloat(s.Longitude, 'f', -1, 64),
+				joinParams(s.Parameters, " "), formatTime(s.LastMeasurement, "")})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SOURCE\tID\tNAME\tLOCALITY\tPARAMETERS\tLAST MEASUREMENT")
+		for _, s := range stations {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+				s.Source, s.Id, s.Name, orDash(s.Locality), joinParams(s.Parameters, ","), formatTime(s.LastMeasurement, "-"))
+		}
+		return tw.Flush()
+	}
+}
+
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatValue(value *float32, missing string) string {
+	if value == nil {
+		return missing
+	}
+	return strconv.FormatFloat(float64(*value), 'f', -1, 32)
+}
+
+// hourlyTrend returns the trend of the parameter over the last hour.
+func hourlyTrend(p api.Parameter, missing string) string {
+	for _, c := range p.Changes {
+		if c.Period == "1h" {
+			return string(c.Trend)
+		}
+	}
+	return missing
+}
+
+func formatTime(t *time.Time, missing string) string {
+	if t == nil {
+		return missing
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func joinParams(params []api.ParamType, sep string) string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = string(p)
+	}
+	return strings.Join(names, sep)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
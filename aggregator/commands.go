@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+const usage = `usage: aggregator [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  report exceedances [-set ID] [-year YEAR] [-regions LIST] [-format text|json]
+        count the days each limit value was exceeded on in a year
+  validate regions [-set ID] [-stations] [-fail-on info|warning|error|none] [-format text|json]
+        check the region boundaries for overlaps, uncovered areas and, with
+        -stations, for stations assigned to several regions or to none`
+
+var errUsage = errors.New(usage)
+
+// runCommand runs a command line subcommand and writes its output to out.
+func runCommand(args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	switch args[0] + " " + args[1] {
+	case "report exceedances":
+		return runReportExceedances(args[2:], out)
+	case "validate regions":
+		return runValidateRegions(args[2:], out)
+	default:
+		return errUsage
+	}
+}
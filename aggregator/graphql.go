@@ -0,0 +1,52 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/gql"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// maxGraphQLBody is the size limit of a GraphQL request body.
+const maxGraphQLBody = 64 << 10
+
+// serveGraphQL runs GraphQL queries sent as a JSON body with POST, or as the
+// query, operationName and variables parameters with GET. Errors of the
+// query itself are reported in the GraphQL response, not as a problem.
+func serveGraphQL(executor *gql.Executor) func(*aggregator.Service) http.HandlerFunc {
+	return func(service *aggregator.Service) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var req gql.Request
+			switch r.Method {
+			case http.MethodGet:
+				query := r.URL.Query()
+				req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+				if v := query.Get("variables"); v != "" {
+					if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+						writeBadRequest(w, r, "Invalid variables: "+err.Error())
+						return
+					}
+				}
+			case http.MethodPost:
+				if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+					writeProblem(w, r, problem{Status: http.StatusUnsupportedMediaType, Code: codeInvalidRequest, Detail: "GraphQL requests have to be sent as application/json"})
+					return
+				}
+				if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody)).Decode(&req); err != nil {
+					writeBadRequest(w, r, "Invalid request body: "+err.Error())
+					return
+				}
+			default:
+				w.Header().Set("Allow", "GET, POST")
+				writeProblem(w, r, problem{Status: http.StatusMethodNotAllowed, Code: codeInvalidRequest, Detail: "GraphQL requests have to use GET or POST"})
+				return
+			}
+			if strings.TrimSpace(req.Query) == "" {
+				writeBadRequest(w, r, "Missing GraphQL query")
+				return
+			}
+			writeJSON(w, r, executor.Execute(r.Context(), service, requestCatalog(r.Context()), req))
+		}
+	}
+}
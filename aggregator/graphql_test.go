@@ -0,0 +1,48 @@
+package main
+
+import (
+	"aggregator/internal/gql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeGraphQL(t *testing.T) {
+	executor, err := gql.NewExecutor(gql.Limits{MaxDepth: 6, MaxCost: 2000})
+	assert.NoError(t, err)
+	handler := serveGraphQL(executor)(nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "query Name { __typename }", "operationName": "Name"}`))
+	r.Header.Set("Content-Type", "application/json")
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"__typename": "Query"}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{"query": {"{ unknown }"}}.Encode(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `Cannot query field \"unknown\" on type \"Query\"`)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ __typename }"}`)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/graphql?variables=%7B", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Missing GraphQL query")
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodDelete, "/graphql", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+}
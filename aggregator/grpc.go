@@ -0,0 +1,397 @@
+package main
+
+import (
+	"aggregator/internal/access"
+	"aggregator/internal/aggregator"
+	"aggregator/internal/aggregatorpb"
+	"aggregator/internal/api"
+	"aggregator/internal/apiclient"
+	"aggregator/internal/locale"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcAddrFromEnv returns the address of the gRPC server, set by GRPC_ADDR.
+func grpcAddrFromEnv() string {
+	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
+		return addr
+	}
+	return ":9090"
+}
+
+// aggregatorServer serves the Aggregator gRPC service over the region set
+// services.
+type aggregatorServer struct {
+	aggregatorpb.UnimplementedAggregatorServer
+	services *regionServices
+	catalogs *locale.Catalogs
+	feeds    map[string]*updateFeed
+}
+
+func newAggregatorServer(rs *regionServices, catalogs *locale.Catalogs, interval time.Duration) *aggregatorServer {
+	s := &aggregatorServer{services: rs, catalogs: catalogs, feeds: make(map[string]*updateFeed, len(rs.services))}
+	for id, service := range rs.services {
+		s.feeds[id] = newUpdateFeed(service, interval)
+	}
+	return s
+}
+
+func (s *aggregatorServer) AggregateAll(ctx context.Context, req *aggregatorpb.AggregateAllRequest) (*aggregatorpb.AggregateAllResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	service, err := s.service(req.GetRegionSet())
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.catalog(ctx, req.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+	regions, err := grpcRegions(service.RegionSet(), req.GetVoivodeships())
+	if err != nil {
+		return nil, err
+	}
+	opts, err := grpcOptions(service.Params(), req.GetParams(), req.GetMerge(), req.GetHour())
+	if err != nil {
+		return nil, err
+	}
+	results, err := service.AggregateAll(ctx, regions, opts)
+	if err != nil {
+		return nil, grpcError(err, "Aggregating data failed")
+	}
+	response := &aggregatorpb.AggregateAllResponse{Data: make([]*aggregatorpb.AggregatedData, 0, len(results))}
+	for i := range results {
+		localizeData(catalog, &results[i])
+		response.Data = append(response.Data, protoData(results[i]))
+	}
+	return response, nil
+}
+
+func (s *aggregatorServer) AggregateForVoivodeship(ctx context.Context, req *aggregatorpb.AggregateForVoivodeshipRequest) (*aggregatorpb.AggregatedData, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	service, err := s.service(req.GetRegionSet())
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.catalog(ctx, req.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+	region, err := service.RegionSet().Region(req.GetVoivodeship())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Unknown region: "+req.GetVoivodeship())
+	}
+	opts, err := grpcOptions(service.Params(), req.GetParams(), req.GetMerge(), req.GetHour())
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.AggregateForRegion(ctx, region, opts)
+	if err != nil {
+		return nil, grpcError(err, "Aggregating data for region failed")
+	}
+	localizeData(catalog, &result)
+	return protoData(result), nil
+}
+
+// StreamUpdates sends the latest data of the selected regions and then the
+// data of every region whose data changed since it was last sent, until the
+// client cancels the stream.
+func (s *aggregatorServer) StreamUpdates(req *aggregatorpb.StreamUpdatesRequest, stream grpc.ServerStreamingServer[aggregatorpb.AggregatedData]) error {
+	ctx := stream.Context()
+	service, err := s.service(req.GetRegionSet())
+	if err != nil {
+		return err
+	}
+	catalog, err := s.catalog(ctx, req.GetLanguage())
+	if err != nil {
+		return err
+	}
+	regions, err := grpcRegions(service.RegionSet(), req.GetVoivodeships())
+	if err != nil {
+		return err
+	}
+	opts, err := grpcOptions(service.Params(), req.GetParams(), aggregatorpb.MergeStrategy_MERGE_STRATEGY_UNSPECIFIED, nil)
+	if err != nil {
+		return err
+	}
+
+	updates, unsubscribe := s.feeds[service.RegionSet().Id].subscribe()
+	defer unsubscribe()
+	sent := make(map[api.Region][]api.Parameter)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case results := <-updates:
+			for _, result := range results {
+				if len(regions) > 0 && !slices.Contains(regions, result.Region) {
+					continue
+				}
+				// The results are shared by all streams of the feed.
+				result.Parameters = slices.Clone(result.Parameters)
+				if len(opts.Params) > 0 {
+					result.KeepParams(opts.Params)
+				}
+				if previous, exists := sent[result.Region]; exists && sameValues(previous, result.Parameters) {
+					continue
+				}
+				values := slices.Clone(result.Parameters)
+				localizeData(catalog, &result)
+				if err := stream.Send(protoData(result)); err != nil {
+					return err
+				}
+				sent[result.Region] = values
+			}
+		}
+	}
+}
+
+// sameValues reports whether the parameters have the same values and
+// statuses.
+func sameValues(a, b []api.Parameter) bool {
+	return slices.EqualFunc(a, b, func(p, q api.Parameter) bool {
+		if p.Type != q.Type || p.Status != q.Status || (p.Value == nil) != (q.Value == nil) {
+			return false
+		}
+		return p.Value == nil || *p.Value == *q.Value
+	})
+}
+
+// service returns the service of a region set, the default one when id is
+// empty.
+func (s *aggregatorServer) service(id string) (*aggregator.Service, error) {
+	if id == "" {
+		return s.services.defaultService(), nil
+	}
+	service, exists := s.services.services[id]
+	if !exists {
+		return nil, status.Error(codes.NotFound, "Unknown region set: "+id)
+	}
+	return service, nil
+}
+
+// catalog returns the catalog of the requested language, negotiated from
+// the accept-language metadata when none is requested, and returns its
+// language in the content-language header.
+func (s *aggregatorServer) catalog(ctx context.Context, lang string) (*locale.Catalog, error) {
+	var catalog *locale.Catalog
+	if lang != "" {
+		c, ok := s.catalogs.Catalog(lang)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Unsupported language: %s, supported are %s", lang, strings.Join(s.catalogs.Languages(), ", "))
+		}
+		catalog = c
+	} else {
+		md, _ := metadata.FromIncomingContext(ctx)
+		catalog = s.catalogs.Negotiate(strings.Join(md.Get("accept-language"), ", "))
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs("content-language", catalog.Language)); err != nil {
+		slog.Debug("Setting content-language header failed", "error", err)
+	}
+	return catalog, nil
+}
+
+func grpcRegions(set api.RegionSet, values []string) ([]api.Region, error) {
+	regions, err := parseRegions(set, strings.Join(values, ","))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return regions, nil
+}
+
+func grpcOptions(registry *api.Registry, params []string, merge aggregatorpb.MergeStrategy, hour *timestamppb.Timestamp) (aggregator.Options, error) {
+	var opts aggregator.Options
+	for _, p := range params {
+		paramType, err := registry.ParamType(p)
+		if err != nil {
+			return aggregator.Options{}, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts.Params = append(opts.Params, paramType)
+	}
+	if merge != aggregatorpb.MergeStrategy_MERGE_STRATEGY_UNSPECIFIED {
+		strategy, exists := mergeStrategies[merge]
+		if !exists {
+			return aggregator.Options{}, status.Errorf(codes.InvalidArgument, "unknown merge strategy: %s", merge)
+		}
+		opts.Strategy = strategy
+	}
+	if hour != nil {
+		if err := hour.CheckValid(); err != nil {
+			return aggregator.Options{}, status.Errorf(codes.InvalidArgument, "invalid hour: %v", err)
+		}
+		opts.Hour = hour.AsTime().Truncate(time.Hour)
+	}
+	return opts, nil
+}
+
+// grpcError maps the errors of the service to the status codes matching the
+// statuses writeError responds with. Internal errors are logged.
+func grpcError(err error, detail string) error {
+	var upstreamErr *apiclient.UpstreamError
+	switch {
+	case errors.Is(err, aggregator.ErrStationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, aggregator.ErrNotReady):
+		return status.Error(codes.Unavailable, "The service is loading station data or failed to load it, retry later")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, detail)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, detail)
+	case errors.As(err, &upstreamErr):
+		return status.Errorf(codes.Unavailable, "%s: upstream responded with status %d", detail, upstreamErr.StatusCode)
+	}
+	slog.Error("Call failed", "error", err)
+	return status.Error(codes.Internal, detail)
+}
+
+var mergeStrategies = map[aggregatorpb.MergeStrategy]api.MergeStrategy{
+	aggregatorpb.MergeStrategy_MERGE_STRATEGY_SOURCE_MEAN:      api.SourceMean,
+	aggregatorpb.MergeStrategy_MERGE_STRATEGY_POOLED:           api.Pooled,
+	aggregatorpb.MergeStrategy_MERGE_STRATEGY_STATION_WEIGHTED: api.StationWeighted,
+	aggregatorpb.MergeStrategy_MERGE_STRATEGY_TRUST_WEIGHTED:   api.TrustWeighted,
+}
+
+var paramStatuses = map[api.ParamStatus]aggregatorpb.ParamStatus{
+	api.Available:   aggregatorpb.ParamStatus_PARAM_STATUS_AVAILABLE,
+	api.NoData:      aggregatorpb.ParamStatus_PARAM_STATUS_NO_DATA,
+	api.Unsupported: aggregatorpb.ParamStatus_PARAM_STATUS_UNSUPPORTED,
+}
+
+var trends = map[api.Trend]aggregatorpb.Trend{
+	api.Rising:  aggregatorpb.Trend_TREND_RISING,
+	api.Falling: aggregatorpb.Trend_TREND_FALLING,
+	api.Stable:  aggregatorpb.Trend_TREND_STABLE,
+}
+
+// protoData converts aggregated data to its protobuf message. Timestamps
+// that are not set are left out.
+func protoData(d api.AggregatedData) *aggregatorpb.AggregatedData {
+	data := &aggregatorpb.AggregatedData{
+		Voivodeship:     string(d.Region),
+		VoivodeshipName: d.RegionName,
+		Parameters:      make([]*aggregatorpb.Parameter, 0, len(d.Parameters)),
+		Timestamp:       protoTime(d.Timestamp),
+		Hour:            protoTime(d.Hour),
+		Merge:           &aggregatorpb.MergeInfo{},
+	}
+	for strategy, s := range mergeStrategies {
+		if s == d.Merge.Strategy {
+			data.Merge.Strategy = strategy
+		}
+	}
+	if len(d.Merge.Weights) > 0 {
+		data.Merge.Weights = make(map[string]float64, len(d.Merge.Weights))
+		for source, weight := range d.Merge.Weights {
+			data.Merge.Weights[string(source)] = weight
+		}
+	}
+	for _, p := range d.Parameters {
+		param := &aggregatorpb.Parameter{
+			Id:          int32(p.Id),
+			Name:        p.Name,
+			Description: p.Description,
+			Unit:        p.Unit,
+			Value:       p.Value,
+			Type:        string(p.Type),
+			Status:      paramStatuses[p.Status],
+		}
+		for _, c := range p.Changes {
+			param.Changes = append(param.Changes, &aggregatorpb.Change{
+				Period:   c.Period,
+				Previous: c.Previous,
+				Delta:    c.Delta,
+				Percent:  c.Percent,
+				Trend:    trends[c.Trend],
+			})
+		}
+		data.Parameters = append(data.Parameters, param)
+	}
+	return data
+}
+
+func protoTime(value string) *timestamppb.Timestamp {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// readinessInterval is how often the health service checks whether the
+// stations of the region sets are loaded.
+const readinessInterval = time.Second
+
+// newGRPCServer builds the gRPC server with the Aggregator service behind
+// the access interceptors, and the health and reflection services. The
+// Aggregator service is reported as serving while the region sets are ready,
+// until ctx is done.
+func newGRPCServer(ctx context.Context, aggregatorServer *aggregatorServer, config *access.Config, limiter *access.Limiter) (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryAccessInterceptor(config, limiter)),
+		grpc.ChainStreamInterceptor(streamAccessInterceptor(config, limiter)),
+	)
+	aggregatorpb.RegisterAggregatorServer(server, aggregatorServer)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(aggregatorpb.Aggregator_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go watchReadiness(ctx, healthServer, aggregatorServer.services, readinessInterval)
+	reflection.Register(server)
+	return server, healthServer
+}
+
+// watchReadiness sets the health status of the Aggregator service to serving
+// while the stations of every region set are loaded, the same condition the
+// HTTP API responds with 503 on, and to not serving otherwise.
+func watchReadiness(ctx context.Context, healthServer *health.Server, rs *regionServices, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		for _, service := range rs.services {
+			if service.Ready() != nil {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+		}
+		healthServer.SetServingStatus(aggregatorpb.Aggregator_ServiceDesc.ServiceName, status)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopGRPC reports the server as not serving and stops it gracefully. Calls
+// still running after the timeout, such as update streams, are cancelled.
+func stopGRPC(server *grpc.Server, healthServer *health.Server, timeout time.Duration) {
+	healthServer.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"aggregator/internal/access"
+	"aggregator/internal/aggregatorpb"
+	"aggregator/internal/api"
+	"aggregator/internal/locale"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testUpstream serves one open meteo station in malopolskie, whose
+// measurements can be changed while the test runs.
+type testUpstream struct {
+	mu           sync.Mutex
+	measurements []openmeteo.Measurement
+}
+
+func (u *testUpstream) setMeasurements(measurements ...openmeteo.Measurement) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.measurements = measurements
+}
+
+func startTestGRPCServer(t *testing.T, upstream *testUpstream) *grpc.ClientConn {
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stations":
+			json.NewEncoder(w).Encode([]openmeteo.Station{{Id: 1, Name: "Kraków", GeoLat: 50.06, GeoLon: 19.94}})
+		case "/parameters":
+			json.NewEncoder(w).Encode([]openmeteo.Parameter{{Id: 1, Name: "PM10"}})
+		default:
+			upstream.mu.Lock()
+			defer upstream.mu.Unlock()
+			json.NewEncoder(w).Encode(upstream.measurements)
+		}
+	}))
+	t.Cleanup(openMeteoServer.Close)
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openaq.Station{})
+	}))
+	t.Cleanup(openAqServer.Close)
+	t.Setenv("OPENMETEO_URL", openMeteoServer.URL)
+	t.Setenv("OPENAQ_URL", openAqServer.URL)
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	services, err := newRegionServices(t.Context(), []api.RegionSet{api.NewRegionSet("pl", "Polska", "PL", map[api.Region]api.Bounds{
+		api.Malopolskie: {MinLatitude: 49, MaxLatitude: 50.5, MinLongitude: 19, MaxLongitude: 21},
+		api.Slaskie:     {MinLatitude: 49.4, MaxLatitude: 51, MinLongitude: 17.8, MaxLongitude: 18.9},
+	})})
+	assert.NoError(t, err)
+	catalogs, err := locale.LoadCatalogs("config/locales")
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "access.json")
+	os.WriteFile(path, []byte(`{"tiers": {"anonymous": {"requestsPerMinute": 600, "burst": 100}}}`), 0o644)
+	accessConfig, err := access.LoadConfig(path)
+	assert.NoError(t, err)
+
+	server, healthServer := newGRPCServer(t.Context(), newAggregatorServer(services, catalogs, 20*time.Millisecond), accessConfig, access.NewLimiter())
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(func() { stopGRPC(server, healthServer, time.Second) })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	// The services load the stations in the background.
+	client := aggregatorpb.NewAggregatorClient(conn)
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		_, err := client.AggregateForVoivodeship(t.Context(), &aggregatorpb.AggregateForVoivodeshipRequest{Voivodeship: "malopolskie"})
+		assert.NoError(c, err)
+	}, 5*time.Second, 10*time.Millisecond)
+	return conn
+}
+
+func testMeasurement(t time.Time, value float32) openmeteo.Measurement {
+	return openmeteo.Measurement{StationId: 1, ParameterId: 1, Value: value, Timestamp: t.UTC().Format(time.RFC3339)}
+}
+
+func TestGRPCAggregate(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	upstream := &testUpstream{}
+	upstream.setMeasurements(testMeasurement(hour.Add(5*time.Minute), 30))
+	client := aggregatorpb.NewAggregatorClient(startTestGRPCServer(t, upstream))
+
+	var header metadata.MD
+	data, err := client.AggregateForVoivodeship(t.Context(), &aggregatorpb.AggregateForVoivodeshipRequest{
+		Voivodeship: "malopolskie",
+		Params:      []string{"pm10"},
+		Language:    "pl",
+	}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pl"}, header.Get("content-language"))
+	assert.Equal(t, "malopolskie", data.GetVoivodeship())
+	assert.Equal(t, "Małopolskie", data.GetVoivodeshipName())
+	assert.Equal(t, hour, data.GetHour().AsTime())
+	assert.Equal(t, hour.Add(5*time.Minute), data.GetTimestamp().AsTime())
+	assert.Equal(t, aggregatorpb.MergeStrategy_MERGE_STRATEGY_SOURCE_MEAN, data.GetMerge().GetStrategy())
+	assert.Len(t, data.GetParameters(), 1)
+	param := data.GetParameters()[0]
+	assert.Equal(t, "PM10", param.GetType())
+	assert.Equal(t, float32(30), param.GetValue())
+	assert.Equal(t, aggregatorpb.ParamStatus_PARAM_STATUS_AVAILABLE, param.GetStatus())
+
+	all, err := client.AggregateAll(t.Context(), &aggregatorpb.AggregateAllRequest{Params: []string{"PM10", "NO2"}})
+	assert.NoError(t, err)
+	assert.Len(t, all.GetData(), 2)
+	for _, p := range all.GetData()[1].GetParameters() {
+		if p.GetType() == "NO2" {
+			assert.Equal(t, aggregatorpb.ParamStatus_PARAM_STATUS_UNSUPPORTED, p.GetStatus())
+			assert.Nil(t, p.Value)
+		}
+	}
+
+	_, err = client.AggregateForVoivodeship(t.Context(), &aggregatorpb.AggregateForVoivodeshipRequest{Voivodeship: "bayern"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.AggregateAll(t.Context(), &aggregatorpb.AggregateAllRequest{RegionSet: "de"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.AggregateAll(t.Context(), &aggregatorpb.AggregateAllRequest{Language: "fr"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "supported are en, pl")
+	_, err = client.AggregateAll(t.Context(), &aggregatorpb.AggregateAllRequest{Merge: aggregatorpb.MergeStrategy(42)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCStreamUpdates(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	upstream := &testUpstream{}
+	upstream.setMeasurements(testMeasurement(hour.Add(5*time.Minute), 30))
+	client := aggregatorpb.NewAggregatorClient(startTestGRPCServer(t, upstream))
+
+	stream, err := client.StreamUpdates(t.Context(), &aggregatorpb.StreamUpdatesRequest{Voivodeships: []string{"malopolskie"}, Params: []string{"PM10"}})
+	assert.NoError(t, err)
+	data, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "malopolskie", data.GetVoivodeship())
+	assert.Equal(t, float32(30), data.GetParameters()[0].GetValue())
+
+	// Unchanged data is not sent again, a late measurement is.
+	time.Sleep(100 * time.Millisecond)
+	upstream.setMeasurements(testMeasurement(hour.Add(5*time.Minute), 30), testMeasurement(hour.Add(40*time.Minute), 50))
+	data, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "malopolskie", data.GetVoivodeship())
+	assert.Equal(t, float32(40), data.GetParameters()[0].GetValue())
+	assert.Equal(t, hour.Add(40*time.Minute), data.GetTimestamp().AsTime())
+
+	// A corrected value is sent although the timestamp stays the same.
+	upstream.setMeasurements(testMeasurement(hour.Add(5*time.Minute), 30), testMeasurement(hour.Add(40*time.Minute), 70))
+	data, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, float32(50), data.GetParameters()[0].GetValue())
+	assert.Equal(t, hour.Add(40*time.Minute), data.GetTimestamp().AsTime())
+}
+
+func TestWatchReadiness(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	t.Setenv("OPENMETEO_URL", failing.URL)
+	t.Setenv("OPENAQ_URL", failing.URL)
+	t.Setenv("DATA_DIR", t.TempDir())
+	services, err := newRegionServices(t.Context(), []api.RegionSet{api.NewRegionSet("pl", "Polska", "PL", map[api.Region]api.Bounds{
+		api.Malopolskie: {MinLatitude: 49, MaxLatitude: 50.5, MinLongitude: 19, MaxLongitude: 21},
+	})})
+	assert.NoError(t, err)
+
+	healthServer := health.NewServer()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go watchReadiness(ctx, healthServer, services, 10*time.Millisecond)
+	// The stations never load, so the service never serves.
+	assert.Never(t, func() bool {
+		health, err := healthServer.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "aggregator.v1.Aggregator"})
+		return err == nil && health.GetStatus() == healthpb.HealthCheckResponse_SERVING
+	}, 200*time.Millisecond, 10*time.Millisecond)
+	health, err := healthServer.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "aggregator.v1.Aggregator"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, health.GetStatus())
+}
+
+func TestGRPCHealthAndReflection(t *testing.T) {
+	conn := startTestGRPCServer(t, &testUpstream{})
+
+	// The status follows the readiness, checked every readinessInterval.
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		health, err := healthpb.NewHealthClient(conn).Check(t.Context(), &healthpb.HealthCheckRequest{Service: "aggregator.v1.Aggregator"})
+		assert.NoError(c, err)
+		assert.Equal(c, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(t.Context())
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}))
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	var services []string
+	for _, s := range response.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	assert.Contains(t, services, "aggregator.v1.Aggregator")
+	assert.Contains(t, services, "grpc.health.v1.Health")
+}
+
+func TestGRPCAccess(t *testing.T) {
+	interceptor := unaryAccessInterceptor(testAccessConfig(t), access.NewLimiter())
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(method string, md metadata.MD) error {
+		ctx := peer.NewContext(metadata.NewIncomingContext(t.Context(), md), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4000}})
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	aggregateAll := aggregatorpb.Aggregator_AggregateAll_FullMethodName
+
+	assert.NoError(t, call(aggregateAll, nil))
+	err := call(aggregateAll, nil)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "anonymous tier exceeded")
+	assert.NoError(t, call(healthpb.Health_Check_FullMethodName, nil))
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(aggregateAll, metadata.Pairs("x-api-key", "wrong"))))
+	assert.NoError(t, call(aggregateAll, metadata.Pairs("x-api-key", "secret")))
+	assert.NoError(t, call(aggregateAll, metadata.Pairs("authorization", "Bearer secret")))
+}
+
+func TestUpdateIntervalFromEnv(t *testing.T) {
+	interval, err := updateIntervalFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultUpdateInterval, interval)
+
+	t.Setenv("GRPC_UPDATE_INTERVAL", "30s")
+	interval, err = updateIntervalFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+
+	t.Setenv("GRPC_UPDATE_INTERVAL", "-1m")
+	_, err = updateIntervalFromEnv()
+	assert.ErrorContains(t, err, "invalid GRPC_UPDATE_INTERVAL")
+}
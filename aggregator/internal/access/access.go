@@ -0,0 +1,97 @@
+// Package access authenticates API clients by key and limits their request
+// rates.
+package access
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	defaultConfigPath = "config/access.json"
+	// AnonymousTier is the tier of requests without an API key.
+	AnonymousTier = "anonymous"
+)
+
+// Tier is a request quota: a token bucket holding up to Burst requests that
+// refills at RequestsPerMinute.
+type Tier struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute"`
+	Burst             int     `json:"burst"`
+}
+
+// Key is an API key of a client. Only the SHA-256 hash of the key is kept, so
+// the access file holds no secrets. Admin keys can also use the admin API.
+type Key struct {
+	Name  string `json:"name"`
+	Hash  string `json:"sha256"`
+	Tier  string `json:"tier"`
+	Admin bool   `json:"admin"`
+}
+
+// Config holds the tiers and the API keys.
+type Config struct {
+	Tiers map[string]Tier `json:"tiers"`
+	Keys  []Key           `json:"keys"`
+
+	keysByHash map[string]Key
+}
+
+// ConfigPath returns the path of the access file set by ACCESS_CONFIG.
+func ConfigPath() string {
+	if path := os.Getenv("ACCESS_CONFIG"); path != "" {
+		return path
+	}
+	return defaultConfigPath
+}
+
+// LoadConfig loads and validates the access file. The file has to define the
+// anonymous tier and every tier its keys refer to.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading access file: %w", err)
+	}
+	var c Config
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing access file: %w", err)
+	}
+	if _, exists := c.Tiers[AnonymousTier]; !exists {
+		return nil, fmt.Errorf("access file defines no %s tier", AnonymousTier)
+	}
+	for name, t := range c.Tiers {
+		if t.RequestsPerMinute <= 0 || t.Burst < 1 {
+			return nil, fmt.Errorf("tier %s needs a positive rate and burst", name)
+		}
+	}
+	c.keysByHash = make(map[string]Key, len(c.Keys))
+	for _, k := range c.Keys {
+		if _, err := hex.DecodeString(k.Hash); err != nil || len(k.Hash) != 2*sha256.Size {
+			return nil, fmt.Errorf("key %s has an invalid sha256 hash", k.Name)
+		}
+		if _, exists := c.Tiers[k.Tier]; !exists {
+			return nil, fmt.Errorf("key %s has unknown tier: %s", k.Name, k.Tier)
+		}
+		if _, exists := c.keysByHash[k.Hash]; exists {
+			return nil, fmt.Errorf("duplicate key: %s", k.Name)
+		}
+		c.keysByHash[k.Hash] = k
+	}
+	return &c, nil
+}
+
+// Authenticate returns the key matching the API key sent by a client.
+func (c *Config) Authenticate(apiKey string) (Key, bool) {
+	k, exists := c.keysByHash[HashKey(apiKey)]
+	return k, exists
+}
+
+// HashKey returns the hex encoded SHA-256 hash of an API key, as stored in
+// the access file.
+func HashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,42 @@
+package access
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig("../../config/access.json")
+	assert.NoError(t, err)
+	assert.Contains(t, c.Tiers, AnonymousTier)
+
+	path := filepath.Join(t.TempDir(), "access.json")
+	os.WriteFile(path, []byte(`{
+		"tiers": {"anonymous": {"requestsPerMinute": 10, "burst": 5}, "standard": {"requestsPerMinute": 100, "burst": 20}},
+		"keys": [{"name": "frontend", "sha256": "`+HashKey("secret")+`", "tier": "standard"}]
+	}`), 0o644)
+	c, err = LoadConfig(path)
+	assert.NoError(t, err)
+	key, ok := c.Authenticate("secret")
+	assert.True(t, ok)
+	assert.Equal(t, "frontend", key.Name)
+	_, ok = c.Authenticate("guess")
+	assert.False(t, ok)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	for content, want := range map[string]string{
+		`{"tiers": {}}`: "defines no anonymous tier",
+		`{"tiers": {"anonymous": {"requestsPerMinute": 0, "burst": 5}}}`:                                                                            "tier anonymous needs a positive rate and burst",
+		`{"tiers": {"anonymous": {"requestsPerMinute": 1, "burst": 1}}, "keys": [{"name": "a", "sha256": "x"}]}`:                                    "key a has an invalid sha256 hash",
+		`{"tiers": {"anonymous": {"requestsPerMinute": 1, "burst": 1}}, "keys": [{"name": "a", "sha256": "` + HashKey("a") + `", "tier": "gold"}]}`: "key a has unknown tier: gold",
+	} {
+		os.WriteFile(path, []byte(content), 0o644)
+		_, err := LoadConfig(path)
+		assert.ErrorContains(t, err, want)
+	}
+}
@@ -0,0 +1,89 @@
+package access
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often buckets that have refilled are dropped. A full
+// bucket behaves the same as a new one, so dropping it loses nothing.
+const pruneInterval = 10 * time.Minute
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed bool
+	// Limit is the burst of the tier.
+	Limit int
+	// Remaining is the number of requests that can be sent right away.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed. It is zero
+	// for allowed requests.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// Limiter keeps a token bucket per client.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token from the bucket of the client if there is one left.
+func (l *Limiter) Allow(client string, tier Tier) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.pruned) > pruneInterval {
+		l.prune(now)
+	}
+
+	perSecond := tier.RequestsPerMinute / 60
+	burst := float64(tier.Burst)
+	b, exists := l.buckets[client]
+	if !exists {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	d := Decision{Limit: tier.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / perSecond)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((burst - b.tokens) / perSecond)
+	b.full = now.Add(d.Reset)
+	return d
+}
+
+// prune drops the buckets that have refilled since their last use.
+func (l *Limiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, client)
+		}
+	}
+	l.pruned = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,34 @@
+package access
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	tier := Tier{RequestsPerMinute: 60, Burst: 2}
+
+	d := l.Allow("a", tier)
+	assert.Equal(t, Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, d)
+	assert.True(t, l.Allow("a", tier).Allowed)
+	d = l.Allow("a", tier)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 2*time.Second, d.Reset)
+	assert.True(t, l.Allow("b", tier).Allowed)
+
+	now = now.Add(1500 * time.Millisecond)
+	d = l.Allow("a", tier)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+
+	now = now.Add(time.Hour)
+	l.Allow("c", tier)
+	assert.NotContains(t, l.buckets, "a")
+	assert.Contains(t, l.buckets, "c")
+}
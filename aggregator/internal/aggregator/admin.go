@@ -0,0 +1,127 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Status describes the station cache of a service.
+type Status struct {
+	RegionSet string `json:"regionSet"`
+	// Refreshed is the time of the last successful refresh.
+	Refreshed *time.Time `json:"refreshed"`
+	// Error is the error of the last refresh if it failed, and Failed the
+	// time it failed at. The cache of the previous refresh stays in use.
+	Error    string             `json:"error,omitempty"`
+	Failed   *time.Time         `json:"failed,omitempty"`
+	Stations map[api.Source]int `json:"stations"`
+	// LatestHour is the latest hour of the collected history.
+	LatestHour *time.Time `json:"latestHour"`
+}
+
+// StationAssignment lists the regions a station is located in. Stations
+// outside every region have none.
+type StationAssignment struct {
+	Source  api.Source   `json:"source"`
+	Id      int          `json:"id"`
+	Name    string       `json:"name"`
+	Regions []api.Region `json:"regions"`
+}
+
+// Status returns the state of the station cache.
+func (s *Service) Status() Status {
+	c := s.readCache()
+	regions := s.RegionSet()
+	s.mu.RLock()
+	refreshErr := s.refreshErr
+	s.mu.RUnlock()
+	status := Status{
+		RegionSet: regions.Id,
+		Stations:  map[api.Source]int{api.OpenMeteo: len(c.openMeteoStations), api.OpenAq: len(c.openaqStations)},
+	}
+	if !c.refreshed.IsZero() {
+		status.Refreshed = &c.refreshed
+	}
+	if refreshErr != nil {
+		status.Error = refreshErr.err.Error()
+		status.Failed = &refreshErr.at
+	}
+	var latest time.Time
+	for _, region := range regions.Regions {
+		if t := s.history.Latest(region); t.After(latest) {
+			latest = t
+		}
+	}
+	if !latest.IsZero() {
+		status.LatestHour = &latest
+	}
+	return status
+}
+
+// Refresh reloads the stations and parameters of the sources right away,
+// without waiting for the next scheduled refresh, and regroups the stations
+// of every region set sharing the sources. A failed refresh is reported by
+// Status, while the stations loaded before stay in use.
+func (s *Service) Refresh(ctx context.Context) error {
+	c, err := s.sources.load(ctx, 0)
+	if err != nil {
+		s.refreshFailed(err)
+		return fmt.Errorf("refreshing stations: %w", err)
+	}
+	for _, service := range s.sources.registered() {
+		service.applyCatalog(c)
+	}
+	return nil
+}
+
+// RebuildHistory collects the hourly history again from the sources and
+// replaces the recorded one with it, dropping values the sources no longer
+// hold, such as those of hours older than the sources keep. The recorded
+// history is only replaced once collecting succeeded. Daily values are kept.
+func (s *Service) RebuildHistory(ctx context.Context) error {
+	rebuilt := history.NewStore(historyRetention)
+	if _, err := s.collectHours(ctx, rebuilt); err != nil {
+		return fmt.Errorf("collecting history: %w", err)
+	}
+	s.history.Replace(rebuilt)
+	if err := s.history.Save(); err != nil {
+		return fmt.Errorf("saving hourly history: %w", err)
+	}
+	return nil
+}
+
+// StationAssignments returns the regions every cached station is assigned to.
+func (s *Service) StationAssignments() ([]StationAssignment, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+	assignments := append(
+		assign(api.OpenMeteo, c.openMeteoStations, c.openMeteoMap),
+		assign(api.OpenAq, c.openaqStations, c.openaqMap)...,
+	)
+	return assignments, nil
+}
+
+func assign[T locatable](source api.Source, stations []T, m Map[T]) []StationAssignment {
+	regions := make(map[int][]api.Region)
+	for region, grouped := range m {
+		for _, st := range grouped {
+			regions[st.StationId()] = append(regions[st.StationId()], region)
+		}
+	}
+	assignments := make([]StationAssignment, 0, len(stations))
+	for _, st := range stations {
+		r := regions[st.StationId()]
+		slices.Sort(r)
+		if r == nil {
+			r = []api.Region{}
+		}
+		assignments = append(assignments, StationAssignment{Source: source, Id: st.StationId(), Name: st.StationName(), Regions: r})
+	}
+	return assignments
+}
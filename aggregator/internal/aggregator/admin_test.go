@@ -0,0 +1,167 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/coverage"
+	"aggregator/internal/history"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusAndStationAssignments(t *testing.T) {
+	refreshed := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	krakow := openmeteo.Station{Id: 1, Name: "Kraków"}
+	border := openaq.Station{Id: 7, Name: "Border"}
+	s := &Service{
+		regions: api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{api.Malopolskie: {}, api.Slaskie: {}}),
+		history: history.NewStore(historyRetention),
+		cache: cache{
+			openMeteoStations: []openmeteo.Station{krakow, {Id: 2, Name: "Sea"}},
+			openaqStations:    []openaq.Station{border},
+			openMeteoMap:      Map[openmeteo.Station]{api.Malopolskie: {krakow}},
+			openaqMap:         Map[openaq.Station]{api.Slaskie: {border}, api.Malopolskie: {border}},
+			refreshed:         refreshed,
+		},
+	}
+	s.history.Record(api.Slaskie, refreshed, map[api.ParamType]float32{api.PM10: 20})
+
+	assignments, err := s.StationAssignments()
+	assert.NoError(t, err)
+	assert.Equal(t, []StationAssignment{
+		{Source: api.OpenMeteo, Id: 1, Name: "Kraków", Regions: []api.Region{api.Malopolskie}},
+		{Source: api.OpenMeteo, Id: 2, Name: "Sea", Regions: []api.Region{}},
+		{Source: api.OpenAq, Id: 7, Name: "Border", Regions: []api.Region{api.Malopolskie, api.Slaskie}},
+	}, assignments)
+
+	// A failed refresh is reported, while the loaded cache stays in use.
+	s.refreshFailed(errors.New("openaq unavailable"))
+	status := s.Status()
+	assert.Equal(t, "pl", status.RegionSet)
+	assert.Equal(t, refreshed, *status.Refreshed)
+	assert.Equal(t, "openaq unavailable", status.Error)
+	assert.NotNil(t, status.Failed)
+	_, err = s.readyCache()
+	assert.NoError(t, err)
+	assert.Equal(t, map[api.Source]int{api.OpenMeteo: 2, api.OpenAq: 1}, status.Stations)
+	assert.Equal(t, refreshed, *status.LatestHour)
+
+	s.history.Replace(history.NewStore(historyRetention))
+	assert.Nil(t, s.Status().LatestHour)
+}
+
+func TestValidateRegions(t *testing.T) {
+	s := &Service{regions: api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+		api.Malopolskie: {MinLatitude: 49, MaxLatitude: 50.5, MinLongitude: 19, MaxLongitude: 21},
+	})}
+	assert.False(t, s.ValidateRegions().StationsChecked)
+
+	s.updateCache(cache{
+		openMeteoStations: []openmeteo.Station{{Id: 1, Name: "Berlin", GeoLat: 52.5, GeoLon: 13.4}},
+		openaqStations:    []openaq.Station{{Id: 7, Name: "Tarnów", Lat: 50.01, Lon: 20.99}},
+		refreshed:         time.Now(),
+	})
+	report := s.ValidateRegions()
+	assert.True(t, report.StationsChecked)
+	assert.Equal(t, map[coverage.Severity]int{coverage.Error: 0, coverage.Warning: 0, coverage.Info: 2}, report.Counts)
+	assert.Equal(t, "Station openmeteo/1 (Berlin) is in no region", report.Findings[0].Detail)
+	assert.Equal(t, "malopolskie has no openmeteo stations", report.Findings[1].Detail)
+}
+
+func TestRebuildHistory(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{{ParameterId: 1, Value: 30, Timestamp: hour.Add(5 * time.Minute).Format(time.RFC3339)}})
+	}))
+	defer server.Close()
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(server.URL),
+		openaqClient:    openaq.NewClientWithURL(server.URL),
+		params:          testParams,
+		regions:         api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{api.Malopolskie: {}}),
+		history:         history.NewStore(historyRetention),
+		cache: cache{
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+			refreshed:           time.Now(),
+		},
+	}
+	old := hour.Add(-48 * time.Hour)
+	s.history.Record(api.Malopolskie, old, map[api.ParamType]float32{api.PM10: 80})
+
+	// The history is kept when collecting fails.
+	assert.Error(t, s.RebuildHistory(t.Context()))
+	assert.Len(t, s.History(api.Malopolskie, api.PM10, old, hour), 1)
+
+	failing.Store(false)
+	assert.NoError(t, s.RebuildHistory(t.Context()))
+	assert.Equal(t, []history.Point{{Hour: hour, Value: 30}}, s.History(api.Malopolskie, api.PM10, old, hour))
+}
+
+func TestRefreshRegroupsAllRegionSets(t *testing.T) {
+	var failing atomic.Bool
+	var stations atomic.Pointer[[]openmeteo.Station]
+	stations.Store(&[]openmeteo.Station{{Id: 1, Name: "Kraków", GeoLat: 50.06, GeoLon: 19.94}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		switch r.URL.Path {
+		case "/stations":
+			json.NewEncoder(w).Encode(*stations.Load())
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer server.Close()
+	src := newSources(openmeteo.NewClientWithURL(server.URL), openaq.NewClientWithURL(server.URL))
+	newService := func(id string) *Service {
+		s := &Service{sources: src, regions: api.NewRegionSet(id, "", "", map[api.Region]api.Bounds{
+			api.Malopolskie: {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 19, MaxLongitude: 21},
+		})}
+		src.register(s)
+		return s
+	}
+	pl, other := newService("pl"), newService("other")
+
+	// The first load fails, so the service isn't ready.
+	failing.Store(true)
+	assert.Error(t, pl.Refresh(t.Context()))
+	_, err := pl.readyCache()
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	failing.Store(false)
+	assert.NoError(t, pl.refreshCache(t.Context(), catalogMaxAge))
+	assert.NoError(t, other.refreshCache(t.Context(), catalogMaxAge))
+
+	// A station added upstream reaches every region set.
+	stations.Store(&[]openmeteo.Station{
+		{Id: 1, Name: "Kraków", GeoLat: 50.06, GeoLon: 19.94},
+		{Id: 2, Name: "Tarnów", GeoLat: 50.01, GeoLon: 20.99},
+	})
+	assert.NoError(t, pl.Refresh(t.Context()))
+	assert.Len(t, other.readCache().openMeteoMap[api.Malopolskie], 2)
+
+	// A failed refresh keeps the stations loaded before.
+	failing.Store(true)
+	assert.Error(t, pl.Refresh(t.Context()))
+	_, err = pl.readyCache()
+	assert.NoError(t, err)
+	assert.Len(t, pl.readCache().openMeteoMap[api.Malopolskie], 2)
+	assert.ErrorContains(t, pl.refreshErr.err, "502")
+}
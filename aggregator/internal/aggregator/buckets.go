@@ -0,0 +1,182 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/matching"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"time"
+)
+
+// measurements holds the measurements of both sources grouped by parameter.
+type measurements struct {
+	openMeteo map[api.ParamType][]openmeteo.Measurement
+	openAq    map[api.ParamType][]openaq.Measurement
+}
+
+// hours returns the buckets that hold at least one measurement.
+func (m measurements) hours() map[time.Time]bool {
+	buckets := make(map[time.Time]bool)
+	hours(m.openMeteo, buckets)
+	hours(m.openAq, buckets)
+	return buckets
+}
+
+// inHour keeps the measurements taken within the hour starting at hour.
+func (m measurements) inHour(hour time.Time) measurements {
+	return measurements{
+		openMeteo: inHour(m.openMeteo, hour, false),
+		openAq:    inHour(m.openAq, hour, false),
+	}
+}
+
+// maxSourceLag bounds how far the latest hour both sources have data for may
+// lie behind the latest hour with data to still be aggregated instead of it.
+const maxSourceLag = 3 * time.Hour
+
+// current keeps the measurements of one complete hour: the latest hour both
+// sources have data for, unless it lags too far behind the latest hour with
+// data, in which case the lagging source is left out. Measurements of
+// different hours are never merged. The measurements without timestamp,
+// which are current but can't be aligned, are kept as well. It returns the
+// hour, or the zero time when no source has one.
+func (m measurements) current(now time.Time) (measurements, time.Time) {
+	openMeteoHours, openAqHours := hoursOf(m.openMeteo), hoursOf(m.openAq)
+	all := make(map[time.Time]bool, len(openMeteoHours)+len(openAqHours))
+	common := make(map[time.Time]bool)
+	for h := range openMeteoHours {
+		all[h] = true
+		if openAqHours[h] {
+			common[h] = true
+		}
+	}
+	for h := range openAqHours {
+		all[h] = true
+	}
+	hour := latestCompleteHour(now, all)
+	if shared := latestCompleteHour(now, common); !shared.IsZero() && !shared.Before(hour.Add(-maxSourceLag)) {
+		hour = shared
+	}
+	return measurements{
+		openMeteo: inHour(m.openMeteo, hour, true),
+		openAq:    inHour(m.openAq, hour, true),
+	}, hour
+}
+
+// merge combines the sources into one value per parameter and returns it
+// together with the time of the most recent measurement taken into account.
+func (m measurements) merge(info api.MergeInfo) (map[api.ParamType]float32, time.Time) {
+	merged := mergeStats(info, map[api.Source]sourceStats{
+		api.OpenMeteo: summarize(m.openMeteo),
+		api.OpenAq:    summarize(m.openAq),
+	})
+	return merged, latest(latestTimestamp(m.openMeteo), latestTimestamp(m.openAq))
+}
+
+// reading identifies the measurements of a parameter a station took.
+type reading struct {
+	station   matching.Key
+	paramType api.ParamType
+}
+
+// withoutDuplicates keeps, for every matched site and parameter, only the
+// measurements of the first station of the site in order of preference that
+// measured the parameter. Every site so contributes once, while parameters
+// the preferred station doesn't measure are still taken from the others. It
+// is applied once the hour is chosen, so that a site isn't counted twice
+// through stations of different sources reporting different hours.
+func (m measurements) withoutDuplicates(sites map[matching.Key][]matching.Key) measurements {
+	if len(sites) == 0 {
+		return m
+	}
+	measured := make(map[reading]bool)
+	markMeasured(api.OpenMeteo, m.openMeteo, measured)
+	markMeasured(api.OpenAq, m.openAq, measured)
+	return measurements{
+		openMeteo: dropDuplicates(api.OpenMeteo, m.openMeteo, sites, measured),
+		openAq:    dropDuplicates(api.OpenAq, m.openAq, sites, measured),
+	}
+}
+
+func markMeasured[T measurable](source api.Source, grouped map[api.ParamType][]T, into map[reading]bool) {
+	for paramType, mList := range grouped {
+		for _, m := range mList {
+			into[reading{matching.Key{Source: source, Id: m.GetStationId()}, paramType}] = true
+		}
+	}
+}
+
+func dropDuplicates[T measurable](source api.Source, grouped map[api.ParamType][]T, sites map[matching.Key][]matching.Key, measured map[reading]bool) map[api.ParamType][]T {
+	result := make(map[api.ParamType][]T, len(grouped))
+	for paramType, mList := range grouped {
+		kept := make([]T, 0, len(mList))
+		for _, m := range mList {
+			key := matching.Key{Source: source, Id: m.GetStationId()}
+			represented := false
+			for _, other := range sites[key] {
+				if other == key {
+					break
+				}
+				if measured[reading{other, paramType}] {
+					represented = true
+					break
+				}
+			}
+			if !represented {
+				kept = append(kept, m)
+			}
+		}
+		result[paramType] = kept
+	}
+	return result
+}
+
+// hourOf returns the start of the UTC hour the time falls into.
+func hourOf(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+func hoursOf[T measurable](grouped map[api.ParamType][]T) map[time.Time]bool {
+	buckets := make(map[time.Time]bool)
+	hours(grouped, buckets)
+	return buckets
+}
+
+// hours adds the buckets that hold at least one measurement. Measurements
+// without a valid timestamp cannot be aligned and are left out.
+func hours[T measurable](grouped map[api.ParamType][]T, into map[time.Time]bool) {
+	for _, mList := range grouped {
+		for _, m := range mList {
+			if ts := m.GetTimestamp(); !ts.IsZero() {
+				into[hourOf(ts)] = true
+			}
+		}
+	}
+}
+
+// latestCompleteHour returns the most recent hour that has ended by now and
+// holds measurements, or the zero time when there is none.
+func latestCompleteHour(now time.Time, buckets map[time.Time]bool) time.Time {
+	current := hourOf(now)
+	var result time.Time
+	for h := range buckets {
+		if h.Before(current) && h.After(result) {
+			result = h
+		}
+	}
+	return result
+}
+
+// inHour keeps the measurements taken within the hour starting at hour, and
+// those without timestamp when undated is set.
+func inHour[T measurable](grouped map[api.ParamType][]T, hour time.Time, undated bool) map[api.ParamType][]T {
+	result := make(map[api.ParamType][]T, len(grouped))
+	for paramType, mList := range grouped {
+		for _, m := range mList {
+			if ts := m.GetTimestamp(); ts.IsZero() && undated || !ts.IsZero() && hourOf(ts).Equal(hour) {
+				result[paramType] = append(result[paramType], m)
+			}
+		}
+	}
+	return result
+}
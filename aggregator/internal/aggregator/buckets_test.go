@@ -0,0 +1,106 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/matching"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestCompleteHour(t *testing.T) {
+	buckets := map[time.Time]bool{
+		time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC): true,
+		time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC): true,
+		time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC): true,
+	}
+	now := time.Date(2025, 10, 1, 12, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC), latestCompleteHour(now, buckets))
+	assert.True(t, latestCompleteHour(now, nil).IsZero())
+}
+
+func TestInHour(t *testing.T) {
+	grouped := map[api.ParamType][]openaq.Measurement{
+		api.PM10: {
+			{Value: 10, Timestamp: time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC)},
+			{Value: 20, Timestamp: time.Date(2025, 10, 1, 13, 59, 0, 0, time.FixedZone("CEST", 2*3600))},
+			{Value: 30, Timestamp: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		api.SO2: {{Value: 5}},
+	}
+	buckets := make(map[time.Time]bool)
+	hours(grouped, buckets)
+	assert.Len(t, buckets, 2)
+
+	result := inHour(grouped, time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC), false)
+	assert.Len(t, result[api.PM10], 2)
+	assert.NotContains(t, result, api.SO2)
+
+	result = inHour(grouped, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), true)
+	assert.Len(t, result[api.PM10], 1)
+	assert.Len(t, result[api.SO2], 1)
+}
+
+func TestCurrent(t *testing.T) {
+	m := measurements{
+		openMeteo: map[api.ParamType][]openmeteo.Measurement{
+			api.PM10: {
+				{Value: 15, Timestamp: "2025-10-01T10:05:00Z"},
+				{Value: 20, Timestamp: "2025-10-01T11:05:00Z"},
+				{Value: 30, Timestamp: "2025-10-01T12:05:00Z"},
+			},
+		},
+		openAq: map[api.ParamType][]openaq.Measurement{
+			api.PM10: {{Value: 40, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)}},
+			api.SO2:  {{Value: 5}},
+		},
+	}
+
+	// The latest hour both sources have data for is aggregated.
+	result, hour := m.current(time.Date(2025, 10, 1, 12, 30, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC), hour)
+	assert.Equal(t, float32(15), result.openMeteo[api.PM10][0].Value)
+	assert.Len(t, result.openMeteo[api.PM10], 1)
+	assert.Equal(t, float32(40), result.openAq[api.PM10][0].Value)
+	assert.Len(t, result.openAq[api.SO2], 1)
+
+	// Unless it lags too far, then the lagging source is left out.
+	m.openAq[api.PM10][0].Timestamp = time.Date(2025, 10, 1, 7, 0, 0, 0, time.UTC)
+	result, hour = m.current(time.Date(2025, 10, 1, 12, 30, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC), hour)
+	assert.Equal(t, float32(20), result.openMeteo[api.PM10][0].Value)
+	assert.Empty(t, result.openAq[api.PM10])
+	assert.Len(t, result.openAq[api.SO2], 1)
+}
+
+func TestWithoutDuplicatesOfLaggingPreferredSource(t *testing.T) {
+	site := []matching.Key{{Source: api.OpenAq, Id: 1}, {Source: api.OpenMeteo, Id: 1}}
+	sites := map[matching.Key][]matching.Key{site[0]: site, site[1]: site}
+	m := measurements{
+		openMeteo: map[api.ParamType][]openmeteo.Measurement{
+			api.PM10: {
+				{StationId: 1, Value: 10, Timestamp: "2025-10-01T10:05:00Z"},
+				{StationId: 1, Value: 20, Timestamp: "2025-10-01T11:05:00Z"},
+			},
+		},
+		openAq: map[api.ParamType][]openaq.Measurement{
+			api.PM10: {{StationId: 1, Value: 30, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)}},
+		},
+	}
+	now := time.Date(2025, 10, 1, 12, 30, 0, 0, time.UTC)
+
+	// The preferred source lags behind, the site still counts once.
+	result, hour := m.current(now)
+	result = result.withoutDuplicates(sites)
+	assert.Equal(t, time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC), hour)
+	assert.Empty(t, result.openMeteo[api.PM10])
+	assert.Len(t, result.openAq[api.PM10], 1)
+
+	// In an hour it has no data for, the other station represents the site.
+	result = m.inHour(time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC)).withoutDuplicates(sites)
+	assert.Len(t, result.openMeteo[api.PM10], 1)
+	assert.Empty(t, result.openAq[api.PM10])
+}
@@ -0,0 +1,159 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"aggregator/internal/regulatory"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	historyRetention = 8 * 24 * time.Hour
+	// collectDelay gives the sources time to store the measurements of the
+	// hour that just ended before they are collected.
+	collectDelay = 10 * time.Minute
+	// dayLookback covers the hours of the previous day the first 8-hour
+	// running means of a day start at.
+	dayLookback = 7 * time.Hour
+)
+
+// CollectHistoryLoop collects the history of the services every hour. The
+// services are collected in one run sharing the fetched measurements, so that
+// stations lying in several regions or region sets are fetched once.
+func CollectHistoryLoop(ctx context.Context, sources *Sources, services ...*Service) {
+	delay := time.Duration(0)
+	for {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		runCtx, done := sources.collection(ctx)
+		failed := false
+		for _, s := range services {
+			if err := s.collectHistory(runCtx); err != nil {
+				if !errors.Is(err, ErrNotReady) {
+					slog.Error("Failed to collect hourly history", "regionSet", s.RegionSet().Id, "error", err)
+				}
+				failed = true
+			}
+		}
+		done()
+		if failed {
+			delay = time.Minute
+			continue
+		}
+		now := time.Now()
+		delay = hourOf(now).Add(time.Hour + collectDelay).Sub(now)
+	}
+}
+
+// collectHistory records the aggregated values of every complete hour the
+// sources still hold and updates the daily values of the ended days these
+// hours fall on. Values recorded before are overwritten, so late measurements
+// are taken into account. Both are saved to DATA_DIR, so that they survive a
+// restart.
+func (s *Service) collectHistory(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "aggregator.collectHistory")
+	defer func() { endSpan(span, err) }()
+
+	days, err := s.collectHours(ctx, s.history)
+	if err != nil {
+		return err
+	}
+	current := hourOf(time.Now())
+	for region, regionDays := range days {
+		s.recordDays(region, regionDays, current)
+	}
+	if err := s.history.Save(); err != nil {
+		return fmt.Errorf("saving hourly history: %w", err)
+	}
+	if err := s.daily.Save(); err != nil {
+		return fmt.Errorf("saving daily values: %w", err)
+	}
+	return nil
+}
+
+// collectHours records the aggregated values of every complete hour the
+// sources still hold into hourly and returns the days, in the time zone of
+// the region set, these hours fall on by region.
+func (s *Service) collectHours(ctx context.Context, hourly *history.Store) (map[api.Region]map[time.Time]bool, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+
+	info := s.merge.info("")
+	current := hourOf(time.Now())
+	regions := s.RegionSet()
+	var mu sync.Mutex
+	days := make(map[api.Region]map[time.Time]bool, len(regions.Regions))
+	g, ctx := errgroup.WithContext(ctx)
+	for _, v := range regions.Regions {
+		g.Go(func() error {
+			m, err := s.fetchForRegion(ctx, c, v, Options{})
+			if err != nil {
+				return fmt.Errorf("collecting %s: %w", v, err)
+			}
+			regionDays := make(map[time.Time]bool)
+			for hour := range m.hours() {
+				if hour.Before(current) {
+					values, _ := m.inHour(hour).withoutDuplicates(c.sites).merge(info)
+					hourly.Record(v, hour, values)
+					regionDays[regions.Day(hour)] = true
+				}
+			}
+			mu.Lock()
+			days[v] = regionDays
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// recordDays computes the daily values of the given days, which start at
+// midnight in the time zone of the region set, from the hourly history. Days that haven't ended yet, and days whose hours are no longer
+// fully retained, are skipped so that their values are not replaced with ones
+// computed from partial data.
+func (s *Service) recordDays(region api.Region, days map[time.Time]bool, current time.Time) {
+	oldest := current.Add(-historyRetention + dayLookback)
+	for day := range days {
+		next := day.AddDate(0, 0, 1)
+		if day.Before(oldest) || next.After(current) {
+			continue
+		}
+		for _, paramType := range s.limitTypes() {
+			series := s.history.Series(region, paramType, day.Add(-dayLookback), next.Add(-time.Hour))
+			s.daily.Record(region, paramType, day, regulatory.Daily(series, day))
+		}
+	}
+}
+
+// limitTypes returns the parameters that have limit values.
+func (s *Service) limitTypes() []api.ParamType {
+	var types []api.ParamType
+	for _, limit := range s.limits {
+		if !slices.Contains(types, limit.Type) {
+			types = append(types, limit.Type)
+		}
+	}
+	return types
+}
+
+// History returns the recorded hourly values of a parameter of a region
+// between from and to, both included.
+func (s *Service) History(region api.Region, paramType api.ParamType, from, to time.Time) []history.Point {
+	return s.history.Series(region, paramType, from, to)
+}
@@ -0,0 +1,104 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"aggregator/internal/regulatory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectHistoryAndRegulatoryReport(t *testing.T) {
+	start := time.Now().UTC().Truncate(24 * time.Hour).Add(-48 * time.Hour)
+	var measurements []openmeteo.Measurement
+	for i := range 24 {
+		value := float32(40)
+		if i >= 12 {
+			value = 70
+		}
+		measurements = append(measurements, openmeteo.Measurement{
+			ParameterId: 1,
+			Value:       value,
+			Timestamp:   start.Add(time.Duration(i)*time.Hour + 5*time.Minute).Format(time.RFC3339),
+		})
+	}
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(measurements)
+	}))
+	defer openMeteoServer.Close()
+
+	dir := t.TempDir()
+	daily, err := regulatory.OpenDailyStore(filepath.Join(dir, "daily.json"))
+	assert.NoError(t, err)
+	hourly, err := history.OpenStore(filepath.Join(dir, "hourly.json"), historyRetention)
+	assert.NoError(t, err)
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openMeteoServer.URL),
+		params:          testParams,
+		regions:         api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{api.Malopolskie: {}}),
+		history:         hourly,
+		daily:           daily,
+		limits:          []regulatory.Limit{{Type: api.PM10, Metric: regulatory.Mean24h, Value: 50}},
+		cache: cache{
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+			refreshed:           time.Now(),
+		},
+	}
+
+	assert.NoError(t, s.collectHistory(t.Context()))
+	assert.Len(t, s.history.Series(api.Malopolskie, api.PM10, start, start.Add(24*time.Hour)), 24)
+	reopened, err := history.OpenStore(filepath.Join(dir, "hourly.json"), historyRetention)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Series(api.Malopolskie, api.PM10, start, start.Add(24*time.Hour)), 24)
+
+	report, err := s.RegulatoryReport(api.Malopolskie, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(23*time.Hour), report.Hour)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, float32(55), *report.Results[0].Value)
+	assert.Equal(t, "µg/m³", report.Results[0].Unit)
+	assert.True(t, report.Results[0].Exceeded)
+
+	report, err = s.RegulatoryReport(api.Malopolskie, start.Add(11*time.Hour))
+	assert.NoError(t, err)
+	assert.Nil(t, report.Results[0].Value)
+
+	reports, err := s.ExceedanceReports([]api.Region{api.Malopolskie}, start.Year())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{start.Format("2006-01-02")}, reports[0].Limits[0].Dates)
+}
+
+func TestCollectHistoryWaitsForCache(t *testing.T) {
+	s := &Service{params: testParams, history: history.NewStore(historyRetention)}
+	assert.ErrorIs(t, s.collectHistory(t.Context()), ErrNotReady)
+}
+
+func TestRecordDaysSkipsDaysInProgress(t *testing.T) {
+	daily, err := regulatory.OpenDailyStore(filepath.Join(t.TempDir(), "daily.json"))
+	assert.NoError(t, err)
+	s := &Service{
+		history: history.NewStore(historyRetention),
+		daily:   daily,
+		limits:  []regulatory.Limit{{Type: api.PM10, Metric: regulatory.Mean24h, Value: 50}},
+	}
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 24 {
+		s.history.Record(api.Malopolskie, day.Add(time.Duration(i)*time.Hour), map[api.ParamType]float32{api.PM10: 60})
+	}
+
+	s.recordDays(api.Malopolskie, map[time.Time]bool{day: true}, day.Add(23*time.Hour))
+	assert.Zero(t, s.daily.ExceedanceReport(api.Malopolskie, 2025, s.limits).Limits[0].DaysWithData)
+
+	s.recordDays(api.Malopolskie, map[time.Time]bool{day: true}, day.Add(24*time.Hour))
+	assert.Equal(t, 1, s.daily.ExceedanceReport(api.Malopolskie, 2025, s.limits).Limits[0].DaysWithData)
+}
@@ -0,0 +1,68 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/coverage"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ValidateRegions checks the boundaries of the region set and, once the
+// stations are loaded, how the stations are assigned to the regions.
+func (s *Service) ValidateRegions() coverage.Report {
+	c := s.readCache()
+	var stations []coverage.Station
+	if !c.refreshed.IsZero() {
+		stations = coverageStations(c.openMeteoStations, c.openaqStations)
+	}
+	return coverage.Check(s.RegionSet(), stations)
+}
+
+// FetchCoverageStations fetches the stations of all sources, for checking
+// region boundaries without a running service.
+func FetchCoverageStations(ctx context.Context) ([]coverage.Station, error) {
+	var (
+		openMeteoStations []openmeteo.Station
+		openaqStations    []openaq.Station
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		if openMeteoStations, err = openmeteo.NewClient().GetStations(ctx); err != nil {
+			return fmt.Errorf("fetching openmeteo stations: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() (err error) {
+		if openaqStations, err = openaq.NewClient().GetStations(ctx); err != nil {
+			return fmt.Errorf("fetching openaq stations: %w", err)
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return coverageStations(openMeteoStations, openaqStations), nil
+}
+
+func coverageStations(openMeteoStations []openmeteo.Station, openaqStations []openaq.Station) []coverage.Station {
+	stations := make([]coverage.Station, 0, len(openMeteoStations)+len(openaqStations))
+	stations = appendCoverageStations(stations, api.OpenMeteo, openMeteoStations)
+	return appendCoverageStations(stations, api.OpenAq, openaqStations)
+}
+
+func appendCoverageStations[T locatable](stations []coverage.Station, source api.Source, located []T) []coverage.Station {
+	for _, st := range located {
+		stations = append(stations, coverage.Station{
+			Source:    source,
+			Id:        st.StationId(),
+			Name:      st.StationName(),
+			Latitude:  st.Latitude(),
+			Longitude: st.Longitude(),
+		})
+	}
+	return stations
+}
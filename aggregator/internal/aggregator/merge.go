@@ -0,0 +1,156 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const defaultMergeStrategy = api.SourceMean
+
+// paramStats summarises the measurements of one parameter reported by one source.
+type paramStats struct {
+	sum          float64
+	measurements int
+	stations     int
+	// stationMeans is the sum of the means of the single stations.
+	stationMeans float64
+}
+
+func (p paramStats) mean() float32 {
+	return float32(p.sum / float64(p.measurements))
+}
+
+// stationMean averages the station means, so that a station reporting more
+// often doesn't count more.
+func (p paramStats) stationMean() float32 {
+	return float32(p.stationMeans / float64(p.stations))
+}
+
+type sourceStats map[api.ParamType]paramStats
+
+func summarize[T measurable](grouped map[api.ParamType][]T) sourceStats {
+	stats := make(sourceStats, len(grouped))
+	for paramType, mList := range grouped {
+		if len(mList) == 0 {
+			continue
+		}
+		var ps paramStats
+		stations := make(map[int]paramStats)
+		for _, m := range mList {
+			ps.sum += float64(m.GetValue())
+			ps.measurements++
+			st := stations[m.GetStationId()]
+			st.sum += float64(m.GetValue())
+			st.measurements++
+			stations[m.GetStationId()] = st
+		}
+		ps.stations = len(stations)
+		for _, st := range stations {
+			ps.stationMeans += float64(st.mean())
+		}
+		stats[paramType] = ps
+	}
+	return stats
+}
+
+type mergeConfig struct {
+	strategy api.MergeStrategy
+	trust    map[api.Source]float64
+}
+
+// mergeConfigFromEnv reads the default strategy from MERGE_STRATEGY and the
+// trust weights from SOURCE_TRUST_WEIGHTS, e.g. "openaq=1,openmeteo=0.5".
+func mergeConfigFromEnv() (mergeConfig, error) {
+	cfg := mergeConfig{strategy: defaultMergeStrategy}
+	if v := os.Getenv("MERGE_STRATEGY"); v != "" {
+		strategy, err := api.MapMergeStrategy(v)
+		if err != nil {
+			return mergeConfig{}, fmt.Errorf("parsing MERGE_STRATEGY: %w", err)
+		}
+		cfg.strategy = strategy
+	}
+	if v := os.Getenv("SOURCE_TRUST_WEIGHTS"); v != "" {
+		cfg.trust = make(map[api.Source]float64)
+		for item := range strings.SplitSeq(v, ",") {
+			name, weight, _ := strings.Cut(item, "=")
+			source, err := api.MapSource(strings.TrimSpace(name))
+			if err != nil {
+				return mergeConfig{}, fmt.Errorf("parsing SOURCE_TRUST_WEIGHTS: %w", err)
+			}
+			w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+			if err != nil || w < 0 {
+				return mergeConfig{}, fmt.Errorf("parsing SOURCE_TRUST_WEIGHTS: invalid weight for %s: %q", source, weight)
+			}
+			cfg.trust[source] = w
+		}
+	}
+	return cfg, nil
+}
+
+// info describes the merge for the response. The strategy falls back to the
+// configured default, and to the mean of the source means when there is none.
+func (c mergeConfig) info(strategy api.MergeStrategy) api.MergeInfo {
+	if strategy == "" {
+		strategy = c.strategy
+	}
+	if strategy == "" {
+		strategy = defaultMergeStrategy
+	}
+	info := api.MergeInfo{Strategy: strategy}
+	if strategy == api.TrustWeighted {
+		info.Weights = c.trust
+	}
+	return info
+}
+
+// mergeStats combines the per-source statistics into a single value per
+// parameter as a weighted mean of the source means. Pooling weighs the mean
+// of the station means of a source by its stations instead, which averages
+// all stations together. Sources without trust weight count with weight 1.
+func mergeStats(info api.MergeInfo, stats map[api.Source]sourceStats) map[api.ParamType]float32 {
+	sources := slices.Sorted(maps.Keys(stats))
+	result := make(map[api.ParamType]float32)
+	for _, source := range sources {
+		for paramType := range stats[source] {
+			if _, done := result[paramType]; done {
+				continue
+			}
+			var sum, weights float64
+			for _, other := range sources {
+				ps, exists := stats[other][paramType]
+				if !exists {
+					continue
+				}
+				w, mean := weight(info, other, ps), ps.mean()
+				if info.Strategy == api.Pooled {
+					mean = ps.stationMean()
+				}
+				sum += w * float64(mean)
+				weights += w
+			}
+			if weights > 0 {
+				result[paramType] = float32(sum / weights)
+			}
+		}
+	}
+	return result
+}
+
+func weight(info api.MergeInfo, source api.Source, ps paramStats) float64 {
+	switch info.Strategy {
+	case api.Pooled, api.StationWeighted:
+		return float64(ps.stations)
+	case api.TrustWeighted:
+		if w, exists := info.Weights[source]; exists {
+			return w
+		}
+		return 1
+	default:
+		return 1
+	}
+}
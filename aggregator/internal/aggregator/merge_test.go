@@ -0,0 +1,101 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	m1 := openaq.Measurement{Value: 10, ParameterId: 1, StationId: 2}
+	m2 := openaq.Measurement{Value: 20, ParameterId: 1, StationId: 2}
+	m3 := openaq.Measurement{Value: 60, ParameterId: 1, StationId: 3}
+	m4 := openaq.Measurement{Value: 10, ParameterId: 2, StationId: 2}
+	m5 := openaq.Measurement{Value: 20, ParameterId: 2, StationId: 2}
+	grouped := map[api.ParamType][]measurable{
+		api.SO2: {m1, m2, m3},
+		api.CH4: {m4, m5},
+	}
+	result := summarize(grouped)
+	assert.Equal(t, float32(30), result[api.SO2].mean())
+	assert.Equal(t, float32(37.5), result[api.SO2].stationMean())
+	assert.Equal(t, 3, result[api.SO2].measurements)
+	assert.Equal(t, 2, result[api.SO2].stations)
+	assert.Equal(t, float32(15), result[api.CH4].mean())
+	assert.Equal(t, float32(15), result[api.CH4].stationMean())
+	assert.Equal(t, 1, result[api.CH4].stations)
+}
+
+func TestMergeStats(t *testing.T) {
+	stats := map[api.Source]sourceStats{
+		api.OpenMeteo: {
+			api.SO2: {sum: 50, measurements: 1, stations: 1, stationMeans: 50},
+			api.CH4: {sum: 30, measurements: 1, stations: 1, stationMeans: 30},
+		},
+		api.OpenAq: {
+			// One station reported 10 three times, the other 50 once.
+			api.SO2: {sum: 80, measurements: 4, stations: 2, stationMeans: 60},
+			api.O3:  {sum: 10, measurements: 1, stations: 1, stationMeans: 10},
+		},
+	}
+
+	tests := []struct {
+		info api.MergeInfo
+		so2  float32
+	}{
+		{api.MergeInfo{Strategy: api.SourceMean}, 35},
+		{api.MergeInfo{Strategy: api.Pooled}, 36.667},
+		{api.MergeInfo{Strategy: api.StationWeighted}, 30},
+		{api.MergeInfo{Strategy: api.TrustWeighted, Weights: map[api.Source]float64{api.OpenMeteo: 0.5}}, 30},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.info.Strategy), func(t *testing.T) {
+			result := mergeStats(tt.info, stats)
+			assert.InDelta(t, tt.so2, result[api.SO2], 0.001)
+			assert.Equal(t, float32(30), result[api.CH4])
+			assert.Equal(t, float32(10), result[api.O3])
+		})
+	}
+
+	result := mergeStats(api.MergeInfo{Strategy: api.TrustWeighted, Weights: map[api.Source]float64{api.OpenAq: 0}}, stats)
+	assert.Equal(t, float32(50), result[api.SO2])
+	assert.NotContains(t, result, api.O3)
+}
+
+func TestMergeConfigFromEnv(t *testing.T) {
+	t.Setenv("MERGE_STRATEGY", "trust-weighted")
+	t.Setenv("SOURCE_TRUST_WEIGHTS", "openaq=1, openmeteo=0.5")
+	cfg, err := mergeConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, api.MergeInfo{
+		Strategy: api.TrustWeighted,
+		Weights:  map[api.Source]float64{api.OpenAq: 1, api.OpenMeteo: 0.5},
+	}, cfg.info(""))
+	assert.Equal(t, api.MergeInfo{Strategy: api.StationWeighted}, cfg.info(api.StationWeighted))
+
+	t.Setenv("SOURCE_TRUST_WEIGHTS", "openaq=high")
+	_, err = mergeConfigFromEnv()
+	assert.Error(t, err)
+
+	assert.Equal(t, api.SourceMean, mergeConfig{}.info("").Strategy)
+}
+
+func TestMergeMeasurements(t *testing.T) {
+	m := measurements{
+		openMeteo: map[api.ParamType][]openmeteo.Measurement{
+			api.SO2: {{StationId: 1, Value: 50}},
+			api.CH4: {{StationId: 1, Value: 30}},
+		},
+		openAq: map[api.ParamType][]openaq.Measurement{
+			api.SO2: {{StationId: 2, Value: 10}, {StationId: 2, Value: 30}},
+			api.O3:  {{StationId: 2, Value: 10}},
+		},
+	}
+	result, _ := m.merge(mergeConfig{}.info(""))
+	assert.Equal(t, float32(35), result[api.SO2])
+	assert.Equal(t, float32(30), result[api.CH4])
+	assert.Equal(t, float32(10), result[api.O3])
+}
@@ -0,0 +1,242 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"cmp"
+	"context"
+	"slices"
+)
+
+// RankByAqi ranks regions by their air quality index instead of a parameter.
+const RankByAqi = "aqi"
+
+// RankingOptions choose what the regions are ranked by. The zero value ranks
+// by AQI, cleanest first.
+type RankingOptions struct {
+	Options
+	// Param is the parameter to rank by, or empty to rank by AQI.
+	Param api.ParamType
+	// Descending ranks the highest values, the most polluted regions, first.
+	Descending bool
+	// Limit keeps only the first regions of the ranking if positive.
+	Limit int
+}
+
+// RankedRegion is a region in a ranking. Regions with equal values share a
+// rank.
+type RankedRegion struct {
+	Rank   int        `json:"rank"`
+	Region api.Region `json:"region"`
+	Name   string     `json:"name,omitempty"`
+	// Value is the value ranked by: the parameter value or the AQI score.
+	Value float64     `json:"value"`
+	Aqi   *aqi.Result `json:"aqi"`
+	Hour  string      `json:"hour,omitempty"`
+}
+
+// Ranking orders regions by a parameter or by AQI.
+type Ranking struct {
+	By      string         `json:"by"`
+	Unit    string         `json:"unit,omitempty"`
+	Order   string         `json:"order"`
+	Regions []RankedRegion `json:"regions"`
+	// Unranked lists the regions without a value to rank by.
+	Unranked []api.Region `json:"unranked"`
+}
+
+// ComparedRegion is a region of a comparison with its AQI.
+type ComparedRegion struct {
+	Region api.Region  `json:"region"`
+	Name   string      `json:"name,omitempty"`
+	Aqi    *aqi.Result `json:"aqi"`
+	Hour   string      `json:"hour,omitempty"`
+}
+
+// ComparedValue is the value of a parameter in a region. Delta and Percent
+// compare it with the value of the baseline region and are nil when either
+// value is missing, Percent also when the baseline value is zero.
+type ComparedValue struct {
+	Region  api.Region `json:"region"`
+	Value   *float32   `json:"value"`
+	Delta   *float32   `json:"delta"`
+	Percent *float64   `json:"percent"`
+}
+
+// ParamComparison compares one parameter across the regions.
+type ParamComparison struct {
+	Type   api.ParamType   `json:"type"`
+	Unit   string          `json:"unit"`
+	Values []ComparedValue `json:"values"`
+	// Lowest and Highest are the regions with the lowest and the highest
+	// value, and Spread the difference between the two values.
+	Lowest  api.Region `json:"lowest,omitempty"`
+	Highest api.Region `json:"highest,omitempty"`
+	Spread  *float32   `json:"spread"`
+}
+
+// Comparison compares regions with the first of them, the baseline.
+type Comparison struct {
+	Baseline   api.Region        `json:"baseline"`
+	Regions    []ComparedRegion  `json:"regions"`
+	Parameters []ParamComparison `json:"parameters"`
+}
+
+// Rank aggregates the data of the given regions, or of all of them, and
+// ranks them.
+func (s *Service) Rank(ctx context.Context, regions []api.Region, opts RankingOptions) (Ranking, error) {
+	aggOpts := opts.Options
+	if opts.Param != "" {
+		aggOpts.Params = []api.ParamType{opts.Param}
+	} else {
+		aggOpts.Params = s.aqi.Params()
+	}
+	results, err := s.AggregateAll(ctx, regions, aggOpts)
+	if err != nil {
+		return Ranking{}, err
+	}
+	return rank(results, s.aqi, opts), nil
+}
+
+// Compare aggregates the data of the regions and compares them with the
+// first one. The AQI is computed from all its parameters, even if only some
+// parameters are selected.
+func (s *Service) Compare(ctx context.Context, regions []api.Region, opts Options) (Comparison, error) {
+	aggOpts := opts
+	if len(opts.Params) > 0 {
+		aggOpts.Params = slices.Concat(opts.Params, s.aqi.Params())
+	}
+	results, err := s.AggregateAll(ctx, regions, aggOpts)
+	if err != nil {
+		return Comparison{}, err
+	}
+	comparison := compare(results, s.aqi)
+	if len(opts.Params) > 0 {
+		comparison.Parameters = slices.DeleteFunc(comparison.Parameters, func(p ParamComparison) bool {
+			return !slices.Contains(opts.Params, p.Type)
+		})
+	}
+	return comparison, nil
+}
+
+func rank(results []api.AggregatedData, idx *aqi.Index, opts RankingOptions) Ranking {
+	r := Ranking{By: RankByAqi, Order: "asc", Regions: []RankedRegion{}, Unranked: []api.Region{}}
+	if opts.Param != "" {
+		r.By = string(opts.Param)
+	}
+	if opts.Descending {
+		r.Order = "desc"
+	}
+	for _, result := range results {
+		entry := RankedRegion{Region: result.Region, Aqi: evaluate(idx, result), Hour: result.Hour}
+		ranked := false
+		if opts.Param == "" {
+			if entry.Aqi != nil {
+				entry.Value, ranked = entry.Aqi.Score, true
+			}
+		} else if p, ok := parameter(result, opts.Param); ok {
+			r.Unit = p.Unit
+			if p.Value != nil {
+				entry.Value, ranked = float64(*p.Value), true
+			}
+		}
+		if !ranked {
+			r.Unranked = append(r.Unranked, result.Region)
+			continue
+		}
+		r.Regions = append(r.Regions, entry)
+	}
+
+	slices.SortStableFunc(r.Regions, func(a, b RankedRegion) int {
+		if opts.Descending {
+			return cmp.Compare(b.Value, a.Value)
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	for i := range r.Regions {
+		r.Regions[i].Rank = i + 1
+		if i > 0 && r.Regions[i].Value == r.Regions[i-1].Value {
+			r.Regions[i].Rank = r.Regions[i-1].Rank
+		}
+	}
+	if opts.Limit > 0 && len(r.Regions) > opts.Limit {
+		r.Regions = r.Regions[:opts.Limit]
+	}
+	return r
+}
+
+func compare(results []api.AggregatedData, idx *aqi.Index) Comparison {
+	c := Comparison{Regions: make([]ComparedRegion, 0, len(results)), Parameters: []ParamComparison{}}
+	if len(results) == 0 {
+		return c
+	}
+	c.Baseline = results[0].Region
+	for _, result := range results {
+		c.Regions = append(c.Regions, ComparedRegion{Region: result.Region, Aqi: evaluate(idx, result), Hour: result.Hour})
+	}
+
+	for _, baseline := range results[0].Parameters {
+		pc := ParamComparison{Type: baseline.Type, Unit: baseline.Unit, Values: make([]ComparedValue, 0, len(results))}
+		var lowest, highest *ComparedValue
+		for _, result := range results {
+			v := ComparedValue{Region: result.Region}
+			if p, ok := parameter(result, baseline.Type); ok {
+				v.Value = p.Value
+			}
+			if v.Value != nil && baseline.Value != nil {
+				delta := *v.Value - *baseline.Value
+				v.Delta = &delta
+				if *baseline.Value != 0 {
+					percent := float64(delta) / float64(*baseline.Value) * 100
+					v.Percent = &percent
+				}
+			}
+			pc.Values = append(pc.Values, v)
+			if v.Value == nil {
+				continue
+			}
+			if lowest == nil || *v.Value < *lowest.Value {
+				lowest = &v
+			}
+			if highest == nil || *v.Value > *highest.Value {
+				highest = &v
+			}
+		}
+		if lowest != nil {
+			spread := *highest.Value - *lowest.Value
+			pc.Lowest, pc.Highest, pc.Spread = lowest.Region, highest.Region, &spread
+		}
+		c.Parameters = append(c.Parameters, pc)
+	}
+	return c
+}
+
+// evaluate computes the AQI of the available values of a result.
+func evaluate(idx *aqi.Index, result api.AggregatedData) *aqi.Result {
+	if idx == nil {
+		return nil
+	}
+	values := make(map[api.ParamType]float32)
+	for _, p := range result.Parameters {
+		if p.Value != nil {
+			values[p.Type] = *p.Value
+		}
+	}
+	if r, ok := idx.Evaluate(values); ok {
+		return &r
+	}
+	return nil
+}
+
+func parameter(result api.AggregatedData, paramType api.ParamType) (api.Parameter, bool) {
+	i := slices.IndexFunc(result.Parameters, func(p api.Parameter) bool { return p.Type == paramType })
+	if i < 0 {
+		return api.Parameter{}, false
+	}
+	return result.Parameters[i], true
+}
+
+// AirQualityIndex returns the index the regions are ranked and compared by.
+func (s *Service) AirQualityIndex() *aqi.Index {
+	return s.aqi
+}
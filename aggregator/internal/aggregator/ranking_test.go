@@ -0,0 +1,88 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rankingResult(region api.Region, values map[api.ParamType]float32) api.AggregatedData {
+	ad := api.AggregatedData{Region: region}
+	ad.AddParamInfo(testParams)
+	ad.AddParamValues(values, time.Time{})
+	return ad
+}
+
+var testIndex = &aqi.Index{
+	Levels: []string{"good", "fair", "poor"},
+	Bands:  map[api.ParamType][]float32{api.PM10: {20, 40}, api.NO2: {40, 90}},
+}
+
+func TestRank(t *testing.T) {
+	results := []api.AggregatedData{
+		rankingResult(api.Malopolskie, map[api.ParamType]float32{api.PM10: 45, api.NO2: 10}),
+		rankingResult(api.Slaskie, map[api.ParamType]float32{api.PM10: 30}),
+		rankingResult(api.Pomorskie, map[api.ParamType]float32{api.PM10: 10, api.NO2: 60}),
+		rankingResult(api.Opolskie, map[api.ParamType]float32{api.PM10: 30}),
+		rankingResult(api.Lubuskie, nil),
+	}
+
+	r := rank(results, testIndex, RankingOptions{})
+	assert.Equal(t, RankByAqi, r.By)
+	assert.Equal(t, "asc", r.Order)
+	assert.Equal(t, []api.Region{api.Pomorskie, api.Slaskie, api.Opolskie, api.Malopolskie},
+		[]api.Region{r.Regions[0].Region, r.Regions[1].Region, r.Regions[2].Region, r.Regions[3].Region})
+	assert.Equal(t, []int{1, 2, 2, 4}, []int{r.Regions[0].Rank, r.Regions[1].Rank, r.Regions[2].Rank, r.Regions[3].Rank})
+	assert.Equal(t, "fair", r.Regions[0].Aqi.Name)
+	assert.Equal(t, api.NO2, r.Regions[0].Aqi.Dominant)
+	assert.Equal(t, "poor", r.Regions[3].Aqi.Name)
+	assert.Equal(t, []api.Region{api.Lubuskie}, r.Unranked)
+
+	r = rank(results, testIndex, RankingOptions{Param: api.PM10, Descending: true, Limit: 2})
+	assert.Equal(t, "PM10", r.By)
+	assert.Equal(t, "desc", r.Order)
+	assert.Equal(t, "µg/m³", r.Unit)
+	assert.Len(t, r.Regions, 2)
+	assert.Equal(t, api.Malopolskie, r.Regions[0].Region)
+	assert.Equal(t, 45.0, r.Regions[0].Value)
+	assert.Equal(t, 2, r.Regions[1].Rank)
+}
+
+func TestCompare(t *testing.T) {
+	results := []api.AggregatedData{
+		rankingResult(api.Malopolskie, map[api.ParamType]float32{api.PM10: 40, api.NO2: 0}),
+		rankingResult(api.Slaskie, map[api.ParamType]float32{api.PM10: 50, api.NO2: 20}),
+		rankingResult(api.Pomorskie, map[api.ParamType]float32{api.PM10: 10}),
+	}
+
+	c := compare(results, testIndex)
+	assert.Equal(t, api.Malopolskie, c.Baseline)
+	assert.Len(t, c.Regions, 3)
+	assert.Equal(t, "poor", c.Regions[1].Aqi.Name)
+
+	var pm10, no2 ParamComparison
+	for _, p := range c.Parameters {
+		switch p.Type {
+		case api.PM10:
+			pm10 = p
+		case api.NO2:
+			no2 = p
+		}
+	}
+	assert.Equal(t, api.Pomorskie, pm10.Lowest)
+	assert.Equal(t, api.Slaskie, pm10.Highest)
+	assert.Equal(t, float32(40), *pm10.Spread)
+	assert.Equal(t, float32(0), *pm10.Values[0].Delta)
+	assert.Equal(t, float32(10), *pm10.Values[1].Delta)
+	assert.Equal(t, 25.0, *pm10.Values[1].Percent)
+	assert.Equal(t, -75.0, *pm10.Values[2].Percent)
+
+	assert.Equal(t, float32(20), *no2.Values[1].Delta)
+	assert.Nil(t, no2.Values[1].Percent)
+	assert.Nil(t, no2.Values[2].Value)
+	assert.Nil(t, no2.Values[2].Delta)
+	assert.Equal(t, float32(20), *no2.Spread)
+}
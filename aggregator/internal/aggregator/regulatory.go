@@ -0,0 +1,44 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/regulatory"
+	"time"
+)
+
+// RegulatoryReport evaluates the configured limit values for the hour
+// starting at hour, or for the latest recorded hour when hour is zero.
+func (s *Service) RegulatoryReport(region api.Region, hour time.Time) (regulatory.Report, error) {
+	if _, err := s.readyCache(); err != nil {
+		return regulatory.Report{}, err
+	}
+	if hour.IsZero() {
+		hour = s.history.Latest(region)
+	}
+	report := regulatory.Report{Region: region, Hour: hour, Results: make([]regulatory.Result, 0, len(s.limits))}
+	for _, limit := range s.limits {
+		series := s.history.Series(region, limit.Type, hour.Add(-regulatory.Window(limit.Metric)+time.Hour), hour)
+		result := regulatory.Evaluate(limit, series, hour)
+		if d, ok := s.params.Definition(limit.Type); ok {
+			result.Unit = d.Unit
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// ExceedanceReports counts the days of the year each limit value was exceeded
+// on in the given regions, or in all of them when none are given.
+func (s *Service) ExceedanceReports(regions []api.Region, year int) ([]regulatory.ExceedanceReport, error) {
+	if _, err := s.readyCache(); err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		regions = s.RegionSet().Regions
+	}
+	reports := make([]regulatory.ExceedanceReport, 0, len(regions))
+	for _, v := range regions {
+		reports = append(reports, s.daily.ExceedanceReport(v, year, s.limits))
+	}
+	return reports, nil
+}
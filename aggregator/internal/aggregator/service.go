@@ -2,50 +2,125 @@ package aggregator
 
 import (
 	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"aggregator/internal/history"
+	"aggregator/internal/matching"
 	"aggregator/internal/openaq"
 	"aggregator/internal/openmeteo"
+	"aggregator/internal/regulatory"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
+	"slices"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
 type cache struct {
+	openMeteoStations   []openmeteo.Station
+	openaqStations      []openaq.Station
 	openMeteoMap        Map[openmeteo.Station]
 	openaqMap           Map[openaq.Station]
 	openMeteoParameters []openmeteo.Parameter
 	openaqParameters    []openaq.Parameter
-	err                 error
+	matches             []matching.Match
+	// sites maps every matched station to the stations of its site, in
+	// order of the source preference.
+	sites     map[matching.Key][]matching.Key
+	refreshed time.Time
+	err       error
 }
 
 const cacheRefreshInterval = 24 * time.Hour
 
 type Service struct {
-	openmeteoClient   *openmeteo.Client
-	openaqClient      *openaq.Client
-	voivodeshipBounds map[api.Voivodeship]geographicalBounds
-	mu                sync.RWMutex
-	cache             cache
+	sources         *Sources
+	openmeteoClient *openmeteo.Client
+	openaqClient    *openaq.Client
+	regions         api.RegionSet
+	params          *api.Registry
+	matching        matching.Config
+	merge           mergeConfig
+	history         *history.Store
+	daily           *regulatory.DailyStore
+	limits          []regulatory.Limit
+	aqi             *aqi.Index
+	trendThreshold  float64
+	mu              sync.RWMutex
+	cache           cache
+	refreshErr      *refreshError
 }
 
-func NewService(ctx context.Context) *Service {
+// NewService creates the service of a region set. Every region set is served
+// by its own service, with its own station grouping, history and daily values.
+// The stations are fetched from the sources shared by all sets, and the
+// history is collected by CollectHistoryLoop. Invalid configuration is
+// returned as an error, so that the service does not start with a partial one.
+func NewService(ctx context.Context, sources *Sources, regions api.RegionSet) (*Service, error) {
 	s := &Service{
-		openmeteoClient: openmeteo.NewClient(),
-		openaqClient:    openaq.NewClient(),
+		sources:         sources,
+		openmeteoClient: sources.openmeteoClient,
+		openaqClient:    sources.openaqClient,
+		regions:         regions,
 	}
-	bounds, err := loadVoivodeshipBounds("config/voivodeships.json")
+	params, err := api.LoadRegistry("config/parameters.json")
 	if err != nil {
-		s.updateCacheErr(fmt.Errorf("failed to load voivodeship bounds: %w", err))
-	} else {
-		s.voivodeshipBounds = bounds
+		return nil, fmt.Errorf("loading parameters: %w", err)
+	}
+	s.params = params
+	if s.matching, err = matching.ConfigFromEnv(); err != nil {
+		return nil, fmt.Errorf("loading station matching config: %w", err)
+	}
+	if s.merge, err = mergeConfigFromEnv(); err != nil {
+		return nil, fmt.Errorf("loading merge config: %w", err)
+	}
+	if s.limits, err = regulatory.LoadLimits("config/limits.json"); err != nil {
+		return nil, fmt.Errorf("loading limit values: %w", err)
+	}
+	if s.aqi, err = aqi.LoadIndex("config/aqi.json"); err != nil {
+		return nil, fmt.Errorf("loading aqi bands: %w", err)
 	}
+	if s.trendThreshold, err = trendThresholdFromEnv(); err != nil {
+		return nil, fmt.Errorf("loading trend config: %w", err)
+	}
+	if s.history, err = history.OpenStore(history.StorePath(regions.Id), historyRetention); err != nil {
+		return nil, fmt.Errorf("loading hourly history: %w", err)
+	}
+	if s.daily, err = regulatory.OpenDailyStore(regulatory.DailyStorePath(regions.Id)); err != nil {
+		return nil, fmt.Errorf("loading daily values: %w", err)
+	}
+	sources.register(s)
 	go s.refreshCacheLoop(ctx)
-	return s
+	return s, nil
+}
+
+// RegionSet returns the region set the service aggregates data for.
+func (s *Service) RegionSet() api.RegionSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.regions
+}
+
+// SetRegionSet replaces the boundaries of the region set and regroups the
+// cached stations by them, without fetching the stations again.
+func (s *Service) SetRegionSet(regions api.RegionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if regions.Id != s.regions.Id {
+		return fmt.Errorf("region set %s can't replace %s", regions.Id, s.regions.Id)
+	}
+	s.regions = regions
+	s.cache.group(regions.Bounds)
+	return nil
+}
+
+// Params returns the registry of supported parameters.
+func (s *Service) Params() *api.Registry {
+	return s.params
 }
 
 func (s *Service) refreshCacheLoop(ctx context.Context) {
@@ -56,9 +131,9 @@ func (s *Service) refreshCacheLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		}
-		if err := s.refreshCache(ctx); err != nil {
+		if err := s.refreshCache(ctx, catalogMaxAge); err != nil {
 			slog.Error("Failed to refresh cache", "error", err)
-			s.updateCacheErr(err)
+			s.refreshFailed(err)
 			if delay < 5*time.Second {
 				delay = 5 * time.Second
 			} else if delay < 5*time.Minute {
@@ -70,63 +145,87 @@ func (s *Service) refreshCacheLoop(ctx context.Context) {
 	}
 }
 
-func (s *Service) refreshCache(ctx context.Context) error {
-	var (
-		openMeteoMap        Map[openmeteo.Station]
-		openaqMap           Map[openaq.Station]
-		openMeteoParameters []openmeteo.Parameter
-		openaqParameters    []openaq.Parameter
-	)
+// refreshCache takes over the station and parameter lists of the shared
+// sources, fetching them unless they are younger than maxAge, and groups the
+// stations by the regions of the set.
+func (s *Service) refreshCache(ctx context.Context, maxAge time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "aggregator.refreshCache")
+	defer func() { endSpan(span, err) }()
 
-	g, gctx := errgroup.WithContext(ctx)
+	c, err := s.sources.load(ctx, maxAge)
+	if err != nil {
+		return err
+	}
+	s.applyCatalog(c)
+	return nil
+}
 
-	g.Go(func() error {
-		stations, err := s.openmeteoClient.GetStations(gctx)
-		if err != nil {
-			return fmt.Errorf("fetching openmeteo stations: %w", err)
+// applyCatalog matches the stations of the sources and replaces the cache
+// with them.
+func (s *Service) applyCatalog(c catalog) {
+	matches := matching.FindMatches(stationSites(c.openMeteoStations, c.openaqStations), s.matching)
+	sites := make(map[matching.Key][]matching.Key)
+	for _, m := range matches {
+		keys := m.ByPreference(s.matching.Preference)
+		for _, key := range keys {
+			sites[key] = keys
 		}
-		openMeteoMap = groupStationsByVoivodeship(stations, s.voivodeshipBounds)
-		return nil
-	})
+	}
 
-	g.Go(func() error {
-		stations, err := s.openaqClient.GetStations(gctx)
-		if err != nil {
-			return fmt.Errorf("fetching openaq stations: %w", err)
-		}
-		openaqMap = groupStationsByVoivodeship(stations, s.voivodeshipBounds)
-		return nil
+	s.updateCache(cache{
+		openMeteoStations:   c.openMeteoStations,
+		openaqStations:      c.openaqStations,
+		openMeteoParameters: c.openMeteoParameters,
+		openaqParameters:    c.openaqParameters,
+		matches:             matches,
+		sites:               sites,
+		refreshed:           time.Now(),
 	})
+}
 
-	g.Go(func() error {
-		params, err := s.openmeteoClient.GetParameters(gctx)
-		if err != nil {
-			return fmt.Errorf("fetching openmeteo parameters: %w", err)
-		}
-		openMeteoParameters = params
-		return nil
-	})
+func stationSites(openMeteoStations []openmeteo.Station, openaqStations []openaq.Station) []matching.Site {
+	sites := make([]matching.Site, 0, len(openMeteoStations)+len(openaqStations))
+	for _, st := range openMeteoStations {
+		sites = append(sites, matching.Site{Key: matching.Key{Source: api.OpenMeteo, Id: st.Id}, Name: st.Name, Latitude: st.GeoLat, Longitude: st.GeoLon})
+	}
+	for _, st := range openaqStations {
+		sites = append(sites, matching.Site{Key: matching.Key{Source: api.OpenAq, Id: st.Id}, Name: st.Name, Latitude: st.Lat, Longitude: st.Lon})
+	}
+	return sites
+}
 
-	g.Go(func() error {
-		params, err := s.openaqClient.GetParameters(gctx)
-		if err != nil {
-			return fmt.Errorf("fetching openaq parameters: %w", err)
-		}
-		openaqParameters = params
-		return nil
-	})
+// StationMatches returns the stations recognised as the same site in several sources.
+func (s *Service) StationMatches() ([]matching.Match, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+	if c.matches == nil {
+		return []matching.Match{}, nil
+	}
+	return c.matches, nil
+}
 
-	if err := g.Wait(); err != nil {
-		return err
+// ErrNotReady is returned while the station cache is not loaded, either
+// because the first refresh has not finished yet or because it failed.
+var ErrNotReady = errors.New("service not ready")
+
+// readyCache returns the cache once it has been loaded.
+func (s *Service) readyCache() (cache, error) {
+	c := s.readCache()
+	if c.err != nil {
+		return cache{}, fmt.Errorf("%w: initialization failed: %w", ErrNotReady, c.err)
 	}
+	if c.refreshed.IsZero() {
+		return cache{}, ErrNotReady
+	}
+	return c, nil
+}
 
-	s.updateCache(cache{
-		openMeteoMap:        openMeteoMap,
-		openaqMap:           openaqMap,
-		openMeteoParameters: openMeteoParameters,
-		openaqParameters:    openaqParameters,
-	})
-	return nil
+// Ready returns ErrNotReady until the stations have been loaded.
+func (s *Service) Ready() error {
+	_, err := s.readyCache()
+	return err
 }
 
 func (s *Service) readCache() cache {
@@ -135,81 +234,88 @@ func (s *Service) readCache() cache {
 	return s.cache
 }
 
+// updateCache replaces the cache, grouping its stations by the current
+// region boundaries, and clears the error of the last refresh.
 func (s *Service) updateCache(c cache) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	c.group(s.regions.Bounds)
 	s.cache = c
+	s.refreshErr = nil
+}
+
+// group assigns the cached stations to the regions they are located in.
+func (c *cache) group(bounds map[api.Region]api.Bounds) {
+	c.openMeteoMap = groupStationsByRegion(c.openMeteoStations, bounds)
+	c.openaqMap = groupStationsByRegion(c.openaqStations, bounds)
 }
 
-func (s *Service) updateCacheErr(err error) {
+// refreshFailed records the error of a failed refresh. Until the cache was
+// loaded once it is also the error the service isn't ready with; a loaded
+// cache stays in use.
+func (s *Service) refreshFailed(err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.cache.err = err
+	s.refreshErr = &refreshError{err: err, at: time.Now()}
+	if s.cache.refreshed.IsZero() {
+		s.cache.err = err
+	}
 }
 
-type Map[T any] map[api.Voivodeship][]T
-
-type geographicalBounds struct {
-	MaxLatitude  float64 `json:"maxLat"`
-	MinLatitude  float64 `json:"minLat"`
-	MaxLongitude float64 `json:"maxLon"`
-	MinLongitude float64 `json:"minLon"`
+// refreshError is the error of the last refresh and when it failed.
+type refreshError struct {
+	err error
+	at  time.Time
 }
 
-func loadVoivodeshipBounds(path string) (map[api.Voivodeship]geographicalBounds, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading voivodeship bounds file: %w", err)
-	}
-	var raw map[string]geographicalBounds
-	if err = json.Unmarshal(data, &raw); err != nil {
-		return nil, fmt.Errorf("parsing voivodeship bounds file: %w", err)
-	}
-	bounds := make(map[api.Voivodeship]geographicalBounds, len(raw))
-	for k, v := range raw {
-		bounds[api.Voivodeship(k)] = v
-	}
-	return bounds, nil
-}
+type Map[T any] map[api.Region][]T
 
 type locatable interface {
 	Latitude() float64
 	Longitude() float64
 	StationName() string
+	StationId() int
 }
 
-func groupStationsByVoivodeship[T locatable](stations []T, bounds map[api.Voivodeship]geographicalBounds) Map[T] {
-	vm := make(map[api.Voivodeship][]T)
-	for v, b := range bounds {
+func groupStationsByRegion[T locatable](stations []T, bounds map[api.Region]api.Bounds) Map[T] {
+	rm := make(map[api.Region][]T)
+	for r, b := range bounds {
 		for _, s := range stations {
-			if stationInVoivodeship(s, b) {
-				vm[v] = append(vm[v], s)
+			if b.Contains(s.Latitude(), s.Longitude()) {
+				rm[r] = append(rm[r], s)
 			}
 		}
 	}
-	return vm
+	return rm
+}
+
+// Options narrow an aggregation run down to a subset of parameters and a
+// single hour, and choose how the sources are merged. The zero value
+// aggregates all supported parameters for the most recent complete hour with
+// the configured merge strategy.
+type Options struct {
+	Params   []api.ParamType
+	Strategy api.MergeStrategy
+	// Hour is the start of the UTC hour to aggregate.
+	Hour time.Time
 }
 
-func stationInVoivodeship[T locatable](s T, b geographicalBounds) bool {
-	return s.Latitude() >= b.MinLatitude &&
-		s.Latitude() <= b.MaxLatitude &&
-		s.Longitude() >= b.MinLongitude &&
-		s.Longitude() <= b.MaxLongitude
+func (o Options) selects(paramType api.ParamType) bool {
+	return len(o.Params) == 0 || slices.Contains(o.Params, paramType)
 }
 
-func (s *Service) AggregateAll(ctx context.Context) ([]api.AggregatedData, error) {
-	voivodeships := []api.Voivodeship{
-		api.Dolnoslaskie, api.KujawskoPomorskie, api.Lubelskie, api.Lubuskie,
-		api.Lodzkie, api.Malopolskie, api.Mazowieckie, api.Opolskie,
-		api.Podkarpackie, api.Podlaskie, api.Pomorskie, api.Slaskie,
-		api.Swietokrzyskie, api.WarminskoMazurskie, api.Wielkopolskie, api.Zachodniopomorskie,
+// AggregateAll aggregates data for the given regions, or for all of them
+// when none are given.
+func (s *Service) AggregateAll(ctx context.Context, regions []api.Region, opts Options) ([]api.AggregatedData, error) {
+	if len(regions) == 0 {
+		regions = s.RegionSet().Regions
 	}
 
-	results := make([]api.AggregatedData, len(voivodeships))
+	results := make([]api.AggregatedData, len(regions))
 	g, ctx := errgroup.WithContext(ctx)
-	for i, v := range voivodeships {
+	for i, v := range regions {
 		g.Go(func() error {
-			data, err := s.AggregateForVoivodeship(ctx, v)
+			data, err := s.AggregateForRegion(ctx, v, opts)
 			if err != nil {
 				return fmt.Errorf("aggregating %s: %w", v, err)
 			}
@@ -223,61 +329,154 @@ func (s *Service) AggregateAll(ctx context.Context) ([]api.AggregatedData, error
 	return results, nil
 }
 
-func (s *Service) AggregateForVoivodeship(ctx context.Context, voivodeship api.Voivodeship) (api.AggregatedData, error) {
+// AggregateForRegion aggregates data for a single region. Only the
+// sources and stations that can provide the selected parameters are queried.
+func (s *Service) AggregateForRegion(ctx context.Context, region api.Region, opts Options) (api.AggregatedData, error) {
 	if err := ctx.Err(); err != nil {
 		return api.AggregatedData{}, fmt.Errorf("context cancelled before aggregation: %w", err)
 	}
 
-	c := s.readCache()
-	if c.err != nil {
-		return api.AggregatedData{}, fmt.Errorf("service initialization failed: %w", c.err)
+	c, err := s.readyCache()
+	if err != nil {
+		return api.AggregatedData{}, err
 	}
 
-	g, ctx := errgroup.WithContext(ctx)
+	m, err := s.fetchForRegion(ctx, c, region, opts)
+	if err != nil {
+		return api.AggregatedData{}, err
+	}
+
+	results := api.AggregatedData{Region: region, Merge: s.merge.info(opts.Strategy)}
+	results.AddParamInfo(s.params,
+		api.MapOpenMeteoParameters(s.params, c.openMeteoParameters),
+		api.MapOpenAqParameters(s.params, c.openaqParameters),
+	)
+
+	hour := opts.Hour
+	selected := m.inHour(hour)
+	if hour.IsZero() {
+		selected, hour = m.current(time.Now())
+	}
+	if !hour.IsZero() {
+		results.Hour = hour.Format(time.RFC3339)
+	}
+	results.AddParamValues(selected.withoutDuplicates(c.sites).merge(results.Merge))
+	// Changes compare values of the hour alone, as the history records them.
+	hourly, _ := m.inHour(hour).withoutDuplicates(c.sites).merge(results.Merge)
+	s.addChanges(&results, hour, hourly)
+	if len(opts.Params) > 0 {
+		results.KeepParams(opts.Params)
+	}
+	return results, nil
+}
 
-	var openMeteoAverages map[api.ParamType]float32
-	var openAqAverages map[api.ParamType]float32
+// fetchForRegion fetches the measurements of all hours from the stations
+// of a region that can provide the selected parameters.
+func (s *Service) fetchForRegion(ctx context.Context, c cache, region api.Region, opts Options) (_ measurements, err error) {
+	ctx, span := startSpan(ctx, "aggregator.fetchRegion", regionAttr(region))
+	defer func() { endSpan(span, err) }()
+
+	openMeteoParameters := selectOpenMeteoParameters(s.params, c.openMeteoParameters, opts)
+	openAqParameters := selectOpenAqParameters(s.params, c.openaqParameters, opts)
+	openMeteoStations := c.openMeteoMap[region]
+	if len(openMeteoParameters) == 0 {
+		openMeteoStations = nil
+	}
+	openAqStations := selectOpenAqStations(c.openaqMap[region], openAqParameters)
+	span.SetAttributes(
+		attribute.Int("aggregator.openmeteo.stations", len(openMeteoStations)),
+		attribute.Int("aggregator.openaq.stations", len(openAqStations)),
+	)
+
+	var m measurements
+	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		averages, err := s.calculateOpenMeteoAverages(ctx, c.openMeteoParameters, c.openMeteoMap[voivodeship])
+		grouped, err := s.fetchOpenMeteoMeasurements(ctx, openMeteoParameters, openMeteoStations)
 		if err != nil {
-			return fmt.Errorf("failed to calculate averages for open meteo parameters: %w", err)
+			return fmt.Errorf("failed to fetch open meteo measurements: %w", err)
 		}
-		openMeteoAverages = averages
+		m.openMeteo = grouped
 		return nil
 	})
 
 	g.Go(func() error {
-		averages, err := s.calculateOpenAqAverages(ctx, c.openaqParameters, c.openaqMap[voivodeship])
+		grouped, err := s.fetchOpenAqMeasurements(ctx, openAqParameters, openAqStations)
 		if err != nil {
-			return fmt.Errorf("failed to calculate averages for open aq parameters: %w", err)
+			return fmt.Errorf("failed to fetch open aq measurements: %w", err)
 		}
-		openAqAverages = averages
+		m.openAq = grouped
 		return nil
 	})
 
 	if err := g.Wait(); err != nil {
-		return api.AggregatedData{}, err
+		return measurements{}, err
+	}
+	return m, nil
+}
+
+func selectOpenMeteoParameters(registry *api.Registry, parameters []openmeteo.Parameter, opts Options) []openmeteo.Parameter {
+	var selected []openmeteo.Parameter
+	for _, param := range parameters {
+		pt, err := registry.Lookup(api.OpenMeteo, param.Name)
+		if err == nil && opts.selects(pt) {
+			selected = append(selected, param)
+		}
 	}
+	return selected
+}
 
-	results := api.AggregatedData{Voivodeship: voivodeship}
-	if err := results.AddParamInfoFromOpenMeteo(c.openMeteoParameters); err != nil {
-		return api.AggregatedData{}, fmt.Errorf("failed to add param info: %w", err)
+func selectOpenAqParameters(registry *api.Registry, parameters []openaq.Parameter, opts Options) []openaq.Parameter {
+	var selected []openaq.Parameter
+	for _, param := range parameters {
+		pt, err := registry.Lookup(api.OpenAq, param.Name)
+		if err == nil && opts.selects(pt) {
+			selected = append(selected, param)
+		}
 	}
-	results.AddParamValues(mergeAverages(openMeteoAverages, openAqAverages))
-	return results, nil
+	return selected
+}
+
+// selectOpenAqStations keeps the stations that measure at least one of the parameters.
+func selectOpenAqStations(stations []openaq.Station, parameters []openaq.Parameter) []openaq.Station {
+	var selected []openaq.Station
+	for _, station := range stations {
+		for _, param := range parameters {
+			if slices.Contains(station.ParameterIds, param.Id) {
+				selected = append(selected, station)
+				break
+			}
+		}
+	}
+	return selected
 }
 
-func (s *Service) calculateOpenMeteoAverages(ctx context.Context, parameters []openmeteo.Parameter, stations []openmeteo.Station) (map[api.ParamType]float32, error) {
+// fetchOpenMeteoMeasurements returns the measurements of the given stations grouped by parameter.
+func (s *Service) fetchOpenMeteoMeasurements(ctx context.Context, parameters []openmeteo.Parameter, stations []openmeteo.Station) (map[api.ParamType][]openmeteo.Measurement, error) {
 	results := make([][]openmeteo.Measurement, len(stations))
 
 	g, ctx := errgroup.WithContext(ctx)
 	for i, station := range stations {
-		g.Go(func() error {
-			m, err := s.openmeteoClient.GetMeasurementForStation(ctx, station.Id)
+		g.Go(func() (err error) {
+			ctx, span := startSpan(ctx, "aggregator.fetchStation", stationAttrs(api.OpenMeteo, station.Id)...)
+			defer func() { endSpan(span, err) }()
+
+			fetch := func() ([]openmeteo.Measurement, error) {
+				return s.openmeteoClient.GetMeasurementForStation(ctx, station.Id)
+			}
+			var m []openmeteo.Measurement
+			if memo := memoFrom(ctx); memo != nil {
+				m, err = memo.openMeteoStation(ctx, station.Id, fetch)
+			} else {
+				m, err = fetch()
+			}
 			if err != nil {
 				return fmt.Errorf("fetching open meteo measurements for station %d: %w", station.Id, err)
 			}
+			// The station is implied by the request, so it is not always set.
+			for j := range m {
+				m[j].StationId = station.Id
+			}
 			results[i] = m
 			return nil
 		})
@@ -290,40 +489,51 @@ func (s *Service) calculateOpenMeteoAverages(ctx context.Context, parameters []o
 	for _, m := range results {
 		measurements = append(measurements, m...)
 	}
-	parameterMap := buildOpenMeteoParameterMap(parameters)
-	return calculateAverage(groupByParamId(measurements, parameterMap)), nil
+	parameterMap := buildOpenMeteoParameterMap(s.params, parameters)
+	return groupByParamId(measurements, parameterMap), nil
 }
 
-func (s *Service) calculateOpenAqAverages(ctx context.Context, parameters []openaq.Parameter, stations []openaq.Station) (map[api.ParamType]float32, error) {
-	results := make([][]openaq.Measurement, len(stations))
-
-	g, ctx := errgroup.WithContext(ctx)
-	for i, station := range stations {
-		g.Go(func() error {
-			m, err := s.openaqClient.GetMeasurementForStation(ctx, station.Id)
-			if err != nil {
-				return fmt.Errorf("fetching open aq measurements for station %d: %w", station.Id, err)
-			}
-			results[i] = m
-			return nil
-		})
+// fetchOpenAqMeasurements returns the measurements of the given stations
+// grouped by parameter. The stations are fetched in a few batched requests.
+func (s *Service) fetchOpenAqMeasurements(ctx context.Context, parameters []openaq.Parameter, stations []openaq.Station) (map[api.ParamType][]openaq.Measurement, error) {
+	if len(stations) == 0 {
+		return map[api.ParamType][]openaq.Measurement{}, nil
 	}
-	if err := g.Wait(); err != nil {
-		return nil, err
+	parameterIds := make([]int, len(parameters))
+	for i, param := range parameters {
+		parameterIds[i] = param.Id
+	}
+	fetch := func(ids []int) ([]openaq.Measurement, error) {
+		return s.openaqClient.GetMeasurements(ctx, ids, parameterIds)
+	}
+	var (
+		measurements []openaq.Measurement
+		err          error
+	)
+	if memo := memoFrom(ctx); memo != nil {
+		measurements, err = memo.openAqStations(stationIds(stations), parameterIds, fetch)
+	} else {
+		measurements, err = fetch(stationIds(stations))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching open aq measurements for %d stations: %w", len(stations), err)
 	}
+	parameterMap := buildOpenAqParameterMap(s.params, parameters)
+	return groupByParamId(measurements, parameterMap), nil
+}
 
-	measurements := make([]openaq.Measurement, 0)
-	for _, m := range results {
-		measurements = append(measurements, m...)
+func stationIds[T locatable](stations []T) []int {
+	ids := make([]int, len(stations))
+	for i, station := range stations {
+		ids[i] = station.StationId()
 	}
-	parameterMap := buildOpenAqParameterMap(parameters)
-	return calculateAverage(groupByParamId(measurements, parameterMap)), nil
+	return ids
 }
 
-func buildOpenMeteoParameterMap(parameters []openmeteo.Parameter) map[int]api.ParamType {
+func buildOpenMeteoParameterMap(registry *api.Registry, parameters []openmeteo.Parameter) map[int]api.ParamType {
 	paramIdAndType := make(map[int]api.ParamType)
 	for _, param := range parameters {
-		pt, err := api.MapOpenMeteoParamName(param.Name)
+		pt, err := registry.Lookup(api.OpenMeteo, param.Name)
 		if err != nil {
 			slog.Debug("Unsupported openmeteo parameter", "name", param.Name, "id", param.Id)
 			continue
@@ -333,10 +543,10 @@ func buildOpenMeteoParameterMap(parameters []openmeteo.Parameter) map[int]api.Pa
 	return paramIdAndType
 }
 
-func buildOpenAqParameterMap(parameters []openaq.Parameter) map[int]api.ParamType {
+func buildOpenAqParameterMap(registry *api.Registry, parameters []openaq.Parameter) map[int]api.ParamType {
 	paramIdAndType := make(map[int]api.ParamType)
 	for _, param := range parameters {
-		pt, err := api.MapOpenAqParamName(param.Name)
+		pt, err := registry.Lookup(api.OpenAq, param.Name)
 		if err != nil {
 			slog.Debug("Unsupported openaq parameter", "name", param.Name, "id", param.Id)
 			continue
@@ -349,6 +559,8 @@ func buildOpenAqParameterMap(parameters []openaq.Parameter) map[int]api.ParamTyp
 type measurable interface {
 	GetParameterId() int
 	GetValue() float32
+	GetStationId() int
+	GetTimestamp() time.Time
 }
 
 func groupByParamId[T measurable](measurements []T, paramMap map[int]api.ParamType) map[api.ParamType][]T {
@@ -364,33 +576,21 @@ func groupByParamId[T measurable](measurements []T, paramMap map[int]api.ParamTy
 	return grouped
 }
 
-func calculateAverage[T measurable](grouped map[api.ParamType][]T) map[api.ParamType]float32 {
-	averages := make(map[api.ParamType]float32, len(grouped))
-	for paramType, mList := range grouped {
-		if len(mList) == 0 {
-			continue
-		}
-		var sum float32 = 0.0
+func latestTimestamp[T measurable](grouped map[api.ParamType][]T) time.Time {
+	var result time.Time
+	for _, mList := range grouped {
 		for _, m := range mList {
-			sum += m.GetValue()
+			result = latest(result, m.GetTimestamp())
 		}
-		averages[paramType] = sum / float32(len(mList))
 	}
-	return averages
+	return result
 }
 
-func mergeAverages(openMeteoMap, openAqMap map[api.ParamType]float32) map[api.ParamType]float32 {
-	result := make(map[api.ParamType]float32)
-	for paramType, value1 := range openMeteoMap {
-		if value2, exists := openAqMap[paramType]; exists {
-			result[paramType] = (value1 + value2) / 2.0
-		} else {
-			result[paramType] = value1
-		}
-	}
-	for paramType, value2 := range openAqMap {
-		if _, exists := openMeteoMap[paramType]; !exists {
-			result[paramType] = value2
+func latest(times ...time.Time) time.Time {
+	var result time.Time
+	for _, t := range times {
+		if t.After(result) {
+			result = t
 		}
 	}
 	return result
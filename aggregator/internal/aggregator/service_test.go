@@ -2,6 +2,7 @@ package aggregator
 
 import (
 	"aggregator/internal/api"
+	"aggregator/internal/matching"
 	"aggregator/internal/openaq"
 	"aggregator/internal/openmeteo"
 	"encoding/json"
@@ -9,21 +10,82 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+var testParams = func() *api.Registry {
+	registry, err := api.LoadRegistry("../../config/parameters.json")
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}()
+
+func TestNewServiceRejectsInvalidConfig(t *testing.T) {
+	t.Chdir("../..")
+	t.Setenv("DATA_DIR", t.TempDir())
+	regions := api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{api.Malopolskie: {}})
+
+	t.Setenv("TREND_THRESHOLD_PERCENT", "-5")
+	_, err := NewService(t.Context(), NewSources(), regions)
+	assert.ErrorContains(t, err, "loading trend config")
+
+	t.Setenv("TREND_THRESHOLD_PERCENT", "")
+	t.Setenv("MERGE_STRATEGY", "median")
+	_, err = NewService(t.Context(), NewSources(), regions)
+	assert.ErrorContains(t, err, "loading merge config")
+}
+
 func TestAggregateData(t *testing.T) {
 	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]openmeteo.Measurement{
-			{ParameterId: 1, Value: 20},
+			{ParameterId: 1, Value: 20, Timestamp: "2025-10-01T12:00:00Z"},
+		})
+	}))
+	defer openMeteoServer.Close()
+
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openaq.Measurement{
+			{ParameterId: 1, Value: 30, Timestamp: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)},
+		})
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openaqParameters:    []openaq.Parameter{{Id: 1, Name: "pm10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+			openaqMap:           Map[openaq.Station]{api.Malopolskie: {{Id: 1, ParameterIds: []int{1}}}},
+		},
+	}
+
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(25), *result.Parameters[0].Value)
+	assert.Equal(t, "2025-10-01T12:00:00Z", result.Timestamp)
+	assert.Equal(t, "2025-10-01T12:00:00Z", result.Hour)
+}
+
+func TestAggregateDataAlignsHours(t *testing.T) {
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{
+			{ParameterId: 1, Value: 10, Timestamp: "2025-10-01T10:05:00Z"},
+			{ParameterId: 1, Value: 20, Timestamp: "2025-10-01T11:05:00Z"},
+			{ParameterId: 1, Value: 40, Timestamp: "2025-10-01T12:05:00Z"},
 		})
 	}))
 	defer openMeteoServer.Close()
 
 	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]openaq.Measurement{
-			{ParameterId: 1, Value: 30},
+			{ParameterId: 1, Value: 30, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)},
 		})
 	}))
 	defer openAqServer.Close()
@@ -31,17 +93,130 @@ func TestAggregateData(t *testing.T) {
 	s := &Service{
 		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
 		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
 		cache: cache{
+			refreshed:           time.Now(),
 			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
 			openaqParameters:    []openaq.Parameter{{Id: 1, Name: "pm10"}},
 			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
-			openaqMap:           Map[openaq.Station]{api.Malopolskie: {{Id: 1}}},
+			openaqMap:           Map[openaq.Station]{api.Malopolskie: {{Id: 1, ParameterIds: []int{1}}}},
 		},
 	}
 
-	result, err := s.AggregateForVoivodeship(t.Context(), api.Malopolskie)
+	// Open aq lags behind, so the latest hour of both sources is aggregated.
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{})
 	assert.NoError(t, err)
-	assert.Equal(t, float32(25), result.Parameters[0].Value)
+	assert.Equal(t, float32(20), *result.Parameters[0].Value)
+	assert.Equal(t, "2025-10-01T10:00:00Z", result.Hour)
+
+	result, err = s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Hour: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(20), *result.Parameters[0].Value)
+	assert.Equal(t, "2025-10-01T10:05:00Z", result.Timestamp)
+
+	result, err = s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Hour: time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)})
+	assert.NoError(t, err)
+	assert.Nil(t, result.Parameters[0].Value)
+	assert.Equal(t, api.NoData, result.Parameters[0].Status)
+}
+
+func TestAggregateDataWithSelectedParams(t *testing.T) {
+	var openMeteoRequests, openAqRequests []string
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openMeteoRequests = append(openMeteoRequests, r.URL.Path)
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{{ParameterId: 1, Value: 20, Timestamp: "2025-10-01T12:00:00Z"}})
+	}))
+	defer openMeteoServer.Close()
+
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAqRequests = append(openAqRequests, r.URL.RequestURI())
+		json.NewEncoder(w).Encode([]openaq.Measurement{{ParameterId: 1, Value: 30, Timestamp: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)}})
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}, {Id: 2, Name: "OZONE"}},
+			openaqParameters:    []openaq.Parameter{{Id: 1, Name: "pm10"}, {Id: 2, Name: "no2"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+			openaqMap: Map[openaq.Station]{api.Malopolskie: {
+				{Id: 1, ParameterIds: []int{1}},
+				{Id: 2, ParameterIds: []int{2}},
+			}},
+		},
+	}
+
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Params: []api.ParamType{api.PM10}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/stations/1/measurements"}, openMeteoRequests)
+	assert.Equal(t, []string{"/measurements?stationIds=1&parameterIds=1"}, openAqRequests)
+	assert.Len(t, result.Parameters, 1)
+	assert.Equal(t, float32(25), *result.Parameters[0].Value)
+
+	openMeteoRequests, openAqRequests = nil, nil
+	_, err = s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Params: []api.ParamType{api.NO2}})
+	assert.NoError(t, err)
+	assert.Empty(t, openMeteoRequests)
+	assert.Equal(t, []string{"/measurements?stationIds=2&parameterIds=2"}, openAqRequests)
+}
+
+func TestAggregateDataSkipsDuplicateStations(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	ts := hour.Format(time.RFC3339)
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stations/1/measurements":
+			json.NewEncoder(w).Encode([]openmeteo.Measurement{{ParameterId: 1, Value: 20, Timestamp: ts}, {ParameterId: 2, Value: 40, Timestamp: ts}})
+		case "/stations/2/measurements":
+			json.NewEncoder(w).Encode([]openmeteo.Measurement{{ParameterId: 1, Value: 10, Timestamp: ts}})
+		}
+	}))
+	defer openMeteoServer.Close()
+
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openaq.Measurement{{StationId: 1, ParameterId: 1, Value: 30, Timestamp: hour}})
+	}))
+	defer openAqServer.Close()
+
+	site := []matching.Key{{Source: api.OpenAq, Id: 1}, {Source: api.OpenMeteo, Id: 1}}
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}, {Id: 2, Name: "NITROGEN_DIOXIDE"}},
+			openaqParameters:    []openaq.Parameter{{Id: 1, Name: "pm10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}, {Id: 2}}},
+			openaqMap:           Map[openaq.Station]{api.Malopolskie: {{Id: 1, ParameterIds: []int{1}}}},
+			sites:               map[matching.Key][]matching.Key{site[0]: site, site[1]: site},
+		},
+	}
+
+	// The open aq station represents the site for PM10, but the open meteo
+	// station still contributes the NO2 the other one doesn't measure.
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{})
+	assert.NoError(t, err)
+	values := make(map[api.ParamType]float32)
+	for _, p := range result.Parameters {
+		if p.Value != nil {
+			values[p.Type] = *p.Value
+		}
+	}
+	assert.Equal(t, map[api.ParamType]float32{api.PM10: 20, api.NO2: 40}, values)
+}
+
+func TestAggregateAllForSelectedRegions(t *testing.T) {
+	s := &Service{params: testParams, cache: cache{refreshed: time.Now()}}
+	result, err := s.AggregateAll(t.Context(), []api.Region{api.Slaskie, api.Opolskie}, Options{})
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, api.Slaskie, result[0].Region)
+	assert.Equal(t, api.Opolskie, result[1].Region)
 }
 
 func TestAggregateDataWithCacheError(t *testing.T) {
@@ -50,8 +225,12 @@ func TestAggregateDataWithCacheError(t *testing.T) {
 			err: fmt.Errorf("initialization failed"),
 		},
 	}
-	_, err := s.AggregateForVoivodeship(t.Context(), api.Malopolskie)
+	_, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{})
 	assert.ErrorContains(t, err, "initialization failed")
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	_, err = (&Service{}).AggregateForRegion(t.Context(), api.Malopolskie, Options{})
+	assert.ErrorIs(t, err, ErrNotReady)
 }
 
 func TestRefreshCacheWithError(t *testing.T) {
@@ -76,10 +255,9 @@ func TestRefreshCacheWithError(t *testing.T) {
 	defer openAqServer.Close()
 
 	s := &Service{
-		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
-		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		sources: newSources(openmeteo.NewClientWithURL(openMeteoServer.URL), openaq.NewClientWithURL(openAqServer.URL)),
 	}
-	err := s.refreshCache(t.Context())
+	err := s.refreshCache(t.Context(), 0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "fetching openmeteo parameters")
 }
@@ -106,18 +284,58 @@ func TestRefreshCache(t *testing.T) {
 	defer openAqServer.Close()
 
 	s := &Service{
-		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
-		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		sources: newSources(openmeteo.NewClientWithURL(openMeteoServer.URL), openaq.NewClientWithURL(openAqServer.URL)),
 	}
-	err := s.refreshCache(t.Context())
+	err := s.refreshCache(t.Context(), 0)
 	assert.NoError(t, err)
 	assert.Len(t, s.cache.openMeteoParameters, 1)
 	assert.Len(t, s.cache.openaqParameters, 1)
 	assert.Len(t, s.cache.openMeteoMap, 0)
 	assert.Len(t, s.cache.openaqMap, 0)
+
+	// Encoded as an empty list rather than null.
+	matches, err := s.StationMatches()
+	assert.NoError(t, err)
+	assert.NotNil(t, matches)
+	assert.Empty(t, matches)
 }
 
-func TestCalculateOpenMeteoAverages(t *testing.T) {
+func TestRefreshCacheMatchesStations(t *testing.T) {
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stations":
+			json.NewEncoder(w).Encode([]openmeteo.Station{{Id: 1, Name: "Kraków, Bulwarowa", GeoLat: 50.0692, GeoLon: 20.0530}})
+		case "/parameters":
+			json.NewEncoder(w).Encode([]openmeteo.Parameter{{Id: 1, Name: "PM10"}})
+		}
+	}))
+	defer openMeteoServer.Close()
+
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stations":
+			json.NewEncoder(w).Encode([]openaq.Station{{Id: 4, Name: "Kraków - Bulwarowa", Lat: 50.0693, Lon: 20.0531}})
+		case "/parameters":
+			json.NewEncoder(w).Encode([]openaq.Parameter{{Id: 1, Name: "pm10"}})
+		}
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		sources:  newSources(openmeteo.NewClientWithURL(openMeteoServer.URL), openaq.NewClientWithURL(openAqServer.URL)),
+		matching: matching.Config{MaxDistance: 300, Preference: []api.Source{api.OpenAq}},
+	}
+	err := s.refreshCache(t.Context(), 0)
+	assert.NoError(t, err)
+
+	matches, err := s.StationMatches()
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, matching.Key{Source: api.OpenAq, Id: 4}, matches[0].Preferred)
+	assert.Equal(t, []matching.Key{{Source: api.OpenAq, Id: 4}, {Source: api.OpenMeteo, Id: 1}}, s.cache.sites[matching.Key{Source: api.OpenMeteo, Id: 1}])
+}
+
+func TestFetchOpenMeteoMeasurements(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]openmeteo.Measurement{
 			{ParameterId: 1, Value: 10},
@@ -128,6 +346,7 @@ func TestCalculateOpenMeteoAverages(t *testing.T) {
 
 	s := &Service{
 		openmeteoClient: openmeteo.NewClientWithURL(server.URL),
+		params:          testParams,
 	}
 	parameters := []openmeteo.Parameter{
 		{Id: 1, Name: "PM10"},
@@ -135,12 +354,12 @@ func TestCalculateOpenMeteoAverages(t *testing.T) {
 	stations := []openmeteo.Station{
 		{Id: 1},
 	}
-	result, err := s.calculateOpenMeteoAverages(t.Context(), parameters, stations)
+	result, err := s.fetchOpenMeteoMeasurements(t.Context(), parameters, stations)
 	assert.NoError(t, err)
-	assert.Equal(t, float32(15), result[api.PM10])
+	assert.Len(t, result[api.PM10], 2)
 }
 
-func TestCalculateOpenAqAverages(t *testing.T) {
+func TestFetchOpenAqMeasurements(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode([]openaq.Measurement{
 			{ParameterId: 1, Value: 10},
@@ -151,6 +370,7 @@ func TestCalculateOpenAqAverages(t *testing.T) {
 
 	s := &Service{
 		openaqClient: openaq.NewClientWithURL(server.URL),
+		params:       testParams,
 	}
 	parameters := []openaq.Parameter{
 		{Id: 1, Name: "pm10"},
@@ -158,16 +378,16 @@ func TestCalculateOpenAqAverages(t *testing.T) {
 	stations := []openaq.Station{
 		{Id: 1},
 	}
-	result, err := s.calculateOpenAqAverages(t.Context(), parameters, stations)
+	result, err := s.fetchOpenAqMeasurements(t.Context(), parameters, stations)
 	assert.NoError(t, err)
-	assert.Equal(t, float32(15), result[api.PM10])
+	assert.Len(t, result[api.PM10], 2)
 }
 
 func TestBuildOpenMeteoParameterMap(t *testing.T) {
 	p1 := openmeteo.Parameter{Id: 2, Name: "PM10"}
 	p2 := openmeteo.Parameter{Id: 4, Name: "CARBON_MONOXIDE"}
 	p3 := openmeteo.Parameter{Id: 6, Name: "UNKNOWN_PARAM"}
-	result := buildOpenMeteoParameterMap([]openmeteo.Parameter{p1, p2, p3})
+	result := buildOpenMeteoParameterMap(testParams, []openmeteo.Parameter{p1, p2, p3})
 	assert.Equal(t, api.PM10, result[2])
 	assert.Equal(t, api.CO, result[4])
 	_, exists := result[6]
@@ -178,18 +398,18 @@ func TestBuildOpenAqParameterMap(t *testing.T) {
 	p1 := openaq.Parameter{Id: 2, Name: "pm10"}
 	p2 := openaq.Parameter{Id: 4, Name: "co"}
 	p3 := openaq.Parameter{Id: 6, Name: "UNKNOWN_PARAM"}
-	result := buildOpenAqParameterMap([]openaq.Parameter{p1, p2, p3})
+	result := buildOpenAqParameterMap(testParams, []openaq.Parameter{p1, p2, p3})
 	assert.Equal(t, api.PM10, result[2])
 	assert.Equal(t, api.CO, result[4])
 	_, exists := result[6]
 	assert.False(t, exists)
 }
 
-func TestGroupStationsByVoivodeship(t *testing.T) {
-	b1 := geographicalBounds{MaxLatitude: 10, MinLatitude: 5, MaxLongitude: 10, MinLongitude: 5}
-	b2 := geographicalBounds{MaxLatitude: 20, MinLatitude: 11, MaxLongitude: 20, MinLongitude: 11}
-	b3 := geographicalBounds{MaxLatitude: 30, MinLatitude: 21, MaxLongitude: 30, MinLongitude: 21}
-	bounds := map[api.Voivodeship]geographicalBounds{
+func TestGroupStationsByRegion(t *testing.T) {
+	b1 := api.Bounds{MaxLatitude: 10, MinLatitude: 5, MaxLongitude: 10, MinLongitude: 5}
+	b2 := api.Bounds{MaxLatitude: 20, MinLatitude: 11, MaxLongitude: 20, MinLongitude: 11}
+	b3 := api.Bounds{MaxLatitude: 30, MinLatitude: 21, MaxLongitude: 30, MinLongitude: 21}
+	bounds := map[api.Region]api.Bounds{
 		api.Malopolskie: b1,
 		api.Mazowieckie: b2,
 		api.Pomorskie:   b3,
@@ -199,22 +419,12 @@ func TestGroupStationsByVoivodeship(t *testing.T) {
 		{GeoLat: 18, GeoLon: 15},
 		{GeoLat: 13, GeoLon: 15},
 	}
-	result := groupStationsByVoivodeship(stations, bounds)
+	result := groupStationsByRegion(stations, bounds)
 	assert.Len(t, result[api.Malopolskie], 1)
 	assert.Len(t, result[api.Mazowieckie], 2)
 	assert.Len(t, result[api.Pomorskie], 0)
 }
 
-func TestStationInVoivodeship(t *testing.T) {
-	bounds := geographicalBounds{MaxLatitude: 10, MinLatitude: 5, MaxLongitude: 20, MinLongitude: 5}
-	station := openmeteo.Station{GeoLat: 8, GeoLon: 10}
-	assert.True(t, stationInVoivodeship(station, bounds))
-	station.GeoLat = 20
-	assert.False(t, stationInVoivodeship(station, bounds))
-	station.GeoLon = 2
-	assert.False(t, stationInVoivodeship(station, bounds))
-}
-
 func TestGroupByParamId(t *testing.T) {
 	paramMap := map[int]api.ParamType{1: api.PM10, 2: api.SO2}
 	measurements := []openmeteo.Measurement{
@@ -229,26 +439,28 @@ func TestGroupByParamId(t *testing.T) {
 	assert.Len(t, result, 2)
 }
 
-func TestMergeAverages(t *testing.T) {
-	openMeteo := map[api.ParamType]float32{api.SO2: 50, api.CH4: 30}
-	openAq := map[api.ParamType]float32{api.SO2: 20, api.O3: 10}
-	result := mergeAverages(openMeteo, openAq)
-	assert.Equal(t, float32(35), result[api.SO2])
-	assert.Equal(t, float32(30), result[api.CH4])
-	assert.Equal(t, float32(10), result[api.O3])
-}
-
-func TestCalculateAverages(t *testing.T) {
-	m1 := openaq.Measurement{Value: 10, ParameterId: 1, StationId: 2}
-	m2 := openaq.Measurement{Value: 20, ParameterId: 1, StationId: 2}
-	m3 := openaq.Measurement{Value: 30, ParameterId: 1, StationId: 2}
-	m4 := openaq.Measurement{Value: 10, ParameterId: 2, StationId: 2}
-	m5 := openaq.Measurement{Value: 20, ParameterId: 2, StationId: 2}
-	grouped := map[api.ParamType][]measurable{
-		api.SO2: {m1, m2, m3},
-		api.CH4: {m4, m5},
-	}
-	result := calculateAverage(grouped)
-	assert.Equal(t, float32(20), result[api.SO2])
-	assert.Equal(t, float32(15), result[api.CH4])
+func TestLatestTimestamp(t *testing.T) {
+	grouped := map[api.ParamType][]openmeteo.Measurement{
+		api.PM10: {{Timestamp: "2025-10-01T11:00:00.123"}, {Timestamp: "2025-10-01T12:00:00Z"}},
+		api.SO2:  {{Timestamp: "not-a-timestamp"}},
+	}
+	assert.Equal(t, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), latestTimestamp(grouped))
+}
+
+func TestSetRegionSetRegroupsStations(t *testing.T) {
+	tarnow := openaq.Station{Id: 7, Name: "Tarnów", Lat: 50.01, Lon: 20.99}
+	s := &Service{regions: api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+		api.Slaskie: {MinLatitude: 49.4, MaxLatitude: 51, MinLongitude: 17.5, MaxLongitude: 19.5},
+	})}
+	s.updateCache(cache{openaqStations: []openaq.Station{tarnow}, refreshed: time.Now()})
+	assert.Empty(t, s.readCache().openaqMap)
+
+	err := s.SetRegionSet(api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+		api.Malopolskie: {MinLatitude: 49, MaxLatitude: 50.5, MinLongitude: 19, MaxLongitude: 21.5},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, Map[openaq.Station]{api.Malopolskie: {tarnow}}, s.readCache().openaqMap)
+	assert.Equal(t, []api.Region{api.Malopolskie}, s.RegionSet().Regions)
+
+	assert.EqualError(t, s.SetRegionSet(api.NewRegionSet("de", "", "DE", nil)), "region set de can't replace pl")
 }
@@ -0,0 +1,259 @@
+package aggregator
+
+import (
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// catalogMaxAge is how old the station and parameter lists may be for a
+// service refreshing its cache to take them over instead of fetching them.
+// The services of all region sets refresh at about the same time, so only
+// the first one fetches.
+const catalogMaxAge = time.Hour
+
+// Sources holds the clients of the sources and the station and parameter
+// lists fetched from them. It is shared by the services of all region sets,
+// which group the stations by their own boundaries, so that the lists are
+// fetched once rather than once per set.
+type Sources struct {
+	openmeteoClient *openmeteo.Client
+	openaqClient    *openaq.Client
+	mu              sync.Mutex
+	catalog         catalog
+	services        []*Service
+	// memo holds the measurements of the collection in progress, if any.
+	memo *measurementMemo
+}
+
+// catalog holds the stations and parameters of the sources.
+type catalog struct {
+	openMeteoStations   []openmeteo.Station
+	openaqStations      []openaq.Station
+	openMeteoParameters []openmeteo.Parameter
+	openaqParameters    []openaq.Parameter
+	fetched             time.Time
+}
+
+// NewSources creates the clients of the sources from the environment.
+func NewSources() *Sources {
+	return newSources(openmeteo.NewClient(), openaq.NewClient())
+}
+
+func newSources(openmeteoClient *openmeteo.Client, openaqClient *openaq.Client) *Sources {
+	return &Sources{openmeteoClient: openmeteoClient, openaqClient: openaqClient}
+}
+
+// register adds a service to the ones sharing the sources.
+func (src *Sources) register(s *Service) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.services = append(src.services, s)
+}
+
+// registered returns the services sharing the sources.
+func (src *Sources) registered() []*Service {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	return slices.Clone(src.services)
+}
+
+// DropCaches drops the station and parameter lists, so that the next refresh
+// of every region set fetches them, and the measurements fetched by the
+// collection in progress, so that the rest of it fetches them again. The
+// stations the region sets hold stay in use until then.
+func (src *Sources) DropCaches() {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.catalog = catalog{}
+	if src.memo != nil {
+		src.memo.clear()
+	}
+}
+
+// collection returns a context sharing fetched measurements among the
+// fetches of one collection run, and a function ending the run.
+func (src *Sources) collection(ctx context.Context) (context.Context, func()) {
+	memo := newMeasurementMemo()
+	src.mu.Lock()
+	src.memo = memo
+	src.mu.Unlock()
+	return context.WithValue(ctx, memoKey{}, memo), func() {
+		src.mu.Lock()
+		defer src.mu.Unlock()
+		if src.memo == memo {
+			src.memo = nil
+		}
+	}
+}
+
+// load returns the station and parameter lists, fetching them unless they
+// were fetched within maxAge. Concurrent calls wait for a single fetch.
+func (src *Sources) load(ctx context.Context, maxAge time.Duration) (catalog, error) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if !src.catalog.fetched.IsZero() && time.Since(src.catalog.fetched) < maxAge {
+		return src.catalog, nil
+	}
+	c, err := src.fetch(ctx)
+	if err != nil {
+		return catalog{}, err
+	}
+	src.catalog = c
+	return c, nil
+}
+
+func (src *Sources) fetch(ctx context.Context) (catalog, error) {
+	var c catalog
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		stations, err := src.openmeteoClient.GetStations(gctx)
+		if err != nil {
+			return fmt.Errorf("fetching openmeteo stations: %w", err)
+		}
+		c.openMeteoStations = stations
+		return nil
+	})
+
+	g.Go(func() error {
+		stations, err := src.openaqClient.GetStations(gctx)
+		if err != nil {
+			return fmt.Errorf("fetching openaq stations: %w", err)
+		}
+		c.openaqStations = stations
+		return nil
+	})
+
+	g.Go(func() error {
+		params, err := src.openmeteoClient.GetParameters(gctx)
+		if err != nil {
+			return fmt.Errorf("fetching openmeteo parameters: %w", err)
+		}
+		c.openMeteoParameters = params
+		return nil
+	})
+
+	g.Go(func() error {
+		params, err := src.openaqClient.GetParameters(gctx)
+		if err != nil {
+			return fmt.Errorf("fetching openaq parameters: %w", err)
+		}
+		c.openaqParameters = params
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		return catalog{}, err
+	}
+	c.fetched = time.Now()
+	return c, nil
+}
+
+// measurementMemo holds the measurements fetched during one collection run,
+// so that a station lying in several regions, of the same or of different
+// region sets, is fetched once per run.
+type measurementMemo struct {
+	mu        sync.Mutex
+	openMeteo map[int]*memoCall[[]openmeteo.Measurement]
+	openAqMu  sync.Mutex
+	openAq    map[openAqMemoKey][]openaq.Measurement
+}
+
+type openAqMemoKey struct {
+	station    int
+	parameters string
+}
+
+// memoCall is a fetch that callers asking for the same key wait for.
+type memoCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+type memoKey struct{}
+
+func newMeasurementMemo() *measurementMemo {
+	return &measurementMemo{
+		openMeteo: make(map[int]*memoCall[[]openmeteo.Measurement]),
+		openAq:    make(map[openAqMemoKey][]openaq.Measurement),
+	}
+}
+
+// clear drops the fetched measurements. Fetches in progress still complete
+// for the callers waiting for them.
+func (memo *measurementMemo) clear() {
+	memo.mu.Lock()
+	memo.openMeteo = make(map[int]*memoCall[[]openmeteo.Measurement])
+	memo.mu.Unlock()
+	memo.openAqMu.Lock()
+	memo.openAq = make(map[openAqMemoKey][]openaq.Measurement)
+	memo.openAqMu.Unlock()
+}
+
+func memoFrom(ctx context.Context) *measurementMemo {
+	memo, _ := ctx.Value(memoKey{}).(*measurementMemo)
+	return memo
+}
+
+// openMeteoStation returns the measurements of a station, fetching them only
+// if no other fetch of the run did.
+func (memo *measurementMemo) openMeteoStation(ctx context.Context, station int, fetch func() ([]openmeteo.Measurement, error)) ([]openmeteo.Measurement, error) {
+	memo.mu.Lock()
+	call, exists := memo.openMeteo[station]
+	if !exists {
+		call = &memoCall[[]openmeteo.Measurement]{done: make(chan struct{})}
+		memo.openMeteo[station] = call
+	}
+	memo.mu.Unlock()
+	if !exists {
+		call.value, call.err = fetch()
+		close(call.done)
+	}
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	// Every caller sets the station id on its own copy.
+	return slices.Clone(call.value), call.err
+}
+
+// openAqStations returns the measurements of the stations, fetching those of
+// the stations no other fetch of the run asked for in one request.
+func (memo *measurementMemo) openAqStations(stations, parameterIds []int, fetch func(stations []int) ([]openaq.Measurement, error)) ([]openaq.Measurement, error) {
+	memo.openAqMu.Lock()
+	defer memo.openAqMu.Unlock()
+	parameters := fmt.Sprint(parameterIds)
+	var missing []int
+	for _, station := range stations {
+		if _, exists := memo.openAq[openAqMemoKey{station, parameters}]; !exists {
+			missing = append(missing, station)
+		}
+	}
+	if len(missing) > 0 {
+		fetched, err := fetch(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, station := range missing {
+			memo.openAq[openAqMemoKey{station, parameters}] = []openaq.Measurement{}
+		}
+		for _, m := range fetched {
+			key := openAqMemoKey{m.StationId, parameters}
+			memo.openAq[key] = append(memo.openAq[key], m)
+		}
+	}
+	var result []openaq.Measurement
+	for _, station := range stations {
+		result = append(result, memo.openAq[openAqMemoKey{station, parameters}]...)
+	}
+	return result, nil
+}
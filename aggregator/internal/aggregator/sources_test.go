@@ -0,0 +1,88 @@
+package aggregator
+
+import (
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourcesLoadSharesFetch(t *testing.T) {
+	var stationRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stations" {
+			stationRequests.Add(1)
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+	src := newSources(openmeteo.NewClientWithURL(server.URL), openaq.NewClientWithURL(server.URL))
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			_, err := src.load(t.Context(), catalogMaxAge)
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+	// One request to the stations of every source.
+	assert.Equal(t, int32(2), stationRequests.Load())
+
+	_, err := src.load(t.Context(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), stationRequests.Load())
+
+	// Dropped lists are fetched again.
+	src.DropCaches()
+	_, err = src.load(t.Context(), catalogMaxAge)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(6), stationRequests.Load())
+}
+
+func TestMeasurementMemo(t *testing.T) {
+	src := newSources(nil, nil)
+	ctx, done := src.collection(t.Context())
+	defer done()
+	memo := memoFrom(ctx)
+	assert.Nil(t, memoFrom(t.Context()))
+
+	openMeteoFetches := 0
+	fetch := func() ([]openmeteo.Measurement, error) {
+		openMeteoFetches++
+		return []openmeteo.Measurement{{ParameterId: 1, Value: 10}}, nil
+	}
+	for range 2 {
+		m, err := memo.openMeteoStation(ctx, 1, fetch)
+		assert.NoError(t, err)
+		assert.Len(t, m, 1)
+	}
+	assert.Equal(t, 1, openMeteoFetches)
+	src.DropCaches()
+	_, err := memo.openMeteoStation(ctx, 1, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, openMeteoFetches)
+
+	var fetched [][]int
+	fetchOpenAq := func(stations []int) ([]openaq.Measurement, error) {
+		fetched = append(fetched, stations)
+		var m []openaq.Measurement
+		for _, station := range stations {
+			m = append(m, openaq.Measurement{StationId: station, ParameterId: 1, Timestamp: time.Now()})
+		}
+		return m, nil
+	}
+	m, err := memo.openAqStations([]int{1, 2}, []int{1}, fetchOpenAq)
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+	m, err = memo.openAqStations([]int{2, 3}, []int{1}, fetchOpenAq)
+	assert.NoError(t, err)
+	assert.Len(t, m, 2)
+	assert.Equal(t, [][]int{{1, 2}, {3}}, fetched)
+}
@@ -0,0 +1,258 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/matching"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrStationNotFound = errors.New("station not found")
+
+// StationsForRegion lists the stations of all sources located in the region.
+// Stations whose measurements can't be fetched are listed without them.
+func (s *Service) StationsForRegion(ctx context.Context, region api.Region) ([]api.Station, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+
+	openMeteoStations := c.openMeteoMap[region]
+	openAqStations := c.openaqMap[region]
+	results := make([]api.Station, len(openMeteoStations)+len(openAqStations))
+
+	var wg sync.WaitGroup
+	for i, station := range openMeteoStations {
+		wg.Go(func() {
+			results[i] = s.openMeteoStationDetailsOrEmpty(ctx, c, station).Station
+		})
+	}
+	if len(openAqStations) > 0 {
+		wg.Go(func() {
+			for i, details := range s.openAqStationsDetails(ctx, c, openAqStations) {
+				results[len(openMeteoStations)+i] = details.Station
+			}
+		})
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// openMeteoStationDetailsOrEmpty returns the station without measurements
+// when they can't be fetched.
+func (s *Service) openMeteoStationDetailsOrEmpty(ctx context.Context, c cache, station openmeteo.Station) api.StationDetails {
+	details, err := s.openMeteoStationDetails(ctx, c, station)
+	if err != nil {
+		slog.Warn("Fetching station measurements failed", "source", api.OpenMeteo, "station", station.Id, "error", err)
+		return buildOpenMeteoStationDetails(s.params, c, station, nil)
+	}
+	return details
+}
+
+// openAqStationsDetails fetches the measurements of the stations in one
+// request. The stations are returned without measurements when it fails.
+func (s *Service) openAqStationsDetails(ctx context.Context, c cache, stations []openaq.Station) []api.StationDetails {
+	measurements, err := s.openaqClient.GetMeasurements(ctx, stationIds(stations), nil)
+	if err != nil {
+		slog.Warn("Fetching station measurements failed", "source", api.OpenAq, "stations", len(stations), "error", err)
+	}
+	byStation := make(map[int][]openaq.Measurement)
+	for _, m := range measurements {
+		byStation[m.StationId] = append(byStation[m.StationId], m)
+	}
+	results := make([]api.StationDetails, len(stations))
+	for i, station := range stations {
+		results[i] = s.buildOpenAqStationDetails(c, station, byStation[station.Id])
+	}
+	return results
+}
+
+// Station returns a single station with its latest measurements.
+func (s *Service) Station(ctx context.Context, source api.Source, id int) (api.StationDetails, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return api.StationDetails{}, err
+	}
+
+	switch source {
+	case api.OpenMeteo:
+		i := slices.IndexFunc(c.openMeteoStations, func(st openmeteo.Station) bool { return st.Id == id })
+		if i >= 0 {
+			return s.openMeteoStationDetails(ctx, c, c.openMeteoStations[i])
+		}
+	case api.OpenAq:
+		i := slices.IndexFunc(c.openaqStations, func(st openaq.Station) bool { return st.Id == id })
+		if i >= 0 {
+			return s.openAqStationDetails(ctx, c, c.openaqStations[i])
+		}
+	}
+	return api.StationDetails{}, fmt.Errorf("%w: %s/%d", ErrStationNotFound, source, id)
+}
+
+func (s *Service) openMeteoStationDetails(ctx context.Context, c cache, station openmeteo.Station) (_ api.StationDetails, err error) {
+	ctx, span := startSpan(ctx, "aggregator.fetchStation", stationAttrs(api.OpenMeteo, station.Id)...)
+	defer func() { endSpan(span, err) }()
+
+	measurements, err := s.openmeteoClient.GetMeasurementForStation(ctx, station.Id)
+	if err != nil {
+		return api.StationDetails{}, fmt.Errorf("fetching open meteo measurements for station %d: %w", station.Id, err)
+	}
+	return buildOpenMeteoStationDetails(s.params, c, station, measurements), nil
+}
+
+func buildOpenMeteoStationDetails(registry *api.Registry, c cache, station openmeteo.Station, measurements []openmeteo.Measurement) api.StationDetails {
+	details := api.StationDetails{Station: api.Station{
+		Source:    api.OpenMeteo,
+		Id:        station.Id,
+		Name:      station.Name,
+		Latitude:  station.GeoLat,
+		Longitude: station.GeoLon,
+	}}
+	addLatestMeasurements(&details, measurements, buildOpenMeteoParameterMap(registry, c.openMeteoParameters), registry)
+	return details
+}
+
+func (s *Service) openAqStationDetails(ctx context.Context, c cache, station openaq.Station) (_ api.StationDetails, err error) {
+	ctx, span := startSpan(ctx, "aggregator.fetchStation", stationAttrs(api.OpenAq, station.Id)...)
+	defer func() { endSpan(span, err) }()
+
+	measurements, err := s.openaqClient.GetMeasurements(ctx, []int{station.Id}, nil)
+	if err != nil {
+		return api.StationDetails{}, fmt.Errorf("fetching open aq measurements for station %d: %w", station.Id, err)
+	}
+	return s.buildOpenAqStationDetails(c, station, measurements), nil
+}
+
+func (s *Service) buildOpenAqStationDetails(c cache, station openaq.Station, measurements []openaq.Measurement) api.StationDetails {
+	parameterMap := buildOpenAqParameterMap(s.params, c.openaqParameters)
+	details := api.StationDetails{Station: api.Station{
+		Source:    api.OpenAq,
+		Id:        station.Id,
+		Name:      station.Name,
+		Latitude:  station.Lat,
+		Longitude: station.Lon,
+		Locality:  station.Locality,
+	}}
+	for _, id := range station.ParameterIds {
+		if pt, exists := parameterMap[id]; exists && !slices.Contains(details.Parameters, pt) {
+			details.Parameters = append(details.Parameters, pt)
+		}
+	}
+	addLatestMeasurements(&details, measurements, parameterMap, s.params)
+	return details
+}
+
+// addLatestMeasurements keeps the most recent measurement of every supported
+// parameter. Stations that don't declare their parameters are assumed to
+// support the ones they have measurements for.
+func addLatestMeasurements[T measurable](details *api.StationDetails, measurements []T, paramMap map[int]api.ParamType, registry *api.Registry) {
+	latestByType := make(map[api.ParamType]api.StationMeasurement)
+	for paramType, mList := range groupByParamId(measurements, paramMap) {
+		for _, m := range mList {
+			if current, exists := latestByType[paramType]; exists && !m.GetTimestamp().After(current.Timestamp) {
+				continue
+			}
+			d, _ := registry.Definition(paramType)
+			latestByType[paramType] = api.StationMeasurement{
+				Type:      paramType,
+				Value:     m.GetValue(),
+				Unit:      d.Unit,
+				Timestamp: m.GetTimestamp(),
+			}
+		}
+	}
+
+	declared := len(details.Parameters) > 0
+	var lastMeasurement time.Time
+	for _, d := range registry.Definitions() {
+		m, exists := latestByType[d.Type]
+		if !exists {
+			continue
+		}
+		details.Measurements = append(details.Measurements, m)
+		if !declared {
+			details.Parameters = append(details.Parameters, d.Type)
+		}
+		lastMeasurement = latest(lastMeasurement, m.Timestamp)
+	}
+	if !lastMeasurement.IsZero() {
+		details.LastMeasurement = &lastMeasurement
+	}
+}
+
+// RegionStations lists the cached stations of all sources located in the
+// region, without fetching their measurements.
+func (s *Service) RegionStations(region api.Region) ([]api.Station, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+	stations := make([]api.Station, 0, len(c.openMeteoMap[region])+len(c.openaqMap[region]))
+	for _, st := range c.openMeteoMap[region] {
+		stations = append(stations, api.Station{Source: api.OpenMeteo, Id: st.Id, Name: st.Name, Latitude: st.GeoLat, Longitude: st.GeoLon})
+	}
+	for _, st := range c.openaqMap[region] {
+		stations = append(stations, api.Station{Source: api.OpenAq, Id: st.Id, Name: st.Name, Latitude: st.Lat, Longitude: st.Lon, Locality: st.Locality})
+	}
+	return stations, nil
+}
+
+// StationsByKey returns the stations with their latest measurements. The
+// measurements of all OpenAQ stations are fetched in one request. Unknown
+// stations are left out of the result, stations whose measurements can't be
+// fetched are returned without them.
+func (s *Service) StationsByKey(ctx context.Context, keys []matching.Key) (map[matching.Key]api.StationDetails, error) {
+	c, err := s.readyCache()
+	if err != nil {
+		return nil, err
+	}
+
+	var openMeteoStations []openmeteo.Station
+	var openAqStations []openaq.Station
+	for _, key := range slices.Compact(slices.SortedFunc(slices.Values(keys), compareKeys)) {
+		switch key.Source {
+		case api.OpenMeteo:
+			if i := slices.IndexFunc(c.openMeteoStations, func(st openmeteo.Station) bool { return st.Id == key.Id }); i >= 0 {
+				openMeteoStations = append(openMeteoStations, c.openMeteoStations[i])
+			}
+		case api.OpenAq:
+			if i := slices.IndexFunc(c.openaqStations, func(st openaq.Station) bool { return st.Id == key.Id }); i >= 0 {
+				openAqStations = append(openAqStations, c.openaqStations[i])
+			}
+		}
+	}
+
+	results := make([]api.StationDetails, len(openMeteoStations)+len(openAqStations))
+	var wg sync.WaitGroup
+	for i, station := range openMeteoStations {
+		wg.Go(func() {
+			results[i] = s.openMeteoStationDetailsOrEmpty(ctx, c, station)
+		})
+	}
+	if len(openAqStations) > 0 {
+		wg.Go(func() {
+			copy(results[len(openMeteoStations):], s.openAqStationsDetails(ctx, c, openAqStations))
+		})
+	}
+	wg.Wait()
+	byKey := make(map[matching.Key]api.StationDetails, len(results))
+	for _, details := range results {
+		byKey[matching.Key{Source: details.Source, Id: details.Id}] = details
+	}
+	return byKey, nil
+}
+
+func compareKeys(a, b matching.Key) int {
+	if c := cmp.Compare(a.Source, b.Source); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Id, b.Id)
+}
@@ -0,0 +1,183 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/matching"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStationsForRegion(t *testing.T) {
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{
+			{ParameterId: 1, Value: 20, Timestamp: "2025-10-01T11:00:00Z"},
+			{ParameterId: 2, Value: 40, Timestamp: "2025-10-01T12:00:00Z"},
+		})
+	}))
+	defer openMeteoServer.Close()
+
+	var openAqRequests []string
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAqRequests = append(openAqRequests, r.URL.RequestURI())
+		json.NewEncoder(w).Encode([]openaq.Measurement{
+			{ParameterId: 5, StationId: 7, Value: 12, Timestamp: time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)},
+			{ParameterId: 5, StationId: 8, Value: 14, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)},
+		})
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}, {Id: 2, Name: "OZONE"}},
+			openaqParameters:    []openaq.Parameter{{Id: 5, Name: "no2"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1, Name: "Kraków", GeoLat: 50.06, GeoLon: 19.94}}},
+			openaqMap: Map[openaq.Station]{api.Malopolskie: {
+				{Id: 7, Name: "Tarnów", Locality: "Tarnów", ParameterIds: []int{5}},
+				{Id: 8, Name: "Nowy Sącz", Locality: "Nowy Sącz", ParameterIds: []int{5}},
+			}},
+		},
+	}
+
+	stations, err := s.StationsForRegion(t.Context(), api.Malopolskie)
+	assert.NoError(t, err)
+	assert.Len(t, stations, 3)
+	assert.Equal(t, []string{"/measurements?stationIds=7,8"}, openAqRequests)
+
+	lastMeasurement := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, api.Station{
+		Source:          api.OpenMeteo,
+		Id:              1,
+		Name:            "Kraków",
+		Latitude:        50.06,
+		Longitude:       19.94,
+		Parameters:      []api.ParamType{api.PM10, api.O3},
+		LastMeasurement: &lastMeasurement,
+	}, stations[0])
+	tarnowMeasurement := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, api.Station{
+		Source:          api.OpenAq,
+		Id:              7,
+		Name:            "Tarnów",
+		Locality:        "Tarnów",
+		Parameters:      []api.ParamType{api.NO2},
+		LastMeasurement: &tarnowMeasurement,
+	}, stations[1])
+	assert.Equal(t, 8, stations[2].Id)
+}
+
+func TestStationsForRegionWithoutMeasurements(t *testing.T) {
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stations/2/measurements" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{{ParameterId: 1, Value: 20, Timestamp: "2025-10-01T11:00:00Z"}})
+	}))
+	defer openMeteoServer.Close()
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openAqServer.URL),
+		params:          testParams,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openaqParameters:    []openaq.Parameter{{Id: 5, Name: "no2"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1, Name: "Kraków"}, {Id: 2, Name: "Tarnów"}}},
+			openaqMap:           Map[openaq.Station]{api.Malopolskie: {{Id: 7, Name: "Nowy Sącz", ParameterIds: []int{5}}}},
+		},
+	}
+
+	stations, err := s.StationsForRegion(t.Context(), api.Malopolskie)
+	assert.NoError(t, err)
+	assert.Len(t, stations, 3)
+	assert.NotNil(t, stations[0].LastMeasurement)
+	assert.Equal(t, "Tarnów", stations[1].Name)
+	assert.Nil(t, stations[1].LastMeasurement)
+	assert.Equal(t, "Nowy Sącz", stations[2].Name)
+	assert.Equal(t, []api.ParamType{api.NO2}, stations[2].Parameters)
+	assert.Nil(t, stations[2].LastMeasurement)
+}
+
+func TestStation(t *testing.T) {
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openaq.Measurement{
+			{ParameterId: 1, Value: 10, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)},
+			{ParameterId: 1, Value: 15, Timestamp: time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC)},
+			{ParameterId: 99, Value: 1, Timestamp: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)},
+		})
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openaqClient: openaq.NewClientWithURL(openAqServer.URL),
+		params:       testParams,
+		cache: cache{
+			refreshed:        time.Now(),
+			openaqParameters: []openaq.Parameter{{Id: 1, Name: "pm10"}},
+			openaqStations:   []openaq.Station{{Id: 3, Name: "Gdańsk", ParameterIds: []int{1, 99}}},
+		},
+	}
+
+	station, err := s.Station(t.Context(), api.OpenAq, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.ParamType{api.PM10}, station.Parameters)
+	assert.Equal(t, []api.StationMeasurement{
+		{Type: api.PM10, Value: 15, Unit: "µg/m³", Timestamp: time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC)},
+	}, station.Measurements)
+
+	_, err = s.Station(t.Context(), api.OpenMeteo, 3)
+	assert.ErrorIs(t, err, ErrStationNotFound)
+}
+
+func TestStationsByKey(t *testing.T) {
+	var openAqRequests []string
+	openAqServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openAqRequests = append(openAqRequests, r.URL.RequestURI())
+		json.NewEncoder(w).Encode([]openaq.Measurement{
+			{ParameterId: 1, StationId: 3, Value: 10, Timestamp: time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)},
+			{ParameterId: 1, StationId: 4, Value: 20, Timestamp: time.Date(2025, 10, 1, 11, 0, 0, 0, time.UTC)},
+		})
+	}))
+	defer openAqServer.Close()
+
+	s := &Service{
+		openaqClient: openaq.NewClientWithURL(openAqServer.URL),
+		params:       testParams,
+		cache: cache{
+			refreshed:        time.Now(),
+			openaqParameters: []openaq.Parameter{{Id: 1, Name: "pm10"}},
+			openaqStations: []openaq.Station{
+				{Id: 3, Name: "Gdańsk", ParameterIds: []int{1}},
+				{Id: 4, Name: "Sopot", ParameterIds: []int{1}},
+			},
+		},
+	}
+
+	stations, err := s.StationsByKey(t.Context(), []matching.Key{
+		{Source: api.OpenAq, Id: 4},
+		{Source: api.OpenAq, Id: 3},
+		{Source: api.OpenAq, Id: 4},
+		{Source: api.OpenAq, Id: 9},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/measurements?stationIds=3,4"}, openAqRequests)
+	assert.Len(t, stations, 2)
+	assert.Equal(t, "Sopot", stations[matching.Key{Source: api.OpenAq, Id: 4}].Name)
+	assert.Equal(t, float32(10), stations[matching.Key{Source: api.OpenAq, Id: 3}].Measurements[0].Value)
+}
@@ -0,0 +1,35 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("aggregator/internal/aggregator")
+
+// startSpan starts a span for one step of the aggregation fan-out.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on the span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func regionAttr(v api.Region) attribute.KeyValue {
+	return attribute.String("region", string(v))
+}
+
+func stationAttrs(source api.Source, id int) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("station.source", string(source)), attribute.Int("station.id", id)}
+}
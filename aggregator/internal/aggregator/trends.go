@@ -0,0 +1,61 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultTrendThreshold = 5.0
+
+// trendPeriods are the periods the values are compared over.
+var trendPeriods = []struct {
+	name   string
+	offset time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// trendThresholdFromEnv reads the percentage a value has to change by to be
+// rising or falling from TREND_THRESHOLD_PERCENT.
+func trendThresholdFromEnv() (float64, error) {
+	v := os.Getenv("TREND_THRESHOLD_PERCENT")
+	if v == "" {
+		return defaultTrendThreshold, nil
+	}
+	threshold, err := strconv.ParseFloat(v, 64)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("parsing TREND_THRESHOLD_PERCENT: invalid threshold: %q", v)
+	}
+	return threshold, nil
+}
+
+// addChanges compares the hourly values, merged from the measurements of the
+// hour starting at hour only, with the recorded values of the same hour one
+// period earlier. Parameters without an hourly value and periods without a
+// recorded value are left out. The history holds values merged with the
+// configured strategy, so values merged with another one are not compared.
+func (s *Service) addChanges(results *api.AggregatedData, hour time.Time, hourly map[api.ParamType]float32) {
+	if s.history == nil || hour.IsZero() || results.Merge.Strategy != s.merge.info("").Strategy {
+		return
+	}
+	for i := range results.Parameters {
+		p := &results.Parameters[i]
+		value, exists := hourly[p.Type]
+		if p.Value == nil || !exists {
+			continue
+		}
+		for _, period := range trendPeriods {
+			previous := hour.Add(-period.offset)
+			points := s.history.Series(results.Region, p.Type, previous, previous)
+			if len(points) == 0 {
+				continue
+			}
+			p.Changes = append(p.Changes, api.NewChange(period.name, value, points[0].Value, s.trendThreshold))
+		}
+	}
+}
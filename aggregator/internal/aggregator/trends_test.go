@@ -0,0 +1,108 @@
+package aggregator
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateDataIncludesChanges(t *testing.T) {
+	hour := time.Date(2025, 10, 8, 12, 0, 0, 0, time.UTC)
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{
+			{ParameterId: 1, Value: 40, Timestamp: "2025-10-08T12:05:00Z"},
+		})
+	}))
+	defer openMeteoServer.Close()
+
+	store := history.NewStore(historyRetention)
+	store.Record(api.Malopolskie, hour.Add(-time.Hour), map[api.ParamType]float32{api.PM10: 39})
+	store.Record(api.Malopolskie, hour.Add(-7*24*time.Hour), map[api.ParamType]float32{api.PM10: 80})
+
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openMeteoServer.URL),
+		params:          testParams,
+		history:         store,
+		trendThreshold:  5,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+		},
+	}
+
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Params: []api.ParamType{api.PM10}})
+	assert.NoError(t, err)
+	changes := result.Parameters[0].Changes
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "1h", changes[0].Period)
+	assert.Equal(t, api.Stable, changes[0].Trend)
+	assert.Equal(t, "7d", changes[1].Period)
+	assert.Equal(t, float32(-40), changes[1].Delta)
+	assert.Equal(t, api.Falling, changes[1].Trend)
+
+	// The history was merged with the default strategy.
+	result, err = s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Params: []api.ParamType{api.PM10}, Strategy: api.Pooled})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Parameters[0].Changes)
+}
+
+func TestChangesCompareTheHourAlone(t *testing.T) {
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	openMeteoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]openmeteo.Measurement{
+			{ParameterId: 1, Value: 40, Timestamp: hour.Add(5 * time.Minute).Format(time.RFC3339)},
+			{ParameterId: 1, Value: 100},
+		})
+	}))
+	defer openMeteoServer.Close()
+
+	store := history.NewStore(historyRetention)
+	store.Record(api.Malopolskie, hour.Add(-time.Hour), map[api.ParamType]float32{api.PM10: 40})
+	s := &Service{
+		openmeteoClient: openmeteo.NewClientWithURL(openMeteoServer.URL),
+		openaqClient:    openaq.NewClientWithURL(openMeteoServer.URL),
+		params:          testParams,
+		history:         store,
+		trendThreshold:  5,
+		cache: cache{
+			refreshed:           time.Now(),
+			openMeteoParameters: []openmeteo.Parameter{{Id: 1, Name: "PM10"}},
+			openMeteoMap:        Map[openmeteo.Station]{api.Malopolskie: {{Id: 1}}},
+		},
+	}
+
+	// The undated measurement counts towards the current value, but not
+	// towards the change, as the history holds the values of single hours.
+	result, err := s.AggregateForRegion(t.Context(), api.Malopolskie, Options{Params: []api.ParamType{api.PM10}})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(70), *result.Parameters[0].Value)
+	changes := result.Parameters[0].Changes
+	assert.Len(t, changes, 1)
+	assert.Equal(t, float32(0), changes[0].Delta)
+	assert.Equal(t, api.Stable, changes[0].Trend)
+}
+
+func TestTrendThresholdFromEnv(t *testing.T) {
+	threshold, err := trendThresholdFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTrendThreshold, threshold)
+
+	t.Setenv("TREND_THRESHOLD_PERCENT", "10")
+	threshold, err = trendThresholdFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, threshold)
+
+	t.Setenv("TREND_THRESHOLD_PERCENT", "-1")
+	_, err = trendThresholdFromEnv()
+	assert.Error(t, err)
+}
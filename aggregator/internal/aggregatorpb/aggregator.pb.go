@@ -0,0 +1,962 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: aggregator/v1/aggregator.proto
+
+package aggregatorpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ParamStatus int32
+
+const (
+	ParamStatus_PARAM_STATUS_UNSPECIFIED ParamStatus = 0
+	// The value was aggregated from at least one measurement.
+	ParamStatus_PARAM_STATUS_AVAILABLE ParamStatus = 1
+	// A source supports the parameter, but there were no measurements.
+	ParamStatus_PARAM_STATUS_NO_DATA ParamStatus = 2
+	// No source provides the parameter.
+	ParamStatus_PARAM_STATUS_UNSUPPORTED ParamStatus = 3
+)
+
+// Enum value maps for ParamStatus.
+var (
+	ParamStatus_name = map[int32]string{
+		0: "PARAM_STATUS_UNSPECIFIED",
+		1: "PARAM_STATUS_AVAILABLE",
+		2: "PARAM_STATUS_NO_DATA",
+		3: "PARAM_STATUS_UNSUPPORTED",
+	}
+	ParamStatus_value = map[string]int32{
+		"PARAM_STATUS_UNSPECIFIED": 0,
+		"PARAM_STATUS_AVAILABLE":   1,
+		"PARAM_STATUS_NO_DATA":     2,
+		"PARAM_STATUS_UNSUPPORTED": 3,
+	}
+)
+
+func (x ParamStatus) Enum() *ParamStatus {
+	p := new(ParamStatus)
+	*p = x
+	return p
+}
+
+func (x ParamStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ParamStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_aggregator_v1_aggregator_proto_enumTypes[0].Descriptor()
+}
+
+func (ParamStatus) Type() protoreflect.EnumType {
+	return &file_aggregator_v1_aggregator_proto_enumTypes[0]
+}
+
+func (x ParamStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ParamStatus.Descriptor instead.
+func (ParamStatus) EnumDescriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{0}
+}
+
+type Trend int32
+
+const (
+	Trend_TREND_UNSPECIFIED Trend = 0
+	Trend_TREND_RISING      Trend = 1
+	Trend_TREND_FALLING     Trend = 2
+	Trend_TREND_STABLE      Trend = 3
+)
+
+// Enum value maps for Trend.
+var (
+	Trend_name = map[int32]string{
+		0: "TREND_UNSPECIFIED",
+		1: "TREND_RISING",
+		2: "TREND_FALLING",
+		3: "TREND_STABLE",
+	}
+	Trend_value = map[string]int32{
+		"TREND_UNSPECIFIED": 0,
+		"TREND_RISING":      1,
+		"TREND_FALLING":     2,
+		"TREND_STABLE":      3,
+	}
+)
+
+func (x Trend) Enum() *Trend {
+	p := new(Trend)
+	*p = x
+	return p
+}
+
+func (x Trend) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Trend) Descriptor() protoreflect.EnumDescriptor {
+	return file_aggregator_v1_aggregator_proto_enumTypes[1].Descriptor()
+}
+
+func (Trend) Type() protoreflect.EnumType {
+	return &file_aggregator_v1_aggregator_proto_enumTypes[1]
+}
+
+func (x Trend) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Trend.Descriptor instead.
+func (Trend) EnumDescriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{1}
+}
+
+type MergeStrategy int32
+
+const (
+	// The configured strategy in requests.
+	MergeStrategy_MERGE_STRATEGY_UNSPECIFIED      MergeStrategy = 0
+	MergeStrategy_MERGE_STRATEGY_SOURCE_MEAN      MergeStrategy = 1
+	MergeStrategy_MERGE_STRATEGY_POOLED           MergeStrategy = 2
+	MergeStrategy_MERGE_STRATEGY_STATION_WEIGHTED MergeStrategy = 3
+	MergeStrategy_MERGE_STRATEGY_TRUST_WEIGHTED   MergeStrategy = 4
+)
+
+// Enum value maps for MergeStrategy.
+var (
+	MergeStrategy_name = map[int32]string{
+		0: "MERGE_STRATEGY_UNSPECIFIED",
+		1: "MERGE_STRATEGY_SOURCE_MEAN",
+		2: "MERGE_STRATEGY_POOLED",
+		3: "MERGE_STRATEGY_STATION_WEIGHTED",
+		4: "MERGE_STRATEGY_TRUST_WEIGHTED",
+	}
+	MergeStrategy_value = map[string]int32{
+		"MERGE_STRATEGY_UNSPECIFIED":      0,
+		"MERGE_STRATEGY_SOURCE_MEAN":      1,
+		"MERGE_STRATEGY_POOLED":           2,
+		"MERGE_STRATEGY_STATION_WEIGHTED": 3,
+		"MERGE_STRATEGY_TRUST_WEIGHTED":   4,
+	}
+)
+
+func (x MergeStrategy) Enum() *MergeStrategy {
+	p := new(MergeStrategy)
+	*p = x
+	return p
+}
+
+func (x MergeStrategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MergeStrategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_aggregator_v1_aggregator_proto_enumTypes[2].Descriptor()
+}
+
+func (MergeStrategy) Type() protoreflect.EnumType {
+	return &file_aggregator_v1_aggregator_proto_enumTypes[2]
+}
+
+func (x MergeStrategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MergeStrategy.Descriptor instead.
+func (MergeStrategy) EnumDescriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{2}
+}
+
+type AggregateAllRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Region set of the voivodeships, the default set when empty.
+	RegionSet    string   `protobuf:"bytes,1,opt,name=region_set,json=regionSet,proto3" json:"region_set,omitempty"`
+	Voivodeships []string `protobuf:"bytes,2,rep,name=voivodeships,proto3" json:"voivodeships,omitempty"`
+	// Parameters to aggregate, all supported ones when empty.
+	Params []string      `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	Merge  MergeStrategy `protobuf:"varint,4,opt,name=merge,proto3,enum=aggregator.v1.MergeStrategy" json:"merge,omitempty"`
+	// Hour to aggregate, the latest complete hour when unset.
+	Hour *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=hour,proto3" json:"hour,omitempty"`
+	// Language of the names and descriptions, such as "pl". The
+	// accept-language metadata is used when empty.
+	Language      string `protobuf:"bytes,6,opt,name=language,proto3" json:"language,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateAllRequest) Reset() {
+	*x = AggregateAllRequest{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateAllRequest) ProtoMessage() {}
+
+func (x *AggregateAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateAllRequest.ProtoReflect.Descriptor instead.
+func (*AggregateAllRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AggregateAllRequest) GetRegionSet() string {
+	if x != nil {
+		return x.RegionSet
+	}
+	return ""
+}
+
+func (x *AggregateAllRequest) GetVoivodeships() []string {
+	if x != nil {
+		return x.Voivodeships
+	}
+	return nil
+}
+
+func (x *AggregateAllRequest) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *AggregateAllRequest) GetMerge() MergeStrategy {
+	if x != nil {
+		return x.Merge
+	}
+	return MergeStrategy_MERGE_STRATEGY_UNSPECIFIED
+}
+
+func (x *AggregateAllRequest) GetHour() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Hour
+	}
+	return nil
+}
+
+func (x *AggregateAllRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+type AggregateAllResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []*AggregatedData      `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateAllResponse) Reset() {
+	*x = AggregateAllResponse{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateAllResponse) ProtoMessage() {}
+
+func (x *AggregateAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateAllResponse.ProtoReflect.Descriptor instead.
+func (*AggregateAllResponse) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AggregateAllResponse) GetData() []*AggregatedData {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type AggregateForVoivodeshipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RegionSet     string                 `protobuf:"bytes,1,opt,name=region_set,json=regionSet,proto3" json:"region_set,omitempty"`
+	Voivodeship   string                 `protobuf:"bytes,2,opt,name=voivodeship,proto3" json:"voivodeship,omitempty"`
+	Params        []string               `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	Merge         MergeStrategy          `protobuf:"varint,4,opt,name=merge,proto3,enum=aggregator.v1.MergeStrategy" json:"merge,omitempty"`
+	Hour          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=hour,proto3" json:"hour,omitempty"`
+	Language      string                 `protobuf:"bytes,6,opt,name=language,proto3" json:"language,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateForVoivodeshipRequest) Reset() {
+	*x = AggregateForVoivodeshipRequest{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateForVoivodeshipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateForVoivodeshipRequest) ProtoMessage() {}
+
+func (x *AggregateForVoivodeshipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateForVoivodeshipRequest.ProtoReflect.Descriptor instead.
+func (*AggregateForVoivodeshipRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AggregateForVoivodeshipRequest) GetRegionSet() string {
+	if x != nil {
+		return x.RegionSet
+	}
+	return ""
+}
+
+func (x *AggregateForVoivodeshipRequest) GetVoivodeship() string {
+	if x != nil {
+		return x.Voivodeship
+	}
+	return ""
+}
+
+func (x *AggregateForVoivodeshipRequest) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *AggregateForVoivodeshipRequest) GetMerge() MergeStrategy {
+	if x != nil {
+		return x.Merge
+	}
+	return MergeStrategy_MERGE_STRATEGY_UNSPECIFIED
+}
+
+func (x *AggregateForVoivodeshipRequest) GetHour() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Hour
+	}
+	return nil
+}
+
+func (x *AggregateForVoivodeshipRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+type StreamUpdatesRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RegionSet string                 `protobuf:"bytes,1,opt,name=region_set,json=regionSet,proto3" json:"region_set,omitempty"`
+	// Voivodeships to send updates of, all of them when empty.
+	Voivodeships  []string `protobuf:"bytes,2,rep,name=voivodeships,proto3" json:"voivodeships,omitempty"`
+	Params        []string `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	Language      string   `protobuf:"bytes,4,opt,name=language,proto3" json:"language,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamUpdatesRequest) Reset() {
+	*x = StreamUpdatesRequest{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamUpdatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamUpdatesRequest) ProtoMessage() {}
+
+func (x *StreamUpdatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamUpdatesRequest.ProtoReflect.Descriptor instead.
+func (*StreamUpdatesRequest) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamUpdatesRequest) GetRegionSet() string {
+	if x != nil {
+		return x.RegionSet
+	}
+	return ""
+}
+
+func (x *StreamUpdatesRequest) GetVoivodeships() []string {
+	if x != nil {
+		return x.Voivodeships
+	}
+	return nil
+}
+
+func (x *StreamUpdatesRequest) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *StreamUpdatesRequest) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+type Parameter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Display name in the language of the request.
+	Name        string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description string `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Unit        string `protobuf:"bytes,4,opt,name=unit,proto3" json:"unit,omitempty"`
+	// Unset unless the status is available.
+	Value         *float32    `protobuf:"fixed32,5,opt,name=value,proto3,oneof" json:"value,omitempty"`
+	Type          string      `protobuf:"bytes,6,opt,name=type,proto3" json:"type,omitempty"`
+	Status        ParamStatus `protobuf:"varint,7,opt,name=status,proto3,enum=aggregator.v1.ParamStatus" json:"status,omitempty"`
+	Changes       []*Change   `protobuf:"bytes,8,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Parameter) Reset() {
+	*x = Parameter{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Parameter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Parameter) ProtoMessage() {}
+
+func (x *Parameter) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Parameter.ProtoReflect.Descriptor instead.
+func (*Parameter) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Parameter) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Parameter) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Parameter) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Parameter) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *Parameter) GetValue() float32 {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return 0
+}
+
+func (x *Parameter) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Parameter) GetStatus() ParamStatus {
+	if x != nil {
+		return x.Status
+	}
+	return ParamStatus_PARAM_STATUS_UNSPECIFIED
+}
+
+func (x *Parameter) GetChanges() []*Change {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+// Change compares a value with the one of the same parameter a period
+// earlier.
+type Change struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Period   string                 `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	Previous float32                `protobuf:"fixed32,2,opt,name=previous,proto3" json:"previous,omitempty"`
+	Delta    float32                `protobuf:"fixed32,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	// Delta relative to the previous value, unset when the previous value is
+	// zero.
+	Percent       *float64 `protobuf:"fixed64,4,opt,name=percent,proto3,oneof" json:"percent,omitempty"`
+	Trend         Trend    `protobuf:"varint,5,opt,name=trend,proto3,enum=aggregator.v1.Trend" json:"trend,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Change) Reset() {
+	*x = Change{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Change) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Change) ProtoMessage() {}
+
+func (x *Change) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Change.ProtoReflect.Descriptor instead.
+func (*Change) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Change) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *Change) GetPrevious() float32 {
+	if x != nil {
+		return x.Previous
+	}
+	return 0
+}
+
+func (x *Change) GetDelta() float32 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+func (x *Change) GetPercent() float64 {
+	if x != nil && x.Percent != nil {
+		return *x.Percent
+	}
+	return 0
+}
+
+func (x *Change) GetTrend() Trend {
+	if x != nil {
+		return x.Trend
+	}
+	return Trend_TREND_UNSPECIFIED
+}
+
+type MergeInfo struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Strategy MergeStrategy          `protobuf:"varint,1,opt,name=strategy,proto3,enum=aggregator.v1.MergeStrategy" json:"strategy,omitempty"`
+	// Weights by source, for the weighted strategies.
+	Weights       map[string]float64 `protobuf:"bytes,2,rep,name=weights,proto3" json:"weights,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MergeInfo) Reset() {
+	*x = MergeInfo{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergeInfo) ProtoMessage() {}
+
+func (x *MergeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergeInfo.ProtoReflect.Descriptor instead.
+func (*MergeInfo) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *MergeInfo) GetStrategy() MergeStrategy {
+	if x != nil {
+		return x.Strategy
+	}
+	return MergeStrategy_MERGE_STRATEGY_UNSPECIFIED
+}
+
+func (x *MergeInfo) GetWeights() map[string]float64 {
+	if x != nil {
+		return x.Weights
+	}
+	return nil
+}
+
+type AggregatedData struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Voivodeship string                 `protobuf:"bytes,1,opt,name=voivodeship,proto3" json:"voivodeship,omitempty"`
+	// Display name of the voivodeship in the language of the request.
+	VoivodeshipName string       `protobuf:"bytes,2,opt,name=voivodeship_name,json=voivodeshipName,proto3" json:"voivodeship_name,omitempty"`
+	Parameters      []*Parameter `protobuf:"bytes,3,rep,name=parameters,proto3" json:"parameters,omitempty"`
+	// Time of the most recent measurement behind the values.
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Start of the UTC hour the values were aggregated for.
+	Hour          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=hour,proto3" json:"hour,omitempty"`
+	Merge         *MergeInfo             `protobuf:"bytes,6,opt,name=merge,proto3" json:"merge,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregatedData) Reset() {
+	*x = AggregatedData{}
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregatedData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregatedData) ProtoMessage() {}
+
+func (x *AggregatedData) ProtoReflect() protoreflect.Message {
+	mi := &file_aggregator_v1_aggregator_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregatedData.ProtoReflect.Descriptor instead.
+func (*AggregatedData) Descriptor() ([]byte, []int) {
+	return file_aggregator_v1_aggregator_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AggregatedData) GetVoivodeship() string {
+	if x != nil {
+		return x.Voivodeship
+	}
+	return ""
+}
+
+func (x *AggregatedData) GetVoivodeshipName() string {
+	if x != nil {
+		return x.VoivodeshipName
+	}
+	return ""
+}
+
+func (x *AggregatedData) GetParameters() []*Parameter {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+func (x *AggregatedData) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *AggregatedData) GetHour() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Hour
+	}
+	return nil
+}
+
+func (x *AggregatedData) GetMerge() *MergeInfo {
+	if x != nil {
+		return x.Merge
+	}
+	return nil
+}
+
+var File_aggregator_v1_aggregator_proto protoreflect.FileDescriptor
+
+const file_aggregator_v1_aggregator_proto_rawDesc = "" +
+	"\n" +
+	"\x1eaggregator/v1/aggregator.proto\x12\raggregator.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf0\x01\n" +
+	"\x13AggregateAllRequest\x12\x1d\n" +
+	"\n" +
+	"region_set\x18\x01 \x01(\tR\tregionSet\x12\"\n" +
+	"\fvoivodeships\x18\x02 \x03(\tR\fvoivodeships\x12\x16\n" +
+	"\x06params\x18\x03 \x03(\tR\x06params\x122\n" +
+	"\x05merge\x18\x04 \x01(\x0e2\x1c.aggregator.v1.MergeStrategyR\x05merge\x12.\n" +
+	"\x04hour\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04hour\x12\x1a\n" +
+	"\blanguage\x18\x06 \x01(\tR\blanguage\"I\n" +
+	"\x14AggregateAllResponse\x121\n" +
+	"\x04data\x18\x01 \x03(\v2\x1d.aggregator.v1.AggregatedDataR\x04data\"\xf9\x01\n" +
+	"\x1eAggregateForVoivodeshipRequest\x12\x1d\n" +
+	"\n" +
+	"region_set\x18\x01 \x01(\tR\tregionSet\x12 \n" +
+	"\vvoivodeship\x18\x02 \x01(\tR\vvoivodeship\x12\x16\n" +
+	"\x06params\x18\x03 \x03(\tR\x06params\x122\n" +
+	"\x05merge\x18\x04 \x01(\x0e2\x1c.aggregator.v1.MergeStrategyR\x05merge\x12.\n" +
+	"\x04hour\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04hour\x12\x1a\n" +
+	"\blanguage\x18\x06 \x01(\tR\blanguage\"\x8d\x01\n" +
+	"\x14StreamUpdatesRequest\x12\x1d\n" +
+	"\n" +
+	"region_set\x18\x01 \x01(\tR\tregionSet\x12\"\n" +
+	"\fvoivodeships\x18\x02 \x03(\tR\fvoivodeships\x12\x16\n" +
+	"\x06params\x18\x03 \x03(\tR\x06params\x12\x1a\n" +
+	"\blanguage\x18\x04 \x01(\tR\blanguage\"\x83\x02\n" +
+	"\tParameter\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04unit\x18\x04 \x01(\tR\x04unit\x12\x19\n" +
+	"\x05value\x18\x05 \x01(\x02H\x00R\x05value\x88\x01\x01\x12\x12\n" +
+	"\x04type\x18\x06 \x01(\tR\x04type\x122\n" +
+	"\x06status\x18\a \x01(\x0e2\x1a.aggregator.v1.ParamStatusR\x06status\x12/\n" +
+	"\achanges\x18\b \x03(\v2\x15.aggregator.v1.ChangeR\achangesB\b\n" +
+	"\x06_value\"\xa9\x01\n" +
+	"\x06Change\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\tR\x06period\x12\x1a\n" +
+	"\bprevious\x18\x02 \x01(\x02R\bprevious\x12\x14\n" +
+	"\x05delta\x18\x03 \x01(\x02R\x05delta\x12\x1d\n" +
+	"\apercent\x18\x04 \x01(\x01H\x00R\apercent\x88\x01\x01\x12*\n" +
+	"\x05trend\x18\x05 \x01(\x0e2\x14.aggregator.v1.TrendR\x05trendB\n" +
+	"\n" +
+	"\b_percent\"\xc2\x01\n" +
+	"\tMergeInfo\x128\n" +
+	"\bstrategy\x18\x01 \x01(\x0e2\x1c.aggregator.v1.MergeStrategyR\bstrategy\x12?\n" +
+	"\aweights\x18\x02 \x03(\v2%.aggregator.v1.MergeInfo.WeightsEntryR\aweights\x1a:\n" +
+	"\fWeightsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\xb1\x02\n" +
+	"\x0eAggregatedData\x12 \n" +
+	"\vvoivodeship\x18\x01 \x01(\tR\vvoivodeship\x12)\n" +
+	"\x10voivodeship_name\x18\x02 \x01(\tR\x0fvoivodeshipName\x128\n" +
+	"\n" +
+	"parameters\x18\x03 \x03(\v2\x18.aggregator.v1.ParameterR\n" +
+	"parameters\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12.\n" +
+	"\x04hour\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04hour\x12.\n" +
+	"\x05merge\x18\x06 \x01(\v2\x18.aggregator.v1.MergeInfoR\x05merge*\x7f\n" +
+	"\vParamStatus\x12\x1c\n" +
+	"\x18PARAM_STATUS_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16PARAM_STATUS_AVAILABLE\x10\x01\x12\x18\n" +
+	"\x14PARAM_STATUS_NO_DATA\x10\x02\x12\x1c\n" +
+	"\x18PARAM_STATUS_UNSUPPORTED\x10\x03*U\n" +
+	"\x05Trend\x12\x15\n" +
+	"\x11TREND_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fTREND_RISING\x10\x01\x12\x11\n" +
+	"\rTREND_FALLING\x10\x02\x12\x10\n" +
+	"\fTREND_STABLE\x10\x03*\xb2\x01\n" +
+	"\rMergeStrategy\x12\x1e\n" +
+	"\x1aMERGE_STRATEGY_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aMERGE_STRATEGY_SOURCE_MEAN\x10\x01\x12\x19\n" +
+	"\x15MERGE_STRATEGY_POOLED\x10\x02\x12#\n" +
+	"\x1fMERGE_STRATEGY_STATION_WEIGHTED\x10\x03\x12!\n" +
+	"\x1dMERGE_STRATEGY_TRUST_WEIGHTED\x10\x042\xa5\x02\n" +
+	"\n" +
+	"Aggregator\x12W\n" +
+	"\fAggregateAll\x12\".aggregator.v1.AggregateAllRequest\x1a#.aggregator.v1.AggregateAllResponse\x12g\n" +
+	"\x17AggregateForVoivodeship\x12-.aggregator.v1.AggregateForVoivodeshipRequest\x1a\x1d.aggregator.v1.AggregatedData\x12U\n" +
+	"\rStreamUpdates\x12#.aggregator.v1.StreamUpdatesRequest\x1a\x1d.aggregator.v1.AggregatedData0\x01B\"Z aggregator/internal/aggregatorpbb\x06proto3"
+
+var (
+	file_aggregator_v1_aggregator_proto_rawDescOnce sync.Once
+	file_aggregator_v1_aggregator_proto_rawDescData []byte
+)
+
+func file_aggregator_v1_aggregator_proto_rawDescGZIP() []byte {
+	file_aggregator_v1_aggregator_proto_rawDescOnce.Do(func() {
+		file_aggregator_v1_aggregator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_aggregator_v1_aggregator_proto_rawDesc), len(file_aggregator_v1_aggregator_proto_rawDesc)))
+	})
+	return file_aggregator_v1_aggregator_proto_rawDescData
+}
+
+var file_aggregator_v1_aggregator_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_aggregator_v1_aggregator_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_aggregator_v1_aggregator_proto_goTypes = []any{
+	(ParamStatus)(0),                       // 0: aggregator.v1.ParamStatus
+	(Trend)(0),                             // 1: aggregator.v1.Trend
+	(MergeStrategy)(0),                     // 2: aggregator.v1.MergeStrategy
+	(*AggregateAllRequest)(nil),            // 3: aggregator.v1.AggregateAllRequest
+	(*AggregateAllResponse)(nil),           // 4: aggregator.v1.AggregateAllResponse
+	(*AggregateForVoivodeshipRequest)(nil), // 5: aggregator.v1.AggregateForVoivodeshipRequest
+	(*StreamUpdatesRequest)(nil),           // 6: aggregator.v1.StreamUpdatesRequest
+	(*Parameter)(nil),                      // 7: aggregator.v1.Parameter
+	(*Change)(nil),                         // 8: aggregator.v1.Change
+	(*MergeInfo)(nil),                      // 9: aggregator.v1.MergeInfo
+	(*AggregatedData)(nil),                 // 10: aggregator.v1.AggregatedData
+	nil,                                    // 11: aggregator.v1.MergeInfo.WeightsEntry
+	(*timestamppb.Timestamp)(nil),          // 12: google.protobuf.Timestamp
+}
+var file_aggregator_v1_aggregator_proto_depIdxs = []int32{
+	2,  // 0: aggregator.v1.AggregateAllRequest.merge:type_name -> aggregator.v1.MergeStrategy
+	12, // 1: aggregator.v1.AggregateAllRequest.hour:type_name -> google.protobuf.Timestamp
+	10, // 2: aggregator.v1.AggregateAllResponse.data:type_name -> aggregator.v1.AggregatedData
+	2,  // 3: aggregator.v1.AggregateForVoivodeshipRequest.merge:type_name -> aggregator.v1.MergeStrategy
+	12, // 4: aggregator.v1.AggregateForVoivodeshipRequest.hour:type_name -> google.protobuf.Timestamp
+	0,  // 5: aggregator.v1.Parameter.status:type_name -> aggregator.v1.ParamStatus
+	8,  // 6: aggregator.v1.Parameter.changes:type_name -> aggregator.v1.Change
+	1,  // 7: aggregator.v1.Change.trend:type_name -> aggregator.v1.Trend
+	2,  // 8: aggregator.v1.MergeInfo.strategy:type_name -> aggregator.v1.MergeStrategy
+	11, // 9: aggregator.v1.MergeInfo.weights:type_name -> aggregator.v1.MergeInfo.WeightsEntry
+	7,  // 10: aggregator.v1.AggregatedData.parameters:type_name -> aggregator.v1.Parameter
+	12, // 11: aggregator.v1.AggregatedData.timestamp:type_name -> google.protobuf.Timestamp
+	12, // 12: aggregator.v1.AggregatedData.hour:type_name -> google.protobuf.Timestamp
+	9,  // 13: aggregator.v1.AggregatedData.merge:type_name -> aggregator.v1.MergeInfo
+	3,  // 14: aggregator.v1.Aggregator.AggregateAll:input_type -> aggregator.v1.AggregateAllRequest
+	5,  // 15: aggregator.v1.Aggregator.AggregateForVoivodeship:input_type -> aggregator.v1.AggregateForVoivodeshipRequest
+	6,  // 16: aggregator.v1.Aggregator.StreamUpdates:input_type -> aggregator.v1.StreamUpdatesRequest
+	4,  // 17: aggregator.v1.Aggregator.AggregateAll:output_type -> aggregator.v1.AggregateAllResponse
+	10, // 18: aggregator.v1.Aggregator.AggregateForVoivodeship:output_type -> aggregator.v1.AggregatedData
+	10, // 19: aggregator.v1.Aggregator.StreamUpdates:output_type -> aggregator.v1.AggregatedData
+	17, // [17:20] is the sub-list for method output_type
+	14, // [14:17] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_aggregator_v1_aggregator_proto_init() }
+func file_aggregator_v1_aggregator_proto_init() {
+	if File_aggregator_v1_aggregator_proto != nil {
+		return
+	}
+	file_aggregator_v1_aggregator_proto_msgTypes[4].OneofWrappers = []any{}
+	file_aggregator_v1_aggregator_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_aggregator_v1_aggregator_proto_rawDesc), len(file_aggregator_v1_aggregator_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_aggregator_v1_aggregator_proto_goTypes,
+		DependencyIndexes: file_aggregator_v1_aggregator_proto_depIdxs,
+		EnumInfos:         file_aggregator_v1_aggregator_proto_enumTypes,
+		MessageInfos:      file_aggregator_v1_aggregator_proto_msgTypes,
+	}.Build()
+	File_aggregator_v1_aggregator_proto = out.File
+	file_aggregator_v1_aggregator_proto_goTypes = nil
+	file_aggregator_v1_aggregator_proto_depIdxs = nil
+}
@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: aggregator/v1/aggregator.proto
+
+package aggregatorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Aggregator_AggregateAll_FullMethodName            = "/aggregator.v1.Aggregator/AggregateAll"
+	Aggregator_AggregateForVoivodeship_FullMethodName = "/aggregator.v1.Aggregator/AggregateForVoivodeship"
+	Aggregator_StreamUpdates_FullMethodName           = "/aggregator.v1.Aggregator/StreamUpdates"
+)
+
+// AggregatorClient is the client API for Aggregator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Aggregator serves the aggregated air quality data of the regions of a
+// region set, like the REST API does.
+type AggregatorClient interface {
+	// AggregateAll aggregates data for the given voivodeships, or for all of
+	// them when none are given.
+	AggregateAll(ctx context.Context, in *AggregateAllRequest, opts ...grpc.CallOption) (*AggregateAllResponse, error)
+	// AggregateForVoivodeship aggregates data for a single voivodeship.
+	AggregateForVoivodeship(ctx context.Context, in *AggregateForVoivodeshipRequest, opts ...grpc.CallOption) (*AggregatedData, error)
+	// StreamUpdates sends the current data of the selected voivodeships and
+	// then the data of every voivodeship whose data changes.
+	StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregatedData], error)
+}
+
+type aggregatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAggregatorClient(cc grpc.ClientConnInterface) AggregatorClient {
+	return &aggregatorClient{cc}
+}
+
+func (c *aggregatorClient) AggregateAll(ctx context.Context, in *AggregateAllRequest, opts ...grpc.CallOption) (*AggregateAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregateAllResponse)
+	err := c.cc.Invoke(ctx, Aggregator_AggregateAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aggregatorClient) AggregateForVoivodeship(ctx context.Context, in *AggregateForVoivodeshipRequest, opts ...grpc.CallOption) (*AggregatedData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregatedData)
+	err := c.cc.Invoke(ctx, Aggregator_AggregateForVoivodeship_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aggregatorClient) StreamUpdates(ctx context.Context, in *StreamUpdatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AggregatedData], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Aggregator_ServiceDesc.Streams[0], Aggregator_StreamUpdates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamUpdatesRequest, AggregatedData]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Aggregator_StreamUpdatesClient = grpc.ServerStreamingClient[AggregatedData]
+
+// AggregatorServer is the server API for Aggregator service.
+// All implementations must embed UnimplementedAggregatorServer
+// for forward compatibility.
+//
+// Aggregator serves the aggregated air quality data of the regions of a
+// region set, like the REST API does.
+type AggregatorServer interface {
+	// AggregateAll aggregates data for the given voivodeships, or for all of
+	// them when none are given.
+	AggregateAll(context.Context, *AggregateAllRequest) (*AggregateAllResponse, error)
+	// AggregateForVoivodeship aggregates data for a single voivodeship.
+	AggregateForVoivodeship(context.Context, *AggregateForVoivodeshipRequest) (*AggregatedData, error)
+	// StreamUpdates sends the current data of the selected voivodeships and
+	// then the data of every voivodeship whose data changes.
+	StreamUpdates(*StreamUpdatesRequest, grpc.ServerStreamingServer[AggregatedData]) error
+	mustEmbedUnimplementedAggregatorServer()
+}
+
+// UnimplementedAggregatorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAggregatorServer struct{}
+
+func (UnimplementedAggregatorServer) AggregateAll(context.Context, *AggregateAllRequest) (*AggregateAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AggregateAll not implemented")
+}
+func (UnimplementedAggregatorServer) AggregateForVoivodeship(context.Context, *AggregateForVoivodeshipRequest) (*AggregatedData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AggregateForVoivodeship not implemented")
+}
+func (UnimplementedAggregatorServer) StreamUpdates(*StreamUpdatesRequest, grpc.ServerStreamingServer[AggregatedData]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUpdates not implemented")
+}
+func (UnimplementedAggregatorServer) mustEmbedUnimplementedAggregatorServer() {}
+func (UnimplementedAggregatorServer) testEmbeddedByValue()                    {}
+
+// UnsafeAggregatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AggregatorServer will
+// result in compilation errors.
+type UnsafeAggregatorServer interface {
+	mustEmbedUnimplementedAggregatorServer()
+}
+
+func RegisterAggregatorServer(s grpc.ServiceRegistrar, srv AggregatorServer) {
+	// If the following call pancis, it indicates UnimplementedAggregatorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Aggregator_ServiceDesc, srv)
+}
+
+func _Aggregator_AggregateAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AggregatorServer).AggregateAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aggregator_AggregateAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AggregatorServer).AggregateAll(ctx, req.(*AggregateAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aggregator_AggregateForVoivodeship_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateForVoivodeshipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AggregatorServer).AggregateForVoivodeship(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aggregator_AggregateForVoivodeship_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AggregatorServer).AggregateForVoivodeship(ctx, req.(*AggregateForVoivodeshipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aggregator_StreamUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamUpdatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AggregatorServer).StreamUpdates(m, &grpc.GenericServerStream[StreamUpdatesRequest, AggregatedData]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Aggregator_StreamUpdatesServer = grpc.ServerStreamingServer[AggregatedData]
+
+// Aggregator_ServiceDesc is the grpc.ServiceDesc for Aggregator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Aggregator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aggregator.v1.Aggregator",
+	HandlerType: (*AggregatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AggregateAll",
+			Handler:    _Aggregator_AggregateAll_Handler,
+		},
+		{
+			MethodName: "AggregateForVoivodeship",
+			Handler:    _Aggregator_AggregateForVoivodeship_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUpdates",
+			Handler:       _Aggregator_StreamUpdates_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "aggregator/v1/aggregator.proto",
+}
@@ -0,0 +1,5 @@
+// Package aggregatorpb holds the protobuf messages and gRPC service of the
+// aggregator, generated from proto/aggregator/v1/aggregator.proto.
+package aggregatorpb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=aggregator --go-grpc_out=../.. --go-grpc_opt=module=aggregator aggregator/v1/aggregator.proto
@@ -1,79 +1,80 @@
 package api
 
 import (
+	"aggregator/internal/openaq"
 	"aggregator/internal/openmeteo"
 	"fmt"
 	"strings"
 )
 
-func MapOpenMeteoParameter(parameter openmeteo.Parameter) (Parameter, error) {
-	paramType, err := MapOpenMeteoParamName(parameter.Name)
+func MapOpenMeteoParameter(registry *Registry, parameter openmeteo.Parameter) (Parameter, error) {
+	paramType, err := registry.Lookup(OpenMeteo, parameter.Name)
 	if err != nil {
-		return Parameter{}, fmt.Errorf("unsupported paramName: %s", parameter.Name)
+		return Parameter{}, err
 	}
 	return Parameter{
 		Description: parameter.Description,
 		Unit:        parameter.Unit,
 		Type:        paramType,
-		Id:          validParamTypes[paramType],
+		Id:          registry.Id(paramType),
 	}, nil
 }
 
-func MapOpenMeteoParamName(paramName string) (ParamType, error) {
-	switch paramName {
-	case "PM10":
-		return PM10, nil
-	case "PM2_5":
-		return PM2_5, nil
-	case "CARBON_MONOXIDE":
-		return CO, nil
-	case "CARBON_DIOXIDE":
-		return CO2, nil
-	case "NITROGEN_DIOXIDE":
-		return NO2, nil
-	case "SULPHUR_DIOXIDE":
-		return SO2, nil
-	case "OZONE":
-		return O3, nil
-	case "METHANE":
-		return CH4, nil
-	default:
-		return "", fmt.Errorf("unsupported paramName: %s", paramName)
+func MapOpenAqParameter(registry *Registry, parameter openaq.Parameter) (Parameter, error) {
+	paramType, err := registry.Lookup(OpenAq, parameter.Name)
+	if err != nil {
+		return Parameter{}, err
+	}
+	description := parameter.Description
+	if description == "" {
+		description = parameter.DisplayName
+	}
+	return Parameter{
+		Description: description,
+		Unit:        parameter.Units,
+		Type:        paramType,
+		Id:          registry.Id(paramType),
+	}, nil
+}
+
+// MapOpenMeteoParameters maps the parameters known to the registry and skips the rest.
+func MapOpenMeteoParameters(registry *Registry, parameters []openmeteo.Parameter) []Parameter {
+	var result []Parameter
+	for _, p := range parameters {
+		if param, err := MapOpenMeteoParameter(registry, p); err == nil {
+			result = append(result, param)
+		}
+	}
+	return result
+}
+
+// MapOpenAqParameters maps the parameters known to the registry and skips the rest.
+func MapOpenAqParameters(registry *Registry, parameters []openaq.Parameter) []Parameter {
+	var result []Parameter
+	for _, p := range parameters {
+		if param, err := MapOpenAqParameter(registry, p); err == nil {
+			result = append(result, param)
+		}
 	}
+	return result
 }
 
-func MapOpenAqParamName(paramName string) (ParamType, error) {
-	switch paramName {
-	case "pm10":
-		return PM10, nil
-	case "pm25":
-		return PM2_5, nil
-	case "co":
-		return CO, nil
-	case "co2":
-		return CO2, nil
-	case "no2":
-		return NO2, nil
-	case "so2":
-		return SO2, nil
-	case "o3":
-		return O3, nil
-	case "ch4":
-		return CH4, nil
+func MapSource(s string) (Source, error) {
+	source := Source(strings.ToLower(s))
+	switch source {
+	case OpenMeteo, OpenAq:
+		return source, nil
 	default:
-		return "", fmt.Errorf("unsupported paramName: %s", paramName)
+		return "", fmt.Errorf("unknown source: %s", s)
 	}
 }
 
-func MapVoivodeship(s string) (Voivodeship, error) {
-	v := Voivodeship(strings.ToLower(s))
-	switch v {
-	case Dolnoslaskie, KujawskoPomorskie, Lubelskie, Lubuskie, Lodzkie,
-		Malopolskie, Mazowieckie, Opolskie, Podkarpackie, Podlaskie,
-		Pomorskie, Slaskie, Swietokrzyskie, WarminskoMazurskie,
-		Wielkopolskie, Zachodniopomorskie:
-		return v, nil
+func MapMergeStrategy(s string) (MergeStrategy, error) {
+	strategy := MergeStrategy(strings.ToLower(s))
+	switch strategy {
+	case SourceMean, Pooled, StationWeighted, TrustWeighted:
+		return strategy, nil
 	default:
-		return "", fmt.Errorf("unknown voivodeship: %s", s)
+		return "", fmt.Errorf("unknown merge strategy: %s", s)
 	}
 }
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Bounds is the bounding box of a region.
+type Bounds struct {
+	MaxLatitude  float64 `json:"maxLat"`
+	MinLatitude  float64 `json:"minLat"`
+	MaxLongitude float64 `json:"maxLon"`
+	MinLongitude float64 `json:"minLon"`
+}
+
+func (b Bounds) validate() error {
+	switch {
+	case b.MinLatitude < -90 || b.MaxLatitude > 90:
+		return fmt.Errorf("latitude out of range")
+	case b.MinLongitude < -180 || b.MaxLongitude > 180:
+		return fmt.Errorf("longitude out of range")
+	case b.MinLatitude >= b.MaxLatitude || b.MinLongitude >= b.MaxLongitude:
+		return fmt.Errorf("empty bounding box")
+	}
+	return nil
+}
+
+func (b Bounds) Contains(latitude, longitude float64) bool {
+	return latitude >= b.MinLatitude &&
+		latitude <= b.MaxLatitude &&
+		longitude >= b.MinLongitude &&
+		longitude <= b.MaxLongitude
+}
+
+// RegionSet is a named set of regions of one country, such as the Polish
+// voivodeships, each with its own boundaries.
+type RegionSet struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Country is the ISO 3166-1 alpha-2 code of the country.
+	Country string `json:"country"`
+	// Regions lists the regions in alphabetical order.
+	Regions []Region          `json:"regions"`
+	Bounds  map[Region]Bounds `json:"-"`
+	// BoundariesFile is the file the bounds were loaded from, if any.
+	BoundariesFile string `json:"-"`
+	// Location is the time zone the days of the set are counted in, UTC
+	// when unset.
+	Location *time.Location `json:"-"`
+}
+
+// Day returns the start of the day of the set the time falls into.
+func (rs RegionSet) Day(t time.Time) time.Time {
+	loc := rs.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func NewRegionSet(id, name, country string, bounds map[Region]Bounds) RegionSet {
+	return RegionSet{
+		Id:      id,
+		Name:    name,
+		Country: country,
+		Regions: slices.Sorted(maps.Keys(bounds)),
+		Bounds:  bounds,
+	}
+}
+
+// Region returns the region of the set with the given name.
+func (rs RegionSet) Region(name string) (Region, error) {
+	region := Region(strings.ToLower(name))
+	if _, exists := rs.Bounds[region]; !exists {
+		return "", fmt.Errorf("unknown region of %s: %s", rs.Id, name)
+	}
+	return region, nil
+}
+
+// regionSetConfig is an entry of the region sets file. Boundaries is the
+// path of the file with the bounds of the regions, relative to the region
+// sets file.
+type regionSetConfig struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Country    string `json:"country"`
+	Boundaries string `json:"boundaries"`
+	// Timezone is the IANA time zone the days are counted in.
+	Timezone string `json:"timezone"`
+}
+
+// LoadRegionSets loads the region sets listed in the file, in their order.
+func LoadRegionSets(path string) ([]RegionSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading region sets file: %w", err)
+	}
+	var configs []regionSetConfig
+	if err = json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing region sets file: %w", err)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("region sets file %s lists no region sets", path)
+	}
+
+	sets := make([]RegionSet, 0, len(configs))
+	for i, c := range configs {
+		if c.Id == "" {
+			return nil, fmt.Errorf("region set %d has no id", i+1)
+		}
+		if slices.ContainsFunc(sets, func(rs RegionSet) bool { return rs.Id == c.Id }) {
+			return nil, fmt.Errorf("duplicate region set: %s", c.Id)
+		}
+		boundariesFile := filepath.Join(filepath.Dir(path), c.Boundaries)
+		bounds, err := loadBounds(boundariesFile)
+		if err != nil {
+			return nil, fmt.Errorf("region set %s: %w", c.Id, err)
+		}
+		set := NewRegionSet(c.Id, c.Name, strings.ToUpper(c.Country), bounds)
+		set.BoundariesFile = boundariesFile
+		if set.Location, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("region set %s: loading time zone: %w", c.Id, err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+func loadBounds(path string) (map[Region]Bounds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading region bounds file: %w", err)
+	}
+	var raw map[string]Bounds
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing region bounds file: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("region bounds file %s lists no regions", path)
+	}
+	bounds := make(map[Region]Bounds, len(raw))
+	for name, b := range raw {
+		if err = b.validate(); err != nil {
+			return nil, fmt.Errorf("bounds of %s: %w", name, err)
+		}
+		region := Region(strings.ToLower(name))
+		if _, exists := bounds[region]; exists {
+			return nil, fmt.Errorf("duplicate region: %s", name)
+		}
+		bounds[region] = b
+	}
+	return bounds, nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundsContains(t *testing.T) {
+	bounds := Bounds{MaxLatitude: 10, MinLatitude: 5, MaxLongitude: 20, MinLongitude: 5}
+	assert.True(t, bounds.Contains(8, 10))
+	assert.False(t, bounds.Contains(20, 10))
+	assert.False(t, bounds.Contains(8, 2))
+}
+
+func TestLoadRegionSets(t *testing.T) {
+	sets, err := LoadRegionSets("../../config/regions.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "pl", sets[0].Id)
+	assert.Equal(t, "PL", sets[0].Country)
+	assert.Len(t, sets[0].Regions, 16)
+	assert.Equal(t, Dolnoslaskie, sets[0].Regions[0])
+
+	region, err := sets[0].Region("Malopolskie")
+	assert.NoError(t, err)
+	assert.Equal(t, Malopolskie, region)
+	_, err = sets[0].Region("bayern")
+	assert.EqualError(t, err, "unknown region of pl: bayern")
+
+	for _, set := range sets[1:] {
+		assert.NotEmpty(t, set.Regions, set.Id)
+	}
+
+	// Days are counted in the time zone of the set.
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 10, 2, 0, 0, 0, 0, warsaw), sets[0].Day(time.Date(2025, 10, 1, 22, 30, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), RegionSet{}.Day(time.Date(2025, 10, 1, 22, 30, 0, 0, time.UTC)))
+}
+
+func TestLoadRegionSetsErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "regions.json")
+	os.WriteFile(filepath.Join(dir, "pl.json"), []byte(`{"slaskie": {"minLat": 49, "maxLat": 51, "minLon": 18.2, "maxLon": 19.5}}`), 0o644)
+
+	os.WriteFile(path, []byte(`[{"id": "pl", "boundaries": "pl.json"}, {"id": "pl", "boundaries": "pl.json"}]`), 0o644)
+	_, err := LoadRegionSets(path)
+	assert.EqualError(t, err, "duplicate region set: pl")
+
+	os.WriteFile(path, []byte(`[{"id": "de", "boundaries": "de.json"}]`), 0o644)
+	_, err = LoadRegionSets(path)
+	assert.ErrorContains(t, err, "region set de: reading region bounds file")
+
+	os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"bayern": {"minLat": 50.6, "maxLat": 47.3, "minLon": 10.5, "maxLon": 13.8}}`), 0o644)
+	_, err = LoadRegionSets(path)
+	assert.EqualError(t, err, "region set de: bounds of bayern: empty bounding box")
+
+	os.WriteFile(path, []byte(`[{"id": "pl", "boundaries": "pl.json", "timezone": "Europe/Nowhere"}]`), 0o644)
+	_, err = LoadRegionSets(path)
+	assert.ErrorContains(t, err, "region set pl: loading time zone")
+
+	os.WriteFile(path, []byte(`[]`), 0o644)
+	_, err = LoadRegionSets(path)
+	assert.ErrorContains(t, err, "lists no region sets")
+}
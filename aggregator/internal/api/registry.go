@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Source string
+
+const (
+	OpenMeteo Source = "openmeteo"
+	OpenAq    Source = "openaq"
+)
+
+// ParamDefinition describes a canonical parameter, its unit and the names
+// under which each source reports it.
+type ParamDefinition struct {
+	Type        ParamType           `json:"type"`
+	Unit        string              `json:"unit"`
+	Description string              `json:"description"`
+	Aliases     map[Source][]string `json:"aliases"`
+}
+
+// Registry holds the supported parameters. Parameter ids are assigned from
+// the order of the definitions, starting at 1.
+type Registry struct {
+	definitions []ParamDefinition
+	ids         map[ParamType]int
+	aliases     map[Source]map[string]ParamType
+}
+
+func NewRegistry(definitions []ParamDefinition) (*Registry, error) {
+	r := &Registry{
+		definitions: definitions,
+		ids:         make(map[ParamType]int, len(definitions)),
+		aliases:     make(map[Source]map[string]ParamType),
+	}
+	for i, d := range definitions {
+		if d.Type == "" {
+			return nil, fmt.Errorf("parameter definition %d has no type", i+1)
+		}
+		if _, exists := r.ids[d.Type]; exists {
+			return nil, fmt.Errorf("duplicate parameter type: %s", d.Type)
+		}
+		r.ids[d.Type] = i + 1
+		for source, names := range d.Aliases {
+			if r.aliases[source] == nil {
+				r.aliases[source] = make(map[string]ParamType)
+			}
+			for _, name := range names {
+				key := strings.ToLower(name)
+				if other, exists := r.aliases[source][key]; exists {
+					return nil, fmt.Errorf("%s alias %q used by both %s and %s", source, name, other, d.Type)
+				}
+				r.aliases[source][key] = d.Type
+			}
+		}
+	}
+	return r, nil
+}
+
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading parameters file: %w", err)
+	}
+	var definitions []ParamDefinition
+	if err = json.Unmarshal(data, &definitions); err != nil {
+		return nil, fmt.Errorf("parsing parameters file: %w", err)
+	}
+	return NewRegistry(definitions)
+}
+
+func (r *Registry) Definitions() []ParamDefinition {
+	return r.definitions
+}
+
+func (r *Registry) Definition(paramType ParamType) (ParamDefinition, bool) {
+	id, exists := r.ids[paramType]
+	if !exists {
+		return ParamDefinition{}, false
+	}
+	return r.definitions[id-1], true
+}
+
+func (r *Registry) Id(paramType ParamType) int {
+	return r.ids[paramType]
+}
+
+// Lookup maps the name a source uses for a parameter to its canonical type.
+func (r *Registry) Lookup(source Source, name string) (ParamType, error) {
+	paramType, exists := r.aliases[source][strings.ToLower(name)]
+	if !exists {
+		return "", fmt.Errorf("unsupported %s paramName: %s", source, name)
+	}
+	return paramType, nil
+}
+
+// ParamType maps user input such as "pm2_5" to a registered parameter type.
+func (r *Registry) ParamType(s string) (ParamType, error) {
+	for _, d := range r.definitions {
+		if strings.EqualFold(string(d.Type), s) {
+			return d.Type, nil
+		}
+	}
+	return "", fmt.Errorf("unknown parameter: %s", s)
+}
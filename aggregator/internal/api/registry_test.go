@@ -0,0 +1,73 @@
+package api
+
+import (
+	"aggregator/internal/openaq"
+	"aggregator/internal/openmeteo"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	registry, err := LoadRegistry("../../config/parameters.json")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, registry.Id(PM10))
+	assert.Equal(t, 8, registry.Id(CH4))
+
+	paramType, err := registry.Lookup(OpenMeteo, "NITROGEN_DIOXIDE")
+	assert.NoError(t, err)
+	assert.Equal(t, NO2, paramType)
+	paramType, err = registry.Lookup(OpenAq, "PM25")
+	assert.NoError(t, err)
+	assert.Equal(t, PM2_5, paramType)
+	_, err = registry.Lookup(OpenMeteo, "pm25")
+	assert.Error(t, err)
+
+	paramType, err = registry.ParamType("nh3")
+	assert.NoError(t, err)
+	assert.Equal(t, ParamType("NH3"), paramType)
+}
+
+func TestNewRegistryRejectsDuplicates(t *testing.T) {
+	_, err := NewRegistry([]ParamDefinition{{Type: PM10}, {Type: PM10}})
+	assert.ErrorContains(t, err, "duplicate parameter type")
+
+	_, err = NewRegistry([]ParamDefinition{
+		{Type: PM10, Aliases: map[Source][]string{OpenAq: {"pm"}}},
+		{Type: PM2_5, Aliases: map[Source][]string{OpenAq: {"PM"}}},
+	})
+	assert.ErrorContains(t, err, "alias")
+}
+
+func TestAddParamInfo(t *testing.T) {
+	registry, err := NewRegistry([]ParamDefinition{
+		{Type: PM10, Unit: "µg/m³", Aliases: map[Source][]string{OpenMeteo: {"PM10"}, OpenAq: {"pm10"}}},
+		{Type: "PM1", Description: "Particulate matter 1", Aliases: map[Source][]string{OpenAq: {"pm1"}}},
+		{Type: "BC", Description: "Black carbon", Aliases: map[Source][]string{OpenAq: {"bc"}}},
+	})
+	assert.NoError(t, err)
+
+	var ad AggregatedData
+	ad.AddParamInfo(registry,
+		MapOpenMeteoParameters(registry, []openmeteo.Parameter{
+			{Id: 7, Name: "PM10", Unit: "ug/m3", Description: "Particulate matter"},
+			{Id: 8, Name: "UV_INDEX"},
+		}),
+		MapOpenAqParameters(registry, []openaq.Parameter{
+			{Id: 1, Name: "pm10", Units: "µg/m³", DisplayName: "PM10"},
+			{Id: 19, Name: "pm1", Units: "µg/m³", DisplayName: "PM1"},
+		}),
+	)
+	assert.Equal(t, []Parameter{
+		{Id: 1, Description: "Particulate matter", Unit: "µg/m³", Type: PM10, Status: NoData},
+		{Id: 2, Description: "PM1", Unit: "µg/m³", Type: "PM1", Status: NoData},
+		{Id: 3, Description: "Black carbon", Type: "BC", Status: Unsupported},
+	}, ad.Parameters)
+
+	ad.AddParamValues(map[ParamType]float32{PM10: 0}, time.Time{})
+	assert.Equal(t, Available, ad.Parameters[0].Status)
+	assert.Equal(t, float32(0), *ad.Parameters[0].Value)
+	assert.Nil(t, ad.Parameters[1].Value)
+	assert.Empty(t, ad.Timestamp)
+}
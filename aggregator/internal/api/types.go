@@ -1,44 +1,41 @@
 package api
 
 import (
-	"aggregator/internal/openmeteo"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 )
 
-var validParamTypes = map[ParamType]int{
-	"PM10":  1,
-	"PM2_5": 2,
-	"CO":    3,
-	"CO2":   4,
-	"NO2":   5,
-	"SO2":   6,
-	"O3":    7,
-	"CH4":   8,
-}
-
-type Voivodeship string
+// Region identifies a region of a RegionSet.
+type Region string
 
+// Voivodeships of the Polish region set. The regions of every set are
+// defined by the region sets config.
 const (
-	Dolnoslaskie       Voivodeship = "dolnoslaskie"
-	KujawskoPomorskie  Voivodeship = "kujawsko-pomorskie"
-	Lubelskie          Voivodeship = "lubelskie"
-	Lubuskie           Voivodeship = "lubuskie"
-	Lodzkie            Voivodeship = "lodzkie"
-	Malopolskie        Voivodeship = "malopolskie"
-	Mazowieckie        Voivodeship = "mazowieckie"
-	Opolskie           Voivodeship = "opolskie"
-	Podkarpackie       Voivodeship = "podkarpackie"
-	Podlaskie          Voivodeship = "podlaskie"
-	Pomorskie          Voivodeship = "pomorskie"
-	Slaskie            Voivodeship = "slaskie"
-	Swietokrzyskie     Voivodeship = "swietokrzyskie"
-	WarminskoMazurskie Voivodeship = "warminsko-mazurskie"
-	Wielkopolskie      Voivodeship = "wielkopolskie"
-	Zachodniopomorskie Voivodeship = "zachodniopomorskie"
+	Dolnoslaskie       Region = "dolnoslaskie"
+	KujawskoPomorskie  Region = "kujawsko-pomorskie"
+	Lubelskie          Region = "lubelskie"
+	Lubuskie           Region = "lubuskie"
+	Lodzkie            Region = "lodzkie"
+	Malopolskie        Region = "malopolskie"
+	Mazowieckie        Region = "mazowieckie"
+	Opolskie           Region = "opolskie"
+	Podkarpackie       Region = "podkarpackie"
+	Podlaskie          Region = "podlaskie"
+	Pomorskie          Region = "pomorskie"
+	Slaskie            Region = "slaskie"
+	Swietokrzyskie     Region = "swietokrzyskie"
+	WarminskoMazurskie Region = "warminsko-mazurskie"
+	Wielkopolskie      Region = "wielkopolskie"
+	Zachodniopomorskie Region = "zachodniopomorskie"
 )
 
 type ParamType string
 
+// Commonly used parameter types. The full set of supported parameters is
+// defined by the Registry.
 const (
 	PM10  ParamType = "PM10"
 	PM2_5 ParamType = "PM2_5"
@@ -50,39 +47,204 @@ const (
 	CH4   ParamType = "CH4"
 )
 
+// ParamStatus tells whether a parameter value is available. It lets clients
+// tell a zero reading apart from a missing one.
+type ParamStatus string
+
+const (
+	// Available means the value was aggregated from at least one measurement.
+	Available ParamStatus = "available"
+	// NoData means a source supports the parameter, but there were no measurements.
+	NoData ParamStatus = "no_data"
+	// Unsupported means no source provides the parameter.
+	Unsupported ParamStatus = "unsupported"
+)
+
 type Parameter struct {
-	Id          int       `json:"id"`
-	Description string    `json:"description"`
-	Unit        string    `json:"unit"`
-	Value       float32   `json:"value"`
-	Type        ParamType `json:"type"`
+	Id int `json:"id"`
+	// Name is the display name in the language of the request.
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description"`
+	Unit        string      `json:"unit"`
+	Value       *float32    `json:"value"`
+	Type        ParamType   `json:"type"`
+	Status      ParamStatus `json:"status"`
+	Changes     []Change    `json:"changes,omitempty"`
+}
+
+type Trend string
+
+const (
+	Rising  Trend = "rising"
+	Falling Trend = "falling"
+	Stable  Trend = "stable"
+)
+
+// Change compares a value with the one of the same parameter a period earlier.
+type Change struct {
+	Period   string  `json:"period"`
+	Previous float32 `json:"previous"`
+	Delta    float32 `json:"delta"`
+	// Percent is the delta relative to the previous value, nil when the
+	// previous value is zero.
+	Percent *float64 `json:"percent"`
+	Trend   Trend    `json:"trend"`
+}
+
+// NewChange compares value with previous. Changes of at most threshold
+// percent of the previous value are considered stable.
+func NewChange(period string, value, previous float32, threshold float64) Change {
+	c := Change{Period: period, Previous: previous, Delta: value - previous, Trend: Stable}
+	if previous != 0 {
+		percent := float64(c.Delta) / math.Abs(float64(previous)) * 100
+		c.Percent = &percent
+		switch {
+		case percent > threshold:
+			c.Trend = Rising
+		case percent < -threshold:
+			c.Trend = Falling
+		}
+		return c
+	}
+	switch {
+	case c.Delta > 0:
+		c.Trend = Rising
+	case c.Delta < 0:
+		c.Trend = Falling
+	}
+	return c
+}
+
+// ParameterFields returns the JSON field names of Parameter.
+func ParameterFields() []string {
+	t := reflect.TypeFor[Parameter]()
+	fields := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// MergeStrategy decides how the per-source averages are combined.
+type MergeStrategy string
+
+const (
+	// SourceMean gives every source the same weight.
+	SourceMean MergeStrategy = "source-mean"
+	// Pooled averages the station means of all sources together, so that
+	// every station counts once.
+	Pooled MergeStrategy = "pooled"
+	// StationWeighted weights each source by the number of its stations
+	// that reported the parameter.
+	StationWeighted MergeStrategy = "station-weighted"
+	// TrustWeighted weights each source by a configured trust weight.
+	TrustWeighted MergeStrategy = "trust-weighted"
+)
+
+// MergeInfo records how the values of AggregatedData were merged.
+type MergeInfo struct {
+	Strategy MergeStrategy      `json:"strategy"`
+	Weights  map[Source]float64 `json:"weights,omitempty"`
 }
 
 type AggregatedData struct {
-	Voivodeship Voivodeship `json:"voivodeship"`
-	Parameters  []Parameter `json:"parameters"`
-	Timestamp   string      `json:"timestamp"`
+	Region Region `json:"voivodeship"`
+	// RegionName is the display name of the region in the language of the
+	// request.
+	RegionName string      `json:"voivodeshipName,omitempty"`
+	Parameters []Parameter `json:"parameters"`
+	// Timestamp is the time of the most recent measurement behind the
+	// values, and empty when there were no measurements.
+	Timestamp string `json:"timestamp"`
+	// Hour is the start of the UTC hour the values were aggregated for. A
+	// source that lags behind contributes the values of its latest hour.
+	Hour  string    `json:"hour,omitempty"`
+	Merge MergeInfo `json:"merge"`
 }
 
-func (ad *AggregatedData) AddParamInfoFromOpenMeteo(parameters []openmeteo.Parameter) error {
-	params := make([]Parameter, len(validParamTypes))
-	for _, p := range parameters {
-		param, err := MapOpenMeteoParameter(p)
-		if err != nil {
-			continue
+// AddParamInfo adds an entry for every registered parameter. Each of sources
+// lists the parameters one source provides. Missing metadata is taken from the
+// first source that has it, falling back to the registry definition; the
+// registry unit takes precedence as it is the unit values are reported in.
+func (ad *AggregatedData) AddParamInfo(registry *Registry, sources ...[]Parameter) {
+	params := make([]Parameter, 0, len(registry.Definitions()))
+	for _, d := range registry.Definitions() {
+		param := Parameter{Id: registry.Id(d.Type), Type: d.Type, Unit: d.Unit, Status: Unsupported}
+		for _, source := range sources {
+			for _, p := range source {
+				if p.Type != d.Type {
+					continue
+				}
+				param.Status = NoData
+				if param.Description == "" {
+					param.Description = p.Description
+				}
+				if param.Unit == "" {
+					param.Unit = p.Unit
+				}
+			}
+		}
+		if param.Description == "" {
+			param.Description = d.Description
 		}
-		params[param.Id-1] = param
+		params = append(params, param)
 	}
 	ad.Parameters = params
-	return nil
 }
 
-func (ad *AggregatedData) AddParamValues(averages map[ParamType]float32) {
+// KeepParams drops all parameters except the given types.
+func (ad *AggregatedData) KeepParams(types []ParamType) {
+	ad.Parameters = slices.DeleteFunc(ad.Parameters, func(p Parameter) bool {
+		return !slices.Contains(types, p.Type)
+	})
+}
+
+// AddParamValues fills in the parameter values. The timestamp is the time of the
+// most recent measurement behind the values, so it only changes when the data does.
+func (ad *AggregatedData) AddParamValues(averages map[ParamType]float32, updated time.Time) {
 	for i := range ad.Parameters {
 		p := &ad.Parameters[i]
 		if value, exists := averages[p.Type]; exists {
-			ad.Parameters[i].Value = value
+			p.Value = &value
+			p.Status = Available
 		}
 	}
-	ad.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if !updated.IsZero() {
+		ad.Timestamp = updated.UTC().Format(time.RFC3339)
+	}
+}
+
+// LastModified returns the data timestamp, or the zero time when there is none.
+func (ad *AggregatedData) LastModified() time.Time {
+	t, err := time.Parse(time.RFC3339, ad.Timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Station is the source independent representation of a measuring station.
+type Station struct {
+	Source          Source      `json:"source"`
+	Id              int         `json:"id"`
+	Name            string      `json:"name"`
+	Latitude        float64     `json:"latitude"`
+	Longitude       float64     `json:"longitude"`
+	Locality        string      `json:"locality,omitempty"`
+	Parameters      []ParamType `json:"parameters"`
+	LastMeasurement *time.Time  `json:"lastMeasurement"`
+}
+
+// StationMeasurement is the latest reading of one parameter at a station.
+type StationMeasurement struct {
+	Type      ParamType `json:"type"`
+	Value     float32   `json:"value"`
+	Unit      string    `json:"unit"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type StationDetails struct {
+	Station
+	Measurements []StationMeasurement `json:"measurements"`
 }
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChange(t *testing.T) {
+	c := NewChange("1h", 55, 50, 5)
+	assert.Equal(t, float32(5), c.Delta)
+	assert.InDelta(t, 10, *c.Percent, 0.001)
+	assert.Equal(t, Rising, c.Trend)
+
+	assert.Equal(t, Stable, NewChange("1h", 52, 50, 5).Trend)
+	assert.Equal(t, Falling, NewChange("24h", 40, 50, 5).Trend)
+
+	c = NewChange("7d", 3, 0, 5)
+	assert.Nil(t, c.Percent)
+	assert.Equal(t, Rising, c.Trend)
+	assert.Equal(t, Stable, NewChange("7d", 0, 0, 5).Trend)
+}
@@ -7,11 +7,62 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var client = &http.Client{Timeout: 10 * time.Second}
+var client = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+}
+
+var tracer = otel.Tracer("aggregator/internal/apiclient")
+
+// UpstreamError is returned when an upstream service answers with a status
+// other than 200 OK.
+type UpstreamError struct {
+	URL        string
+	StatusCode int
+	// Message is the message of the upstream error response, if it has one.
+	Message string
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("request %s returned status %d", e.URL, e.StatusCode)
+}
+
+// errorBody covers the error responses of the upstream services.
+type errorBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func newUpstreamError(url string, response *http.Response) *UpstreamError {
+	e := &UpstreamError{URL: url, StatusCode: response.StatusCode}
+	var body errorBody
+	if json.NewDecoder(io.LimitReader(response.Body, 4096)).Decode(&body) == nil {
+		e.Message = body.Message
+		if e.Message == "" {
+			e.Message = body.Error
+		}
+	}
+	return e
+}
+
+func FetchData[T any](ctx context.Context, url string) (_ []T, err error) {
+	ctx, span := tracer.Start(ctx, "apiclient.FetchData", trace.WithAttributes(attribute.String("url.full", url)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-func FetchData[T any](ctx context.Context, url string) ([]T, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request %s failed: %v", url, err)
@@ -19,12 +70,12 @@ func FetchData[T any](ctx context.Context, url string) ([]T, error) {
 
 	response, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request %s failed: %v", url, err)
+		return nil, fmt.Errorf("request %s failed: %w", url, err)
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request %s returned status %d", url, response.StatusCode)
+		return nil, newUpstreamError(url, response)
 	}
 
 	body, err := io.ReadAll(response.Body)
@@ -36,5 +87,6 @@ func FetchData[T any](ctx context.Context, url string) ([]T, error) {
 	if err = json.Unmarshal(body, &results); err != nil {
 		return nil, fmt.Errorf("unmarshalling response body for request %s failed: %v", url, err)
 	}
+	span.SetAttributes(attribute.Int("apiclient.results", len(results)))
 	return results, nil
 }
\ No newline at end of file
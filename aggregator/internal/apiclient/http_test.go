@@ -0,0 +1,52 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFetchDataUpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Not Found","message":"Station with ID 7 not found.","timestamp":"2025-10-01T12:00:00"}`))
+	}))
+	defer server.Close()
+
+	_, err := FetchData[int](t.Context(), server.URL+"/stations/7/measurements")
+	var upstreamErr *UpstreamError
+	assert.True(t, errors.As(err, &upstreamErr))
+	assert.Equal(t, http.StatusNotFound, upstreamErr.StatusCode)
+	assert.Equal(t, "Station with ID 7 not found.", upstreamErr.Message)
+	assert.EqualError(t, err, "request "+server.URL+"/stations/7/measurements returned status 404")
+}
+
+func TestFetchDataPropagatesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`[1,2,3]`))
+	}))
+	defer server.Close()
+
+	results, err := FetchData[int](t.Context(), server.URL)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	spans := recorder.Ended()
+	assert.NotEmpty(t, spans)
+	fetch := spans[len(spans)-1]
+	assert.Equal(t, "apiclient.FetchData", fetch.Name())
+	assert.Contains(t, traceparent, fetch.SpanContext().TraceID().String())
+}
@@ -0,0 +1,194 @@
+package apiclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Upstream modes set by UPSTREAM_MODE.
+const (
+	// ModeLive sends requests to the upstream services.
+	ModeLive = "live"
+	// ModeRecord sends requests to the upstream services and saves every
+	// response.
+	ModeRecord = "record"
+	// ModeReplay serves the saved responses without any network access.
+	ModeReplay = "replay"
+)
+
+const defaultRecordingsDir = "recordings"
+
+// ErrNotRecorded is returned in replay mode for requests that have no
+// recorded response.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// ConfigureFromEnv sets the upstream mode from UPSTREAM_MODE and the
+// directory recordings are kept in from UPSTREAM_RECORDINGS_DIR.
+func ConfigureFromEnv() error {
+	dir := os.Getenv("UPSTREAM_RECORDINGS_DIR")
+	if dir == "" {
+		dir = defaultRecordingsDir
+	}
+	transport, err := newTransport(os.Getenv("UPSTREAM_MODE"), dir)
+	if err != nil {
+		return err
+	}
+	client.Transport = otelhttp.NewTransport(transport)
+	return nil
+}
+
+func newTransport(mode, dir string) (http.RoundTripper, error) {
+	switch mode {
+	case "", ModeLive:
+		return http.DefaultTransport, nil
+	case ModeRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating recordings directory: %w", err)
+		}
+		return &recorder{next: http.DefaultTransport, dir: dir, counts: make(map[string]int)}, nil
+	case ModeReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("opening recordings directory: %w", err)
+		}
+		return &replayer{dir: dir, counts: make(map[string]int)}, nil
+	default:
+		return nil, fmt.Errorf("unknown UPSTREAM_MODE: %s", mode)
+	}
+}
+
+// recording is a saved upstream response with the request it answered.
+type recording struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	RecordedAt time.Time   `json:"recordedAt"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// recordingKey names the directory the responses to a request are saved in.
+// The key covers the full URL, so replay needs the upstream URLs used while
+// recording.
+func recordingKey(r *http.Request) string {
+	sum := sha256.Sum256([]byte(r.Method + " " + r.URL.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func recordingPath(dir, key string, n int) string {
+	return filepath.Join(dir, key, fmt.Sprintf("%06d.json", n))
+}
+
+// recorder saves every response in the order the requests were sent. A
+// request sent repeatedly gets a numbered recording each time, so a replay
+// goes through the data as it changed over the recording.
+type recorder struct {
+	next   http.RoundTripper
+	dir    string
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (rec *recorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	response, err := rec.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response for recording: %w", err)
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err = rec.save(r, response, body); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (rec *recorder) save(r *http.Request, response *http.Response, body []byte) error {
+	data, err := json.MarshalIndent(recording{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		RecordedAt: time.Now().UTC(),
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding recording: %w", err)
+	}
+
+	key := recordingKey(r)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	n, counted := rec.counts[key]
+	if !counted {
+		// Continue the numbering of an earlier recording session.
+		entries, _ := os.ReadDir(filepath.Join(rec.dir, key))
+		n = len(entries)
+	}
+	if err = os.MkdirAll(filepath.Join(rec.dir, key), 0o755); err != nil {
+		return fmt.Errorf("creating recording directory: %w", err)
+	}
+	if err = os.WriteFile(recordingPath(rec.dir, key, n), data, 0o644); err != nil {
+		return fmt.Errorf("saving recording: %w", err)
+	}
+	rec.counts[key] = n + 1
+	return nil
+}
+
+// replayer serves the recorded responses of a request in the order they
+// were recorded. Once they are used up the last one is served again.
+type replayer struct {
+	dir    string
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (rep *replayer) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := recordingKey(r)
+	rep.mu.Lock()
+	n := rep.counts[key]
+	data, err := os.ReadFile(recordingPath(rep.dir, key, n))
+	if errors.Is(err, os.ErrNotExist) && n > 0 {
+		data, err = os.ReadFile(recordingPath(rep.dir, key, n-1))
+	} else if err == nil {
+		rep.counts[key] = n + 1
+	}
+	rep.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, r.Method, r.URL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading recording: %w", err)
+	}
+
+	var rec recording
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("decoding recording: %w", err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rec.Body))),
+		ContentLength: int64(len(rec.Body)),
+		Request:       r,
+	}, nil
+}
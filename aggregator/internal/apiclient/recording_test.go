@@ -0,0 +1,68 @@
+package apiclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Station not found."}`))
+			return
+		}
+		w.Write([]byte("[" + strconv.Itoa(calls) + "]"))
+	}))
+
+	recording, err := newTransport(ModeRecord, dir)
+	assert.NoError(t, err)
+	recordingClient := &http.Client{Transport: recording}
+	for _, path := range []string{"/values", "/values", "/missing"} {
+		response, err := recordingClient.Get(server.URL + path)
+		assert.NoError(t, err)
+		response.Body.Close()
+	}
+	url := server.URL
+	server.Close()
+
+	replay, err := newTransport(ModeReplay, dir)
+	assert.NoError(t, err)
+	previous := client.Transport
+	client.Transport = replay
+	defer func() { client.Transport = previous }()
+
+	for _, want := range []int{1, 2, 2} {
+		values, err := FetchData[int](t.Context(), url+"/values")
+		assert.NoError(t, err)
+		assert.Equal(t, []int{want}, values)
+	}
+
+	_, err = FetchData[int](t.Context(), url+"/missing")
+	var upstreamErr *UpstreamError
+	assert.ErrorAs(t, err, &upstreamErr)
+	assert.Equal(t, "Station not found.", upstreamErr.Message)
+
+	_, err = FetchData[int](t.Context(), url+"/other")
+	assert.ErrorIs(t, err, ErrNotRecorded)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := newTransport("", t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, http.DefaultTransport, transport)
+
+	_, err = newTransport(ModeReplay, t.TempDir()+"/none")
+	assert.ErrorContains(t, err, "opening recordings directory")
+
+	_, err = newTransport("rewind", t.TempDir())
+	assert.EqualError(t, err, "unknown UPSTREAM_MODE: rewind")
+}
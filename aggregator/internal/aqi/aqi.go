@@ -0,0 +1,112 @@
+// Package aqi computes an air quality index from parameter values, following
+// the banding of the European Air Quality Index.
+package aqi
+
+import (
+	"aggregator/internal/api"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Index defines the levels of the index, from the best to the worst, and the
+// bands of every parameter it is based on. The bands of a parameter are the
+// upper bounds of all levels but the last.
+type Index struct {
+	Levels []string                    `json:"levels"`
+	Bands  map[api.ParamType][]float32 `json:"bands"`
+}
+
+// Result is the index of a set of values. The worst parameter sets the level.
+type Result struct {
+	// Level is the 1-based level, 1 being the best.
+	Level int    `json:"level"`
+	Name  string `json:"name"`
+	// Label is the name in the language of the request.
+	Label string `json:"label,omitempty"`
+	// Score orders results within a level: it is the level minus one plus
+	// the position of the worst value within its band.
+	Score    float64       `json:"score"`
+	Dominant api.ParamType `json:"dominant"`
+}
+
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading aqi file: %w", err)
+	}
+	var idx Index
+	if err = json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parsing aqi file: %w", err)
+	}
+	if len(idx.Levels) < 2 {
+		return nil, fmt.Errorf("aqi file needs at least two levels")
+	}
+	if len(idx.Bands) == 0 {
+		return nil, fmt.Errorf("aqi file defines no bands")
+	}
+	for paramType, bands := range idx.Bands {
+		if len(bands) != len(idx.Levels)-1 {
+			return nil, fmt.Errorf("%s has %d bands for %d levels", paramType, len(bands), len(idx.Levels))
+		}
+		for i, b := range bands {
+			if b <= 0 || i > 0 && b <= bands[i-1] {
+				return nil, fmt.Errorf("bands of %s have to be positive and ascending", paramType)
+			}
+		}
+	}
+	return &idx, nil
+}
+
+// Params returns the parameters the index is based on, sorted.
+func (idx *Index) Params() []api.ParamType {
+	params := make([]api.ParamType, 0, len(idx.Bands))
+	for paramType := range idx.Bands {
+		params = append(params, paramType)
+	}
+	slices.Sort(params)
+	return params
+}
+
+// Evaluate computes the index of the values. It reports false when none of
+// the parameters of the index has a value.
+func (idx *Index) Evaluate(values map[api.ParamType]float32) (Result, bool) {
+	var worst Result
+	found := false
+	for _, paramType := range idx.Params() {
+		value, exists := values[paramType]
+		if !exists {
+			continue
+		}
+		score := idx.score(paramType, value)
+		if !found || score > worst.Score {
+			worst = Result{Score: score, Dominant: paramType}
+			found = true
+		}
+	}
+	if !found {
+		return Result{}, false
+	}
+	worst.Level = min(int(worst.Score)+1, len(idx.Levels))
+	worst.Name = idx.Levels[worst.Level-1]
+	return worst, true
+}
+
+// score places the value within the bands of the parameter. Values of the
+// last, open band are placed relative to the width of the band below.
+func (idx *Index) score(paramType api.ParamType, value float32) float64 {
+	bands := idx.Bands[paramType]
+	lower := float32(0)
+	for i, upper := range bands {
+		if value < upper {
+			return float64(i) + float64(max(value, 0)-lower)/float64(upper-lower)
+		}
+		lower = upper
+	}
+	width := bands[len(bands)-1]
+	if len(bands) > 1 {
+		width -= bands[len(bands)-2]
+	}
+	return float64(len(bands)) + float64(value-lower)/float64(width)
+}
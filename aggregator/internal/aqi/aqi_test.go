@@ -0,0 +1,52 @@
+package aqi
+
+import (
+	"aggregator/internal/api"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	idx, err := LoadIndex("../../config/aqi.json")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.ParamType{api.NO2, api.O3, api.PM10, api.PM2_5, api.SO2}, idx.Params())
+
+	_, ok := idx.Evaluate(map[api.ParamType]float32{api.CO: 300})
+	assert.False(t, ok)
+
+	result, ok := idx.Evaluate(map[api.ParamType]float32{api.PM10: 30, api.NO2: 20, api.CO: 9000})
+	assert.True(t, ok)
+	assert.Equal(t, Result{Level: 2, Name: "fair", Score: 1.5, Dominant: api.PM10}, result)
+
+	result, _ = idx.Evaluate(map[api.ParamType]float32{api.PM2_5: 100, api.PM10: 30})
+	assert.Equal(t, 6, result.Level)
+	assert.Equal(t, "extremely-poor", result.Name)
+	assert.Equal(t, api.PM2_5, result.Dominant)
+	assert.InDelta(t, 6, result.Score, 0.001)
+
+	result, _ = idx.Evaluate(map[api.ParamType]float32{api.O3: 0})
+	assert.Equal(t, 1, result.Level)
+	assert.Zero(t, result.Score)
+}
+
+func TestLoadIndexRejectsInvalidBands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aqi.json")
+	write := func(content string) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	write(`{"levels": ["good", "fair", "poor"], "bands": {"PM10": [20]}}`)
+	_, err := LoadIndex(path)
+	assert.ErrorContains(t, err, "PM10 has 1 bands for 3 levels")
+
+	write(`{"levels": ["good", "fair", "poor"], "bands": {"PM10": [20, 10]}}`)
+	_, err = LoadIndex(path)
+	assert.ErrorContains(t, err, "positive and ascending")
+
+	write(`{"levels": ["good", "poor"], "bands": {}}`)
+	_, err = LoadIndex(path)
+	assert.ErrorContains(t, err, "no bands")
+}
@@ -0,0 +1,317 @@
+// Package coverage checks how well the boundaries of a region set cover the
+// country and its stations.
+package coverage
+
+import (
+	"aggregator/internal/api"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Severity ranks a finding.
+type Severity string
+
+const (
+	Info    Severity = "info"
+	Warning Severity = "warning"
+	Error   Severity = "error"
+	// None is used as a threshold that no finding reaches.
+	None Severity = "none"
+)
+
+var severityRanks = map[Severity]int{Info: 1, Warning: 2, Error: 3, None: 4}
+
+func ParseSeverity(value string) (Severity, error) {
+	s := Severity(strings.ToLower(value))
+	if _, exists := severityRanks[s]; !exists {
+		return "", fmt.Errorf("unknown severity: %s", value)
+	}
+	return s, nil
+}
+
+// AtLeast tells whether s is as severe as the threshold.
+func (s Severity) AtLeast(threshold Severity) bool {
+	return severityRanks[s] >= severityRanks[threshold]
+}
+
+// Kinds of findings.
+const (
+	KindOverlap         = "overlap"
+	KindUncoveredArea   = "uncovered_area"
+	KindSeveralRegions  = "station_in_several_regions"
+	KindNoRegion        = "station_in_no_region"
+	KindNoStations      = "region_without_stations"
+	KindNoSourceStation = "region_without_source_stations"
+)
+
+const (
+	// overlapErrorShare is the share of both of two regions that has to
+	// overlap for the overlap to be an error.
+	overlapErrorShare = 0.5
+	// cellSize is the size in degrees of the grid cells uncovered areas are
+	// searched in.
+	cellSize = 0.1
+)
+
+// Station is a station to check the assignment of.
+type Station struct {
+	Source    api.Source `json:"source"`
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Latitude  float64    `json:"latitude"`
+	Longitude float64    `json:"longitude"`
+}
+
+// Finding is an issue of the boundaries.
+type Finding struct {
+	Severity Severity     `json:"severity"`
+	Kind     string       `json:"kind"`
+	Regions  []api.Region `json:"regions,omitempty"`
+	Source   api.Source   `json:"source,omitempty"`
+	Station  *Station     `json:"station,omitempty"`
+	Area     *api.Bounds  `json:"area,omitempty"`
+	Detail   string       `json:"detail"`
+}
+
+// Report lists the findings of a region set, the most severe first.
+type Report struct {
+	RegionSet string           `json:"regionSet"`
+	Counts    map[Severity]int `json:"counts"`
+	Findings  []Finding        `json:"findings"`
+	// StationsChecked tells whether the report covers the stations or only
+	// the boundaries.
+	StationsChecked bool `json:"stationsChecked"`
+}
+
+// Check checks the boundaries of the set and, if stations is not nil, the
+// assignment of the stations to its regions.
+func Check(set api.RegionSet, stations []Station) Report {
+	findings := slices.Concat(checkOverlaps(set), checkUncovered(set))
+	if stations != nil {
+		findings = append(findings, checkStations(set, stations)...)
+	}
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return severityRanks[b.Severity] - severityRanks[a.Severity]
+	})
+	r := Report{RegionSet: set.Id, Counts: map[Severity]int{Info: 0, Warning: 0, Error: 0}, Findings: findings, StationsChecked: stations != nil}
+	for _, f := range findings {
+		r.Counts[f.Severity]++
+	}
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	return r
+}
+
+// Fails tells whether any finding is at least as severe as the threshold.
+func (r Report) Fails(threshold Severity) bool {
+	return slices.ContainsFunc(r.Findings, func(f Finding) bool { return f.Severity.AtLeast(threshold) })
+}
+
+func area(b api.Bounds) float64 {
+	return (b.MaxLatitude - b.MinLatitude) * (b.MaxLongitude - b.MinLongitude)
+}
+
+func intersection(a, b api.Bounds) (api.Bounds, bool) {
+	i := api.Bounds{
+		MinLatitude:  math.Max(a.MinLatitude, b.MinLatitude),
+		MaxLatitude:  math.Min(a.MaxLatitude, b.MaxLatitude),
+		MinLongitude: math.Max(a.MinLongitude, b.MinLongitude),
+		MaxLongitude: math.Min(a.MaxLongitude, b.MaxLongitude),
+	}
+	return i, i.MinLatitude < i.MaxLatitude && i.MinLongitude < i.MaxLongitude
+}
+
+// checkOverlaps reports every pair of overlapping regions. An overlap
+// covering most of both regions is an error, as the regions are then hardly
+// distinguishable, which points to a mistake in the boundaries. Regions
+// within another one, such as city states, are only warned about.
+func checkOverlaps(set api.RegionSet) []Finding {
+	var findings []Finding
+	for i, a := range set.Regions {
+		for _, b := range set.Regions[i+1:] {
+			overlap, ok := intersection(set.Bounds[a], set.Bounds[b])
+			if !ok {
+				continue
+			}
+			smaller, larger := a, b
+			if area(set.Bounds[a]) > area(set.Bounds[b]) {
+				smaller, larger = b, a
+			}
+			share := area(overlap) / area(set.Bounds[smaller])
+			severity := Warning
+			if area(overlap)/area(set.Bounds[larger]) >= overlapErrorShare {
+				severity = Error
+			}
+			findings = append(findings, Finding{
+				Severity: severity,
+				Kind:     KindOverlap,
+				Regions:  []api.Region{a, b},
+				Area:     &overlap,
+				Detail:   fmt.Sprintf("%s and %s overlap on %.0f%% of %s", a, b, share*100, smaller),
+			})
+		}
+	}
+	return findings
+}
+
+// checkUncovered reports the areas inside the country that no region covers.
+// Without the outline of the country, a grid cell counts as inside when
+// covered cells surround it in all four directions. Uncovered cells are
+// merged into rectangles.
+func checkUncovered(set api.RegionSet) []Finding {
+	if len(set.Regions) == 0 {
+		return nil
+	}
+	all := set.Bounds[set.Regions[0]]
+	for _, r := range set.Regions[1:] {
+		b := set.Bounds[r]
+		all.MinLatitude = math.Min(all.MinLatitude, b.MinLatitude)
+		all.MaxLatitude = math.Max(all.MaxLatitude, b.MaxLatitude)
+		all.MinLongitude = math.Min(all.MinLongitude, b.MinLongitude)
+		all.MaxLongitude = math.Max(all.MaxLongitude, b.MaxLongitude)
+	}
+	rows := int(math.Ceil((all.MaxLatitude - all.MinLatitude) / cellSize))
+	cols := int(math.Ceil((all.MaxLongitude - all.MinLongitude) / cellSize))
+	covered := make([][]bool, rows)
+	for row := range rows {
+		covered[row] = make([]bool, cols)
+		for col := range cols {
+			lat := all.MinLatitude + (float64(row)+0.5)*cellSize
+			lon := all.MinLongitude + (float64(col)+0.5)*cellSize
+			covered[row][col] = slices.ContainsFunc(set.Regions, func(r api.Region) bool { return set.Bounds[r].Contains(lat, lon) })
+		}
+	}
+	inside := func(row, col int) bool {
+		return slices.Contains(covered[row][:col], true) && slices.Contains(covered[row][col+1:], true) &&
+			slices.ContainsFunc(covered[:row], func(cells []bool) bool { return cells[col] }) &&
+			slices.ContainsFunc(covered[row+1:], func(cells []bool) bool { return cells[col] })
+	}
+
+	// Runs of uncovered cells of a row, merged with the same run of the rows
+	// right below.
+	type run struct{ from, to int }
+	var gaps []api.Bounds
+	open := make(map[run]int)
+	for row := 0; row <= rows; row++ {
+		runs := make(map[run]bool)
+		for col := 0; row < rows && col < cols; col++ {
+			if covered[row][col] || !inside(row, col) {
+				continue
+			}
+			end := col
+			for end+1 < cols && !covered[row][end+1] && inside(row, end+1) {
+				end++
+			}
+			runs[run{col, end}] = true
+			col = end
+		}
+		for r, start := range open {
+			if !runs[r] {
+				gaps = append(gaps, api.Bounds{
+					MinLatitude:  round(all.MinLatitude + float64(start)*cellSize),
+					MaxLatitude:  round(all.MinLatitude + float64(row)*cellSize),
+					MinLongitude: round(all.MinLongitude + float64(r.from)*cellSize),
+					MaxLongitude: round(all.MinLongitude + float64(r.to+1)*cellSize),
+				})
+				delete(open, r)
+			}
+		}
+		for r := range runs {
+			if _, exists := open[r]; !exists {
+				open[r] = row
+			}
+		}
+	}
+	slices.SortFunc(gaps, func(a, b api.Bounds) int {
+		if c := cmp.Compare(a.MinLatitude, b.MinLatitude); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MinLongitude, b.MinLongitude)
+	})
+
+	findings := make([]Finding, 0, len(gaps))
+	for _, gap := range gaps {
+		findings = append(findings, Finding{
+			Severity: Warning,
+			Kind:     KindUncoveredArea,
+			Area:     &gap,
+			Detail: fmt.Sprintf("No region covers latitude %g-%g, longitude %g-%g",
+				gap.MinLatitude, gap.MaxLatitude, gap.MinLongitude, gap.MaxLongitude),
+		})
+	}
+	return findings
+}
+
+// checkStations reports stations assigned to several regions or to none,
+// and regions without stations.
+func checkStations(set api.RegionSet, stations []Station) []Finding {
+	var findings []Finding
+	counts := make(map[api.Region]map[api.Source]int)
+	sources := make(map[api.Source]bool)
+	for _, st := range stations {
+		sources[st.Source] = true
+		var regions []api.Region
+		for _, r := range set.Regions {
+			if set.Bounds[r].Contains(st.Latitude, st.Longitude) {
+				regions = append(regions, r)
+				if counts[r] == nil {
+					counts[r] = make(map[api.Source]int)
+				}
+				counts[r][st.Source]++
+			}
+		}
+		switch {
+		case len(regions) > 1:
+			findings = append(findings, Finding{
+				Severity: Warning,
+				Kind:     KindSeveralRegions,
+				Regions:  regions,
+				Source:   st.Source,
+				Station:  &st,
+				Detail:   fmt.Sprintf("Station %s/%d (%s) is assigned to %d regions", st.Source, st.Id, st.Name, len(regions)),
+			})
+		case len(regions) == 0:
+			// Sources may return stations of neighbouring countries, which
+			// rightly belong to no region.
+			findings = append(findings, Finding{
+				Severity: Info,
+				Kind:     KindNoRegion,
+				Source:   st.Source,
+				Station:  &st,
+				Detail:   fmt.Sprintf("Station %s/%d (%s) is in no region", st.Source, st.Id, st.Name),
+			})
+		}
+	}
+
+	for _, r := range set.Regions {
+		if len(counts[r]) == 0 {
+			findings = append(findings, Finding{
+				Severity: Warning,
+				Kind:     KindNoStations,
+				Regions:  []api.Region{r},
+				Detail:   fmt.Sprintf("%s has no stations", r),
+			})
+			continue
+		}
+		for _, source := range []api.Source{api.OpenMeteo, api.OpenAq} {
+			if sources[source] && counts[r][source] == 0 {
+				findings = append(findings, Finding{
+					Severity: Info,
+					Kind:     KindNoSourceStation,
+					Regions:  []api.Region{r},
+					Source:   source,
+					Detail:   fmt.Sprintf("%s has no %s stations", r, source),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,73 @@
+package coverage
+
+import (
+	"aggregator/internal/api"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSet = api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+	api.Slaskie:      {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 18, MaxLongitude: 19.5},
+	api.Malopolskie:  {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 19.4, MaxLongitude: 21},
+	api.Opolskie:     {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 16, MaxLongitude: 17.5},
+	api.Lodzkie:      {MinLatitude: 51, MaxLatitude: 52, MinLongitude: 16, MaxLongitude: 21},
+	api.Podkarpackie: {MinLatitude: 48, MaxLatitude: 49, MinLongitude: 16, MaxLongitude: 21},
+})
+
+func TestCheckBounds(t *testing.T) {
+	report := Check(testSet, nil)
+	assert.Equal(t, map[Severity]int{Error: 0, Warning: 2, Info: 0}, report.Counts)
+	assert.False(t, report.StationsChecked)
+
+	overlap := report.Findings[0]
+	assert.Equal(t, KindOverlap, overlap.Kind)
+	assert.Equal(t, []api.Region{api.Malopolskie, api.Slaskie}, overlap.Regions)
+	assert.Equal(t, "malopolskie and slaskie overlap on 7% of slaskie", overlap.Detail)
+
+	gap := report.Findings[1]
+	assert.Equal(t, KindUncoveredArea, gap.Kind)
+	assert.Equal(t, &api.Bounds{MinLatitude: 49, MaxLatitude: 51, MinLongitude: 17.5, MaxLongitude: 18}, gap.Area)
+
+	assert.True(t, report.Fails(Warning))
+	assert.False(t, report.Fails(Error))
+	assert.False(t, report.Fails(None))
+}
+
+func TestCheckOverlapError(t *testing.T) {
+	set := api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+		api.Slaskie:     {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 18, MaxLongitude: 19.5},
+		api.Malopolskie: {MinLatitude: 49, MaxLatitude: 51, MinLongitude: 18.2, MaxLongitude: 19.6},
+		api.Lodzkie:     {MinLatitude: 49.5, MaxLatitude: 50, MinLongitude: 18.5, MaxLongitude: 19},
+	})
+	report := Check(set, nil)
+	assert.Equal(t, map[Severity]int{Error: 1, Warning: 2, Info: 0}, report.Counts)
+	assert.Equal(t, []api.Region{api.Malopolskie, api.Slaskie}, report.Findings[0].Regions)
+	assert.True(t, report.Fails(Error))
+}
+
+func TestCheckStations(t *testing.T) {
+	report := Check(testSet, []Station{
+		{Source: api.OpenMeteo, Id: 1, Name: "Kraków", Latitude: 50.06, Longitude: 19.94},
+		{Source: api.OpenAq, Id: 2, Name: "Border", Latitude: 50, Longitude: 19.45},
+		{Source: api.OpenAq, Id: 3, Name: "Berlin", Latitude: 52.5, Longitude: 13.4},
+		{Source: api.OpenAq, Id: 4, Name: "Łódź", Latitude: 51.76, Longitude: 19.46},
+	})
+	assert.True(t, report.StationsChecked)
+	kinds := make(map[string][]string)
+	for _, f := range report.Findings {
+		kinds[f.Kind] = append(kinds[f.Kind], f.Detail)
+	}
+	assert.Equal(t, []string{"Station openaq/2 (Border) is assigned to 2 regions"}, kinds[KindSeveralRegions])
+	assert.Equal(t, []string{"Station openaq/3 (Berlin) is in no region"}, kinds[KindNoRegion])
+	assert.Equal(t, []string{"opolskie has no stations", "podkarpackie has no stations"}, kinds[KindNoStations])
+	assert.Equal(t, []string{"lodzkie has no openmeteo stations", "slaskie has no openmeteo stations"}, kinds[KindNoSourceStation])
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := ParseSeverity("Warning")
+	assert.NoError(t, err)
+	assert.Equal(t, Warning, s)
+	_, err = ParseSeverity("fatal")
+	assert.EqualError(t, err, "unknown severity: fatal")
+}
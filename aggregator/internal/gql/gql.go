@@ -0,0 +1,115 @@
+// Package gql serves a GraphQL API over the aggregator service, next to the
+// REST routes.
+package gql
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"aggregator/internal/history"
+	"aggregator/internal/locale"
+	"aggregator/internal/matching"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Service is the part of the aggregator service the resolvers use.
+type Service interface {
+	RegionSet() api.RegionSet
+	Params() *api.Registry
+	AirQualityIndex() *aqi.Index
+	AggregateAll(ctx context.Context, regions []api.Region, opts aggregator.Options) ([]api.AggregatedData, error)
+	AggregateForRegion(ctx context.Context, region api.Region, opts aggregator.Options) (api.AggregatedData, error)
+	RegionStations(region api.Region) ([]api.Station, error)
+	StationsByKey(ctx context.Context, keys []matching.Key) (map[matching.Key]api.StationDetails, error)
+	History(region api.Region, paramType api.ParamType, from, to time.Time) []history.Point
+}
+
+// Executor runs queries against the schema.
+type Executor struct {
+	schema graphql.Schema
+	limits Limits
+}
+
+func NewExecutor(limits Limits) (*Executor, error) {
+	schema, err := newSchema()
+	if err != nil {
+		return nil, fmt.Errorf("building graphql schema: %w", err)
+	}
+	return &Executor{schema: schema, limits: limits}, nil
+}
+
+// queryState is what the resolvers of one query share.
+type queryState struct {
+	service  Service
+	catalog  *locale.Catalog
+	stations *stationLoader
+}
+
+type stateKey struct{}
+
+func state(p graphql.ResolveParams) *queryState {
+	return p.Context.Value(stateKey{}).(*queryState)
+}
+
+// Execute runs a query against the service, naming things in the language of
+// the catalog. Queries over the depth or cost limits are rejected before
+// anything is resolved.
+func (e *Executor) Execute(ctx context.Context, service Service, catalog *locale.Catalog, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+	}
+	if err = e.checkLimits(doc, req); err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+	}
+	if catalog == nil {
+		catalog = &locale.Catalog{}
+	}
+	s := &queryState{service: service, catalog: catalog}
+	s.stations = newStationLoader(ctx, service.StationsByKey)
+	return graphql.Do(graphql.Params{
+		Schema:         e.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, stateKey{}, s),
+	})
+}
+
+// checkLimits checks the operation that would run. Documents the validation
+// rejects, such as ones with several anonymous operations, are left to it.
+func (e *Executor) checkLimits(doc *ast.Document, req Request) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operations []*ast.OperationDefinition
+	for _, d := range doc.Definitions {
+		switch d := d.(type) {
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if req.OperationName == "" || d.Name != nil && d.Name.Value == req.OperationName {
+				operations = append(operations, d)
+			}
+		}
+	}
+	for _, op := range operations {
+		if err := e.limits.check(op, fragments, req.Variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
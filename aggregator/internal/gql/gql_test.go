@@ -0,0 +1,212 @@
+package gql
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"aggregator/internal/history"
+	"aggregator/internal/locale"
+	"aggregator/internal/matching"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeService struct {
+	regions  api.RegionSet
+	params   *api.Registry
+	stations map[api.Region][]api.Station
+	history  []history.Point
+	// batches records the keys of every StationsByKey call.
+	batches [][]matching.Key
+}
+
+func (f *fakeService) RegionSet() api.RegionSet { return f.regions }
+
+func (f *fakeService) Params() *api.Registry { return f.params }
+
+func (f *fakeService) AirQualityIndex() *aqi.Index {
+	return &aqi.Index{Levels: []string{"good", "poor"}, Bands: map[api.ParamType][]float32{api.PM10: {20}}}
+}
+
+func (f *fakeService) AggregateAll(ctx context.Context, regions []api.Region, opts aggregator.Options) ([]api.AggregatedData, error) {
+	if len(regions) == 0 {
+		regions = f.regions.Regions
+	}
+	var results []api.AggregatedData
+	for _, region := range regions {
+		result, _ := f.AggregateForRegion(ctx, region, opts)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *fakeService) AggregateForRegion(_ context.Context, region api.Region, opts aggregator.Options) (api.AggregatedData, error) {
+	result := api.AggregatedData{Region: region, Merge: api.MergeInfo{Strategy: api.SourceMean}}
+	result.AddParamInfo(f.params)
+	result.AddParamValues(map[api.ParamType]float32{api.PM10: 25.3}, time.Date(2025, 10, 8, 12, 5, 0, 0, time.UTC))
+	if len(opts.Params) > 0 {
+		result.KeepParams(opts.Params)
+	}
+	return result, nil
+}
+
+func (f *fakeService) RegionStations(region api.Region) ([]api.Station, error) {
+	return f.stations[region], nil
+}
+
+func (f *fakeService) StationsByKey(_ context.Context, keys []matching.Key) (map[matching.Key]api.StationDetails, error) {
+	f.batches = append(f.batches, keys)
+	results := make(map[matching.Key]api.StationDetails)
+	for _, stations := range f.stations {
+		for _, st := range stations {
+			key := matching.Key{Source: st.Source, Id: st.Id}
+			results[key] = api.StationDetails{
+				Station:      api.Station{Source: st.Source, Id: st.Id, Name: st.Name, Parameters: []api.ParamType{api.PM10}},
+				Measurements: []api.StationMeasurement{{Type: api.PM10, Value: float32(st.Id), Unit: "µg/m³", Timestamp: time.Date(2025, 10, 8, 12, 0, 0, 0, time.UTC)}},
+			}
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeService) History(api.Region, api.ParamType, time.Time, time.Time) []history.Point {
+	return f.history
+}
+
+func newFakeService(t *testing.T) *fakeService {
+	params, err := api.LoadRegistry("../../config/parameters.json")
+	assert.NoError(t, err)
+	return &fakeService{
+		regions: api.NewRegionSet("pl", "", "PL", map[api.Region]api.Bounds{
+			api.Malopolskie: {MinLatitude: 49, MaxLatitude: 50.5, MinLongitude: 19, MaxLongitude: 21},
+			api.Slaskie:     {MinLatitude: 49.4, MaxLatitude: 51, MinLongitude: 17.8, MaxLongitude: 19.3},
+		}),
+		params: params,
+		stations: map[api.Region][]api.Station{
+			api.Malopolskie: {{Source: api.OpenAq, Id: 1, Name: "Kraków"}, {Source: api.OpenMeteo, Id: 2, Name: "Tarnów"}},
+			api.Slaskie:     {{Source: api.OpenAq, Id: 3, Name: "Katowice"}},
+		},
+		history: []history.Point{
+			{Hour: time.Date(2025, 10, 8, 10, 0, 0, 0, time.UTC), Value: 10},
+			{Hour: time.Date(2025, 10, 8, 11, 0, 0, 0, time.UTC), Value: 30},
+		},
+	}
+}
+
+func execute(t *testing.T, service Service, catalog *locale.Catalog, query string, variables map[string]any) map[string]any {
+	t.Helper()
+	executor, err := NewExecutor(Limits{MaxDepth: 6, MaxCost: 2000})
+	assert.NoError(t, err)
+	result := executor.Execute(t.Context(), service, catalog, Request{Query: query, Variables: variables})
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	return decoded
+}
+
+func TestExecuteBatchesStationLookups(t *testing.T) {
+	service := newFakeService(t)
+	result := execute(t, service, nil, `{
+		voivodeships {
+			id
+			stations { name measurements { type value } }
+		}
+	}`, nil)
+	assert.Nil(t, result["errors"])
+	voivodeships := result["data"].(map[string]any)["voivodeships"].([]any)
+	assert.Len(t, voivodeships, 2)
+	stations := voivodeships[0].(map[string]any)["stations"].([]any)
+	assert.Equal(t, "Kraków", stations[0].(map[string]any)["name"])
+	assert.Equal(t, 1.0, stations[0].(map[string]any)["measurements"].([]any)[0].(map[string]any)["value"])
+	assert.Len(t, service.batches, 1)
+	assert.Len(t, service.batches[0], 3)
+}
+
+func TestExecuteLocalizesAndAggregates(t *testing.T) {
+	catalog := &locale.Catalog{
+		Regions:    map[api.Region]string{api.Malopolskie: "Małopolskie"},
+		Parameters: map[api.ParamType]locale.ParamText{api.PM10: {Name: "Pył PM10"}},
+		Aqi:        map[string]string{"poor": "Zła"},
+	}
+	result := execute(t, newFakeService(t), catalog, `query($id: String!) {
+		voivodeship(id: $id) {
+			name
+			aggregate(params: ["pm10"]) { hour parameters { name value status changes { delta } } aqi { level label dominant } }
+			history(param: "PM10") { unit points { value } min max mean }
+		}
+	}`, map[string]any{"id": "malopolskie"})
+	assert.Nil(t, result["errors"])
+	v := result["data"].(map[string]any)["voivodeship"].(map[string]any)
+	assert.Equal(t, "Małopolskie", v["name"])
+	aggregate := v["aggregate"].(map[string]any)
+	param := aggregate["parameters"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{"name": "Pył PM10", "value": 25.3, "status": "available", "changes": []any{}}, param)
+	assert.Equal(t, map[string]any{"level": 2.0, "label": "Zła", "dominant": "PM10"}, aggregate["aqi"])
+	hist := v["history"].(map[string]any)
+	assert.Equal(t, "µg/m³", hist["unit"])
+	assert.Equal(t, 10.0, hist["min"])
+	assert.Equal(t, 30.0, hist["max"])
+	assert.Equal(t, 20.0, hist["mean"])
+}
+
+func TestExecuteReportsResolverErrors(t *testing.T) {
+	result := execute(t, newFakeService(t), nil, `{ voivodeship(id: "bayern") { name } }`, nil)
+	assert.Contains(t, result["errors"].([]any)[0].(map[string]any)["message"], "unknown region of pl")
+}
+
+func TestExecuteEnforcesLimits(t *testing.T) {
+	service := newFakeService(t)
+	result := execute(t, service, nil, `{ aggregates { voivodeship { aggregate { voivodeship { aggregate { voivodeship { id } } } } } } }`, nil)
+	assert.Contains(t, result["errors"].([]any)[0].(map[string]any)["message"], "query depth 7 exceeds the limit of 6")
+	assert.Nil(t, result["data"])
+
+	result = execute(t, service, nil, `query($n: Int) {
+		voivodeships { stations(limit: $n) { ...details } }
+	}
+	fragment details on Station { name measurements { type value unit timestamp } }`, map[string]any{"n": 100})
+	assert.Contains(t, result["errors"].([]any)[0].(map[string]any)["message"], "exceeds the limit of 2000")
+	assert.Empty(t, service.batches)
+
+	result = execute(t, service, nil, `{ voivodeships { stations(limit: 5) { name } } }`, nil)
+	assert.Nil(t, result["errors"])
+}
+
+func TestExecuteRejectsLimitsOutOfRange(t *testing.T) {
+	// A negative limit would make the cost of the measurements negative.
+	const details = `fragment details on Station { name measurements { type value unit timestamp } }`
+	for _, tt := range []struct {
+		query     string
+		variables map[string]any
+	}{
+		{`{ voivodeships { stations(limit: -100000) { ...details } } }` + details, nil},
+		{`{ voivodeships { stations(limit: 1000) { ...details } } }` + details, nil},
+		{`query($n: Int) { voivodeships { stations(limit: $n) { ...details } } }` + details, map[string]any{"n": -100000}},
+		{`query($n: Int) { voivodeships { stations(limit: $n) { ...details } } }` + details, map[string]any{"n": 1000}},
+	} {
+		service := newFakeService(t)
+		result := execute(t, service, nil, tt.query, tt.variables)
+		assert.Contains(t, result["errors"].([]any)[0].(map[string]any)["message"], "limit of stations has to be within 0-100", tt.query)
+		assert.Nil(t, result["data"])
+		assert.Empty(t, service.batches, tt.query)
+	}
+}
+
+func TestLimitsFromEnv(t *testing.T) {
+	limits, err := LimitsFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{MaxDepth: defaultMaxDepth, MaxCost: defaultMaxCost}, limits)
+
+	t.Setenv("GRAPHQL_MAX_COST", "500")
+	limits, err = LimitsFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 500, limits.MaxCost)
+
+	t.Setenv("GRAPHQL_MAX_DEPTH", "0")
+	_, err = LimitsFromEnv()
+	assert.ErrorContains(t, err, "invalid GRAPHQL_MAX_DEPTH")
+}
@@ -0,0 +1,179 @@
+package gql
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	defaultMaxDepth = 6
+	defaultMaxCost  = 2000
+)
+
+// fieldCosts are the costs of the fields that aggregate data or fetch from
+// the sources. Other fields with a selection cost 1 and scalar fields
+// nothing.
+var fieldCosts = map[string]int{
+	"aggregate":       10,
+	"aggregates":      160,
+	"station":         2,
+	"measurements":    2,
+	"lastMeasurement": 2,
+	"history":         2,
+}
+
+// listSizes are the assumed lengths of list fields without a limit argument,
+// which multiply the cost of their selections.
+var listSizes = map[string]int{
+	"voivodeships": 16,
+	"aggregates":   16,
+	"stations":     defaultStationsLimit,
+	"parameters":   20,
+	"measurements": 10,
+	"points":       200,
+	"changes":      3,
+}
+
+// Limits bound the depth and the estimated cost of a query.
+type Limits struct {
+	MaxDepth int
+	MaxCost  int
+}
+
+// LimitsFromEnv reads the limits from GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COST.
+func LimitsFromEnv() (Limits, error) {
+	l := Limits{MaxDepth: defaultMaxDepth, MaxCost: defaultMaxCost}
+	for name, target := range map[string]*int{"GRAPHQL_MAX_DEPTH": &l.MaxDepth, "GRAPHQL_MAX_COST": &l.MaxCost} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 1 {
+			return Limits{}, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		*target = v
+	}
+	return l, nil
+}
+
+// check measures the operation and rejects it if it is too deep or too
+// costly. Fragments are expanded where they are spread.
+func (l Limits) check(op *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variables map[string]any) error {
+	m := &measurer{fragments: fragments, variables: variables}
+	depth, cost := m.measure(op.SelectionSet, 1, 0)
+	if m.err != nil {
+		return m.err
+	}
+	if depth > l.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, l.MaxDepth)
+	}
+	if cost > l.MaxCost {
+		return fmt.Errorf("query cost %d exceeds the limit of %d", cost, l.MaxCost)
+	}
+	return nil
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// err is the first limit argument out of range.
+	err error
+}
+
+// measure returns the depth of the selection set and its cost when selected
+// multiplier times. Spreads of fragments being expanded are skipped, as the
+// validation rejects fragment cycles anyway.
+func (m *measurer) measure(set *ast.SelectionSet, multiplier, level int) (depth, cost int) {
+	if set == nil {
+		return level, 0
+	}
+	depth = level
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			name := s.Name.Value
+			if name == "__typename" {
+				continue
+			}
+			fieldCost, exists := fieldCosts[name]
+			if !exists && s.SelectionSet != nil {
+				fieldCost = 1
+			}
+			size := 1
+			if s.SelectionSet != nil {
+				if n, exists := listSizes[name]; exists {
+					size = n
+				}
+				if n, ok, err := m.limitArgument(s); err != nil {
+					if m.err == nil {
+						m.err = err
+					}
+				} else if ok {
+					size = n
+				}
+			}
+			d, c = m.measure(s.SelectionSet, multiplier*size, level+1)
+			c += multiplier * fieldCost
+		case *ast.InlineFragment:
+			d, c = m.measure(s.SelectionSet, multiplier, level)
+		case *ast.FragmentSpread:
+			fragment, exists := m.fragments[s.Name.Value]
+			if !exists {
+				continue
+			}
+			inner := m.fragments
+			m.fragments = make(map[string]*ast.FragmentDefinition, len(inner))
+			for name, f := range inner {
+				if name != s.Name.Value {
+					m.fragments[name] = f
+				}
+			}
+			d, c = m.measure(fragment.SelectionSet, multiplier, level)
+			m.fragments = inner
+		}
+		depth = max(depth, d)
+		cost += c
+	}
+	return depth, cost
+}
+
+// limitArgument returns the value of the limit argument of a field, given
+// literally or as a variable. A limit out of range is an error, so that it
+// can't lower the cost of a query before the resolver rejects it.
+func (m *measurer) limitArgument(f *ast.Field) (int, bool, error) {
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		var n float64
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			parsed, err := strconv.ParseFloat(v.Value, 64)
+			if err != nil {
+				return 0, false, nil
+			}
+			n = parsed
+		case *ast.Variable:
+			switch value := m.variables[v.Name.Value].(type) {
+			case float64:
+				n = value
+			case int:
+				n = float64(value)
+			default:
+				return 0, false, nil
+			}
+		default:
+			return 0, false, nil
+		}
+		if n < 0 || n > maxStationsLimit {
+			return 0, false, fmt.Errorf("limit of %s has to be within 0-%d", f.Name.Value, maxStationsLimit)
+		}
+		return int(n), true, nil
+	}
+	return 0, false, nil
+}
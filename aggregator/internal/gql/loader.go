@@ -0,0 +1,65 @@
+package gql
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/matching"
+	"context"
+	"sync"
+)
+
+// batchFunc fetches the stations of a batch of keys. Unknown stations are
+// left out of the result.
+type batchFunc func(ctx context.Context, keys []matching.Key) (map[matching.Key]api.StationDetails, error)
+
+type stationResult struct {
+	details api.StationDetails
+	found   bool
+	err     error
+}
+
+// stationLoader batches the station lookups of a query. Resolvers queue keys
+// with load and get a thunk back; the executor only calls the thunks after
+// resolving all fields of a level, so the first thunk called fetches every
+// queued key at once. Results are cached for the rest of the query.
+type stationLoader struct {
+	ctx     context.Context
+	fetch   batchFunc
+	mu      sync.Mutex
+	queued  []matching.Key
+	results map[matching.Key]stationResult
+	// batches counts the batches fetched, for tests.
+	batches int
+}
+
+func newStationLoader(ctx context.Context, fetch batchFunc) *stationLoader {
+	return &stationLoader{ctx: ctx, fetch: fetch, results: make(map[matching.Key]stationResult)}
+}
+
+// load queues the key and returns a thunk yielding its station.
+func (l *stationLoader) load(key matching.Key) func() stationResult {
+	l.mu.Lock()
+	if _, exists := l.results[key]; !exists {
+		l.queued = append(l.queued, key)
+	}
+	l.mu.Unlock()
+	return func() stationResult {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, exists := l.results[key]; !exists {
+			l.dispatch()
+		}
+		return l.results[key]
+	}
+}
+
+// dispatch fetches all queued keys. It is called with the lock held.
+func (l *stationLoader) dispatch() {
+	keys := l.queued
+	l.queued = nil
+	l.batches++
+	fetched, err := l.fetch(l.ctx, keys)
+	for _, key := range keys {
+		details, found := fetched[key]
+		l.results[key] = stationResult{details: details, found: found, err: err}
+	}
+}
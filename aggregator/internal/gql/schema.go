@@ -0,0 +1,514 @@
+package gql
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"aggregator/internal/history"
+	"aggregator/internal/matching"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultStationsLimit = 20
+	maxStationsLimit     = 100
+	// defaultHistoryPeriod is how far back history goes without a from
+	// argument.
+	defaultHistoryPeriod = 24 * time.Hour
+)
+
+// historySeries is the source of the History type.
+type historySeries struct {
+	param  api.ParamType
+	unit   string
+	points []history.Point
+}
+
+// newSchema builds the schema. Its resolvers take the service, the catalog
+// and the station loader of a query from the context.
+func newSchema() (graphql.Schema, error) {
+	aqiType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Aqi",
+		Description: "Air quality index of a region, set by its worst parameter.",
+		Fields: graphql.Fields{
+			"level": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "1-based level, 1 being the best."},
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"label": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Name of the level in the language of the request.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).catalog.AqiLabel(p.Source.(aqi.Result).Name), nil
+				},
+			},
+			"score":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"dominant": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	changeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Change",
+		Description: "Change of a value since the same hour one period earlier.",
+		Fields: graphql.Fields{
+			"period":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"previous": floatField(func(src any) *float32 { c := src.(api.Change); return &c.Previous }),
+			"delta":    floatField(func(src any) *float32 { c := src.(api.Change); return &c.Delta }),
+			"percent":  &graphql.Field{Type: graphql.Float},
+			"trend":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	parameterType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Parameter",
+		Description: "Aggregated value of a parameter.",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).catalog.ParamName(p.Source.(api.Parameter).Type), nil
+				},
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					param := p.Source.(api.Parameter)
+					return state(p).catalog.ParamDescription(param.Type, param.Description), nil
+				},
+			},
+			"unit":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value":  floatField(func(src any) *float32 { return src.(api.Parameter).Value }),
+			"status": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"changes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(changeType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if changes := p.Source.(api.Parameter).Changes; changes != nil {
+						return changes, nil
+					}
+					return []api.Change{}, nil
+				},
+			},
+		},
+	})
+
+	definitionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ParameterDefinition",
+		Description: "A supported parameter.",
+		Fields: graphql.Fields{
+			"type": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).catalog.ParamName(p.Source.(api.ParamDefinition).Type), nil
+				},
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					d := p.Source.(api.ParamDefinition)
+					return state(p).catalog.ParamDescription(d.Type, d.Description), nil
+				},
+			},
+			"unit": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	measurementType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StationMeasurement",
+		Description: "Latest reading of a parameter at a station.",
+		Fields: graphql.Fields{
+			"type":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": floatField(func(src any) *float32 { m := src.(api.StationMeasurement); return &m.Value }),
+			"unit":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"timestamp": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(api.StationMeasurement).Timestamp.UTC().Format(time.RFC3339), nil
+				},
+			},
+		},
+	})
+
+	stationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Station",
+		Description: "A measuring station. Its parameters and measurements are fetched from its source, batched across the query.",
+		Fields: graphql.Fields{
+			"source":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"latitude":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"longitude": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"locality":  &graphql.Field{Type: graphql.String},
+			"parameters": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: stationDetails(func(d api.StationDetails) any {
+					if d.Parameters == nil {
+						return []api.ParamType{}
+					}
+					return d.Parameters
+				}),
+			},
+			"lastMeasurement": &graphql.Field{
+				Type: graphql.String,
+				Resolve: stationDetails(func(d api.StationDetails) any {
+					if d.LastMeasurement == nil {
+						return nil
+					}
+					return d.LastMeasurement.UTC().Format(time.RFC3339)
+				}),
+			},
+			"measurements": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(measurementType))),
+				Resolve: stationDetails(func(d api.StationDetails) any {
+					if d.Measurements == nil {
+						return []api.StationMeasurement{}
+					}
+					return d.Measurements
+				}),
+			},
+		},
+	})
+
+	pointType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "HistoryPoint",
+		Fields: graphql.Fields{
+			"hour": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(history.Point).Hour.Format(time.RFC3339), nil
+				},
+			},
+			"value": floatField(func(src any) *float32 { point := src.(history.Point); return &point.Value }),
+		},
+	})
+
+	historyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "History",
+		Description: "Recorded hourly values of a parameter with their statistics.",
+		Fields: graphql.Fields{
+			"parameter": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(historySeries).param, nil },
+			},
+			"unit": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(historySeries).unit, nil },
+			},
+			"points": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(pointType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					points := p.Source.(historySeries).points
+					if points == nil {
+						return []history.Point{}, nil
+					}
+					return points, nil
+				},
+			},
+			"min":  historyStat(func(min, _, _ float32) float32 { return min }),
+			"max":  historyStat(func(_, max, _ float32) float32 { return max }),
+			"mean": historyStat(func(_, _, mean float32) float32 { return mean }),
+		},
+	})
+
+	voivodeshipType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Voivodeship",
+		Description: "A region of the region set.",
+		Fields:      graphql.Fields{},
+	})
+	aggregateType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Aggregate",
+		Description: "Values of a region aggregated from all sources for one hour.",
+		Fields: graphql.Fields{
+			"voivodeship": &graphql.Field{
+				Type:    graphql.NewNonNull(voivodeshipType),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(api.AggregatedData).Region, nil },
+			},
+			"timestamp": &graphql.Field{Type: graphql.String, Description: "Time of the latest measurement behind the values."},
+			"hour":      &graphql.Field{Type: graphql.String, Description: "Start of the UTC hour the values were aggregated for."},
+			"mergeStrategy": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(api.AggregatedData).Merge.Strategy, nil },
+			},
+			"parameters": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(parameterType)))},
+			"aqi": &graphql.Field{
+				Type: aqiType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					values := make(map[api.ParamType]float32)
+					for _, param := range p.Source.(api.AggregatedData).Parameters {
+						if param.Value != nil {
+							values[param.Type] = *param.Value
+						}
+					}
+					idx := state(p).service.AirQualityIndex()
+					if idx == nil {
+						return nil, nil
+					}
+					if result, ok := idx.Evaluate(values); ok {
+						return result, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	aggregateArgs := graphql.FieldConfigArgument{
+		"params": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Parameters to aggregate, all by default."},
+		"hour":   &graphql.ArgumentConfig{Type: graphql.String, Description: "RFC 3339 time of the hour to aggregate, the latest complete one by default."},
+		"merge":  &graphql.ArgumentConfig{Type: graphql.String, Description: "Merge strategy, the configured one by default."},
+	}
+	voivodeshipType.AddFieldConfig("id", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (any, error) { return p.Source.(api.Region), nil },
+	})
+	voivodeshipType.AddFieldConfig("name", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.String),
+		Description: "Display name in the language of the request.",
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return state(p).catalog.RegionName(p.Source.(api.Region)), nil
+		},
+	})
+	voivodeshipType.AddFieldConfig("stations", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(stationType))),
+		Args: graphql.FieldConfigArgument{
+			"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultStationsLimit},
+			"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+			if limit < 0 || limit > maxStationsLimit || offset < 0 {
+				return nil, fmt.Errorf("limit has to be within 0-%d and offset positive", maxStationsLimit)
+			}
+			stations, err := state(p).service.RegionStations(p.Source.(api.Region))
+			if err != nil {
+				return nil, err
+			}
+			stations = stations[min(offset, len(stations)):]
+			return stations[:min(limit, len(stations))], nil
+		},
+	})
+	voivodeshipType.AddFieldConfig("aggregate", &graphql.Field{
+		Type: graphql.NewNonNull(aggregateType),
+		Args: aggregateArgs,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			s := state(p)
+			opts, err := parseOptions(s.service.Params(), p.Args)
+			if err != nil {
+				return nil, err
+			}
+			return s.service.AggregateForRegion(p.Context, p.Source.(api.Region), opts)
+		},
+	})
+	voivodeshipType.AddFieldConfig("history", &graphql.Field{
+		Type: graphql.NewNonNull(historyType),
+		Args: graphql.FieldConfigArgument{
+			"param": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"from":  &graphql.ArgumentConfig{Type: graphql.String, Description: "RFC 3339 start, 24 hours before to by default."},
+			"to":    &graphql.ArgumentConfig{Type: graphql.String, Description: "RFC 3339 end, now by default."},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			registry := state(p).service.Params()
+			paramType, err := registry.ParamType(p.Args["param"].(string))
+			if err != nil {
+				return nil, err
+			}
+			to, err := timeArg(p.Args, "to", time.Now())
+			if err != nil {
+				return nil, err
+			}
+			from, err := timeArg(p.Args, "from", to.Add(-defaultHistoryPeriod))
+			if err != nil {
+				return nil, err
+			}
+			d, _ := registry.Definition(paramType)
+			return historySeries{
+				param:  paramType,
+				unit:   d.Unit,
+				points: state(p).service.History(p.Source.(api.Region), paramType, from, to),
+			}, nil
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"voivodeships": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(voivodeshipType))),
+				Description: "All regions of the region set.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).service.RegionSet().Regions, nil
+				},
+			},
+			"voivodeship": &graphql.Field{
+				Type: voivodeshipType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).service.RegionSet().Region(p.Args["id"].(string))
+				},
+			},
+			"parameters": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(definitionType))),
+				Description: "All supported parameters.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return state(p).service.Params().Definitions(), nil
+				},
+			},
+			"station": &graphql.Field{
+				Type: stationType,
+				Args: graphql.FieldConfigArgument{
+					"source": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					source, err := api.MapSource(p.Args["source"].(string))
+					if err != nil {
+						return nil, err
+					}
+					thunk := state(p).stations.load(matching.Key{Source: source, Id: p.Args["id"].(int)})
+					return func() (any, error) {
+						r := thunk()
+						if r.err != nil || !r.found {
+							return nil, r.err
+						}
+						return r.details.Station, nil
+					}, nil
+				},
+			},
+			"aggregates": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(aggregateType))),
+				Description: "Aggregated values of the given regions, or of all of them.",
+				Args: graphql.FieldConfigArgument{
+					"voivodeships": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"params":       aggregateArgs["params"],
+					"hour":         aggregateArgs["hour"],
+					"merge":        aggregateArgs["merge"],
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					s := state(p)
+					var regions []api.Region
+					for _, v := range stringsArg(p.Args, "voivodeships") {
+						region, err := s.service.RegionSet().Region(v)
+						if err != nil {
+							return nil, err
+						}
+						regions = append(regions, region)
+					}
+					opts, err := parseOptions(s.service.Params(), p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return s.service.AggregateAll(p.Context, regions, opts)
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// floatField resolves a float32 value, formatted the way JSON encodes it
+// rather than with the noise of a conversion to float64.
+func floatField(value func(src any) *float32) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.Float,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			v := value(p.Source)
+			if v == nil {
+				return nil, nil
+			}
+			return float(*v), nil
+		},
+	}
+}
+
+func float(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	return f
+}
+
+func historyStat(stat func(min, max, mean float32) float32) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.Float,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			points := p.Source.(historySeries).points
+			if len(points) == 0 {
+				return nil, nil
+			}
+			lowest, highest, sum := points[0].Value, points[0].Value, float32(0)
+			for _, point := range points {
+				lowest, highest, sum = min(lowest, point.Value), max(highest, point.Value), sum+point.Value
+			}
+			return float(stat(lowest, highest, sum/float32(len(points)))), nil
+		},
+	}
+}
+
+// stationDetails resolves a field from the details of a station, loaded
+// through the station loader of the query.
+func stationDetails(field func(api.StationDetails) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		station := p.Source.(api.Station)
+		thunk := state(p).stations.load(matching.Key{Source: station.Source, Id: station.Id})
+		return func() (any, error) {
+			r := thunk()
+			if r.err != nil {
+				return nil, r.err
+			}
+			if !r.found {
+				return nil, fmt.Errorf("%w: %s/%d", aggregator.ErrStationNotFound, station.Source, station.Id)
+			}
+			return field(r.details), nil
+		}, nil
+	}
+}
+
+func parseOptions(registry *api.Registry, args map[string]any) (aggregator.Options, error) {
+	var opts aggregator.Options
+	for _, p := range stringsArg(args, "params") {
+		paramType, err := registry.ParamType(p)
+		if err != nil {
+			return aggregator.Options{}, err
+		}
+		opts.Params = append(opts.Params, paramType)
+	}
+	if m, ok := args["merge"].(string); ok {
+		strategy, err := api.MapMergeStrategy(m)
+		if err != nil {
+			return aggregator.Options{}, err
+		}
+		opts.Strategy = strategy
+	}
+	if _, ok := args["hour"].(string); ok {
+		hour, err := timeArg(args, "hour", time.Time{})
+		if err != nil {
+			return aggregator.Options{}, err
+		}
+		opts.Hour = hour.UTC().Truncate(time.Hour)
+	}
+	return opts, nil
+}
+
+func timeArg(args map[string]any, name string, fallback time.Time) (time.Time, error) {
+	value, ok := args[name].(string)
+	if !ok {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return t, nil
+}
+
+func stringsArg(args map[string]any, name string) []string {
+	values, _ := args[name].([]any)
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
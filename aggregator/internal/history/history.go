@@ -0,0 +1,207 @@
+// Package history keeps the hourly aggregated values of every region.
+package history
+
+import (
+	"aggregator/internal/api"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDataDir = "data"
+	hourlyFile     = "hourly.json"
+)
+
+// DataDir returns the directory the data of a region set is persisted in,
+// below the directory set by DATA_DIR.
+func DataDir(regionSet string) string {
+	dir := os.Getenv("DATA_DIR")
+	if dir == "" {
+		dir = defaultDataDir
+	}
+	return filepath.Join(dir, regionSet)
+}
+
+// StorePath returns the path of the hourly values file of a region set.
+func StorePath(regionSet string) string {
+	return filepath.Join(DataDir(regionSet), hourlyFile)
+}
+
+// Point is the aggregated value of one parameter for the UTC hour starting at Hour.
+type Point struct {
+	Hour  time.Time `json:"hour"`
+	Value float32   `json:"value"`
+}
+
+type seriesKey struct {
+	region    api.Region
+	paramType api.ParamType
+}
+
+// Store keeps hourly values in memory and persists them to a JSON file when
+// it has a path. Hours older than the retention, counted from the latest
+// recorded hour, are dropped whenever new values are recorded.
+type Store struct {
+	path      string
+	retention time.Duration
+	mu        sync.RWMutex
+	series    map[seriesKey]map[time.Time]float32
+	latest    time.Time
+}
+
+// NewStore creates an empty store that is kept in memory only.
+func NewStore(retention time.Duration) *Store {
+	return &Store{
+		retention: retention,
+		series:    make(map[seriesKey]map[time.Time]float32),
+	}
+}
+
+// fileSeries is the layout of the file, the values by region, parameter and hour.
+type fileSeries map[api.Region]map[api.ParamType]map[time.Time]float32
+
+// OpenStore loads the hourly values saved at path. A missing file yields an
+// empty store.
+func OpenStore(path string, retention time.Duration) (*Store, error) {
+	s := NewStore(retention)
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hourly values file: %w", err)
+	}
+	var saved fileSeries
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parsing hourly values file: %w", err)
+	}
+	for region, params := range saved {
+		for paramType, points := range params {
+			s.series[seriesKey{region, paramType}] = points
+			for hour := range points {
+				if hour.After(s.latest) {
+					s.latest = hour
+				}
+			}
+		}
+	}
+	s.prune(s.latest.Add(-s.retention))
+	return s, nil
+}
+
+// Save writes the hourly values to the file the store was opened from. It
+// does nothing for a store kept in memory only.
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.RLock()
+	saved := make(fileSeries)
+	for key, points := range s.series {
+		if saved[key.region] == nil {
+			saved[key.region] = make(map[api.ParamType]map[time.Time]float32)
+		}
+		saved[key.region][key.paramType] = points
+	}
+	data, err := json.Marshal(saved)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding hourly values: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing hourly values file: %w", err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing hourly values file: %w", err)
+	}
+	return nil
+}
+
+// Record stores the values of a region for the hour starting at hour,
+// replacing the ones recorded for that hour before.
+func (s *Store) Record(region api.Region, hour time.Time, values map[api.ParamType]float32) {
+	hour = hour.UTC().Truncate(time.Hour)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for paramType, value := range values {
+		key := seriesKey{region, paramType}
+		if s.series[key] == nil {
+			s.series[key] = make(map[time.Time]float32)
+		}
+		s.series[key][hour] = value
+	}
+	if hour.After(s.latest) {
+		s.latest = hour
+	}
+	s.prune(s.latest.Add(-s.retention))
+}
+
+func (s *Store) prune(before time.Time) {
+	for key, points := range s.series {
+		for hour := range points {
+			if hour.Before(before) {
+				delete(points, hour)
+			}
+		}
+		if len(points) == 0 {
+			delete(s.series, key)
+		}
+	}
+}
+
+// Series returns the values of a parameter for the hours in [from, to],
+// ordered by hour. Hours without a value are left out.
+func (s *Store) Series(region api.Region, paramType api.ParamType, from, to time.Time) []Point {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var points []Point
+	for hour, value := range s.series[seriesKey{region, paramType}] {
+		if !hour.Before(from) && !hour.After(to) {
+			points = append(points, Point{Hour: hour, Value: value})
+		}
+	}
+	slices.SortFunc(points, func(a, b Point) int { return a.Hour.Compare(b.Hour) })
+	return points
+}
+
+// Latest returns the most recent hour with any recorded value for the
+// region, or the zero time when there is none.
+func (s *Store) Latest(region api.Region) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest time.Time
+	for key, points := range s.series {
+		if key.region != region {
+			continue
+		}
+		for hour := range points {
+			if hour.After(latest) {
+				latest = hour
+			}
+		}
+	}
+	return latest
+}
+
+// Replace swaps the recorded values for those of other, which is not used
+// afterwards. The store keeps its own path and retention.
+func (s *Store) Replace(other *Store) {
+	other.mu.RLock()
+	series, latest := other.series, other.latest
+	other.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series = series
+	s.latest = latest
+}
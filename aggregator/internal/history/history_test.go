@@ -0,0 +1,53 @@
+package history
+
+import (
+	"aggregator/internal/api"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(24 * time.Hour)
+	h := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	s.Record(api.Malopolskie, h.Add(-time.Hour), map[api.ParamType]float32{api.PM10: 10})
+	s.Record(api.Malopolskie, h.Add(30*time.Minute), map[api.ParamType]float32{api.PM10: 20, api.O3: 5})
+	s.Record(api.Slaskie, h, map[api.ParamType]float32{api.PM10: 99})
+
+	assert.Equal(t, []Point{{Hour: h.Add(-time.Hour), Value: 10}, {Hour: h, Value: 20}},
+		s.Series(api.Malopolskie, api.PM10, h.Add(-24*time.Hour), h))
+	assert.Equal(t, []Point{{Hour: h, Value: 5}}, s.Series(api.Malopolskie, api.O3, h, h))
+	assert.Equal(t, h, s.Latest(api.Malopolskie))
+	assert.True(t, s.Latest(api.Pomorskie).IsZero())
+
+	s.Record(api.Malopolskie, h.Add(24*time.Hour), map[api.ParamType]float32{api.PM10: 30})
+	assert.Equal(t, []Point{{Hour: h, Value: 20}, {Hour: h.Add(24 * time.Hour), Value: 30}},
+		s.Series(api.Malopolskie, api.PM10, h.Add(-48*time.Hour), h.Add(48*time.Hour)))
+
+	s.Record(api.Malopolskie, h.Add(-time.Hour), map[api.ParamType]float32{api.PM10: 10})
+	assert.Empty(t, s.Series(api.Malopolskie, api.PM10, h.Add(-time.Hour), h.Add(-time.Hour)))
+}
+
+func TestStoreSaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pl", "hourly.json")
+	s, err := OpenStore(path, 24*time.Hour)
+	assert.NoError(t, err)
+	h := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.Record(api.Malopolskie, h.Add(-time.Hour), map[api.ParamType]float32{api.PM10: 10})
+	s.Record(api.Malopolskie, h, map[api.ParamType]float32{api.PM10: 20, api.O3: 5})
+	assert.NoError(t, s.Save())
+
+	reopened, err := OpenStore(path, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, s.Series(api.Malopolskie, api.PM10, h.Add(-time.Hour), h), reopened.Series(api.Malopolskie, api.PM10, h.Add(-time.Hour), h))
+	assert.Equal(t, []Point{{Hour: h, Value: 5}}, reopened.Series(api.Malopolskie, api.O3, h, h))
+	assert.Equal(t, h, reopened.Latest(api.Malopolskie))
+
+	os.WriteFile(path, []byte("{"), 0o644)
+	_, err = OpenStore(path, 24*time.Hour)
+	assert.ErrorContains(t, err, "parsing hourly values file")
+}
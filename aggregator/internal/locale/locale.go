@@ -0,0 +1,152 @@
+// Package locale holds the translated names of parameters, regions and AQI
+// levels and picks the language of a request.
+package locale
+
+import (
+	"aggregator/internal/api"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultDir      = "config/locales"
+	defaultLanguage = "en"
+)
+
+// ParamText is the translated name and description of a parameter.
+type ParamText struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Catalog holds the texts of one language. Regions are keyed by their id and
+// AQI levels by their name.
+type Catalog struct {
+	Language   string                      `json:"-"`
+	Parameters map[api.ParamType]ParamText `json:"parameters"`
+	Regions    map[api.Region]string       `json:"regions"`
+	Aqi        map[string]string           `json:"aqi"`
+}
+
+// ParamName returns the name of a parameter, or its type if the catalog has
+// none.
+func (c *Catalog) ParamName(paramType api.ParamType) string {
+	if t, exists := c.Parameters[paramType]; exists && t.Name != "" {
+		return t.Name
+	}
+	return string(paramType)
+}
+
+// ParamDescription returns the description of a parameter, or fallback if
+// the catalog has none.
+func (c *Catalog) ParamDescription(paramType api.ParamType, fallback string) string {
+	if t, exists := c.Parameters[paramType]; exists && t.Description != "" {
+		return t.Description
+	}
+	return fallback
+}
+
+// RegionName returns the display name of a region, or its id if the catalog
+// has none.
+func (c *Catalog) RegionName(region api.Region) string {
+	if name, exists := c.Regions[region]; exists {
+		return name
+	}
+	return string(region)
+}
+
+// AqiLabel returns the label of an AQI level, or its name if the catalog has
+// none.
+func (c *Catalog) AqiLabel(level string) string {
+	if label, exists := c.Aqi[level]; exists {
+		return label
+	}
+	return level
+}
+
+// Catalogs holds the catalog of every supported language.
+type Catalogs struct {
+	catalogs map[string]*Catalog
+	fallback string
+}
+
+// Dir returns the directory of the catalogs set by LOCALES_DIR.
+func Dir() string {
+	if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+		return dir
+	}
+	return defaultDir
+}
+
+// LoadCatalogs loads a catalog from every JSON file in dir, named after its
+// language. DEFAULT_LANGUAGE, English by default, is used when a request
+// accepts none of the languages and has to have a catalog.
+func LoadCatalogs(dir string) (*Catalogs, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing catalogs: %w", err)
+	}
+	cs := &Catalogs{catalogs: make(map[string]*Catalog, len(paths)), fallback: os.Getenv("DEFAULT_LANGUAGE")}
+	if cs.fallback == "" {
+		cs.fallback = defaultLanguage
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading catalog: %w", err)
+		}
+		var c Catalog
+		if err = json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("parsing catalog %s: %w", filepath.Base(path), err)
+		}
+		c.Language = strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".json"))
+		cs.catalogs[c.Language] = &c
+	}
+	if _, exists := cs.catalogs[cs.fallback]; !exists {
+		return nil, fmt.Errorf("no catalog for the default language %s", cs.fallback)
+	}
+	return cs, nil
+}
+
+// Languages returns the supported languages, sorted.
+func (cs *Catalogs) Languages() []string {
+	languages := make([]string, 0, len(cs.catalogs))
+	for language := range cs.catalogs {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Catalog returns the catalog of a language, ignoring any region subtag.
+func (cs *Catalogs) Catalog(language string) (*Catalog, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(language)), "-")
+	c, exists := cs.catalogs[base]
+	return c, exists
+}
+
+// Negotiate picks the catalog of the most preferred supported language of an
+// Accept-Language header, or the default one.
+func (cs *Catalogs) Negotiate(acceptLanguage string) *Catalog {
+	best, bestQuality := cs.catalogs[cs.fallback], 0.0
+	for item := range strings.SplitSeq(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(item, ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = v
+		}
+		if c, exists := cs.Catalog(tag); exists && quality > bestQuality {
+			best, bestQuality = c, quality
+		}
+	}
+	return best
+}
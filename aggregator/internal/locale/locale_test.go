@@ -0,0 +1,75 @@
+package locale
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	cs, err := LoadCatalogs("../../config/locales")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en", "pl"}, cs.Languages())
+
+	assert.Equal(t, "en", cs.Negotiate("").Language)
+	assert.Equal(t, "pl", cs.Negotiate("pl-PL,pl;q=0.9,en;q=0.8").Language)
+	assert.Equal(t, "en", cs.Negotiate("de-DE, en;q=0.5, pl;q=0.4").Language)
+	assert.Equal(t, "pl", cs.Negotiate("fr, *;q=0.5, PL;q=0.1").Language)
+	assert.Equal(t, "en", cs.Negotiate("fr").Language)
+
+	c, ok := cs.Catalog("pl")
+	assert.True(t, ok)
+	assert.Equal(t, "Śląskie", c.RegionName(api.Slaskie))
+	assert.Equal(t, "unknown", c.RegionName("unknown"))
+	assert.Equal(t, "Dwutlenek azotu", c.ParamName(api.NO2))
+	assert.Equal(t, "XY", c.ParamName("XY"))
+	assert.Equal(t, "fallback", c.ParamDescription("XY", "fallback"))
+	assert.Equal(t, "Bardzo zła", c.AqiLabel("very-poor"))
+	_, ok = cs.Catalog("de")
+	assert.False(t, ok)
+}
+
+// TestCatalogsAreComplete checks that every catalog names every parameter,
+// region and AQI level the service can return.
+func TestCatalogsAreComplete(t *testing.T) {
+	cs, err := LoadCatalogs("../../config/locales")
+	assert.NoError(t, err)
+	registry, err := api.LoadRegistry("../../config/parameters.json")
+	assert.NoError(t, err)
+	idx, err := aqi.LoadIndex("../../config/aqi.json")
+	assert.NoError(t, err)
+	sets, err := api.LoadRegionSets("../../config/regions.json")
+	assert.NoError(t, err)
+
+	for _, language := range cs.Languages() {
+		c, _ := cs.Catalog(language)
+		for _, d := range registry.Definitions() {
+			assert.NotEmpty(t, c.Parameters[d.Type].Name, "%s: name of %s", language, d.Type)
+			assert.NotEmpty(t, c.Parameters[d.Type].Description, "%s: description of %s", language, d.Type)
+		}
+		for _, set := range sets {
+			for _, region := range set.Regions {
+				assert.Contains(t, c.Regions, region, "%s: name of %s", language, region)
+			}
+		}
+		for _, level := range idx.Levels {
+			assert.Contains(t, c.Aqi, level, "%s: label of %s", language, level)
+		}
+	}
+}
+
+func TestLoadCatalogsNeedsDefaultLanguage(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "pl.json"), []byte(`{"regions": {}}`), 0o644))
+	_, err := LoadCatalogs(dir)
+	assert.ErrorContains(t, err, "no catalog for the default language en")
+
+	t.Setenv("DEFAULT_LANGUAGE", "pl")
+	cs, err := LoadCatalogs(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "pl", cs.Negotiate("en").Language)
+}
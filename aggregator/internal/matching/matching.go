@@ -0,0 +1,264 @@
+// Package matching finds stations of different sources that describe the same
+// physical measuring site.
+package matching
+
+import (
+	"aggregator/internal/api"
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxDistance = 300.0
+	defaultTieDistance = 50.0
+	earthRadius        = 6371000.0
+)
+
+var defaultPreference = []api.Source{api.OpenAq, api.OpenMeteo}
+
+type Config struct {
+	// MaxDistance in meters between two stations that can be matched.
+	// Zero disables matching.
+	MaxDistance float64
+	// TieDistance in meters within which candidates are considered equally
+	// close, so that name similarity decides between them.
+	TieDistance float64
+	// Preference orders the sources by which station represents a matched
+	// site, most preferred first.
+	Preference []api.Source
+}
+
+// ConfigFromEnv reads the configuration from STATION_MATCH_MAX_DISTANCE,
+// STATION_MATCH_TIE_DISTANCE and STATION_SOURCE_PREFERENCE.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		MaxDistance: defaultMaxDistance,
+		TieDistance: defaultTieDistance,
+		Preference:  defaultPreference,
+	}
+	var err error
+	if v := os.Getenv("STATION_MATCH_MAX_DISTANCE"); v != "" {
+		if cfg.MaxDistance, err = strconv.ParseFloat(v, 64); err != nil {
+			return Config{}, fmt.Errorf("parsing STATION_MATCH_MAX_DISTANCE: %w", err)
+		}
+	}
+	if v := os.Getenv("STATION_MATCH_TIE_DISTANCE"); v != "" {
+		if cfg.TieDistance, err = strconv.ParseFloat(v, 64); err != nil {
+			return Config{}, fmt.Errorf("parsing STATION_MATCH_TIE_DISTANCE: %w", err)
+		}
+	}
+	if v := os.Getenv("STATION_SOURCE_PREFERENCE"); v != "" {
+		cfg.Preference = nil
+		for name := range strings.SplitSeq(v, ",") {
+			source, err := api.MapSource(strings.TrimSpace(name))
+			if err != nil {
+				return Config{}, fmt.Errorf("parsing STATION_SOURCE_PREFERENCE: %w", err)
+			}
+			cfg.Preference = append(cfg.Preference, source)
+		}
+	}
+	return cfg, nil
+}
+
+type Key struct {
+	Source api.Source `json:"source"`
+	Id     int        `json:"id"`
+}
+
+type Site struct {
+	Key
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Match is a group of stations from different sources at the same site.
+type Match struct {
+	Sites     []Site `json:"sites"`
+	Preferred Key    `json:"preferred"`
+	// DistanceMeters is the largest distance between two stations of the match.
+	DistanceMeters float64 `json:"distanceMeters"`
+	// NameSimilarity is the lowest name similarity between two stations of the match.
+	NameSimilarity float64 `json:"nameSimilarity"`
+}
+
+// ByPreference returns the stations of the match in order of the source
+// preference, the preferred station first.
+func (m Match) ByPreference(preference []api.Source) []Key {
+	keys := make([]Key, len(m.Sites))
+	for i, site := range m.Sites {
+		keys[i] = site.Key
+	}
+	slices.SortStableFunc(keys, func(x, y Key) int {
+		return cmp.Or(cmp.Compare(rank(preference, x.Source), rank(preference, y.Source)), cmp.Compare(x.Id, y.Id))
+	})
+	return keys
+}
+
+type candidate struct {
+	a, b       int
+	distance   float64
+	similarity float64
+}
+
+// FindMatches clusters stations of different sources that lie within the
+// configured distance. Closer pairs are matched first; pairs that are about
+// equally close are ordered by name similarity. A match never holds two
+// stations of the same source.
+func FindMatches(sites []Site, cfg Config) []Match {
+	if cfg.MaxDistance <= 0 {
+		return nil
+	}
+
+	var candidates []candidate
+	for i := range sites {
+		for j := i + 1; j < len(sites); j++ {
+			if sites[i].Source == sites[j].Source {
+				continue
+			}
+			d := Distance(sites[i], sites[j])
+			if d > cfg.MaxDistance {
+				continue
+			}
+			candidates = append(candidates, candidate{a: i, b: j, distance: d, similarity: NameSimilarity(sites[i].Name, sites[j].Name)})
+		}
+	}
+	slices.SortStableFunc(candidates, func(x, y candidate) int {
+		if c := cmp.Compare(tieBucket(x.distance, cfg.TieDistance), tieBucket(y.distance, cfg.TieDistance)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(y.similarity, x.similarity); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.distance, y.distance)
+	})
+
+	cluster := make([]int, len(sites))
+	members := make(map[int][]int, len(sites))
+	for i := range sites {
+		cluster[i] = i
+		members[i] = []int{i}
+	}
+	for _, c := range candidates {
+		ca, cb := cluster[c.a], cluster[c.b]
+		if ca == cb || sharesSource(sites, members[ca], members[cb]) {
+			continue
+		}
+		for _, m := range members[cb] {
+			cluster[m] = ca
+		}
+		members[ca] = append(members[ca], members[cb]...)
+		delete(members, cb)
+	}
+
+	var matches []Match
+	for _, idx := range members {
+		if len(idx) < 2 {
+			continue
+		}
+		slices.Sort(idx)
+		matches = append(matches, newMatch(sites, idx, cfg.Preference))
+	}
+	slices.SortFunc(matches, func(x, y Match) int {
+		return cmp.Or(cmp.Compare(x.Preferred.Source, y.Preferred.Source), cmp.Compare(x.Preferred.Id, y.Preferred.Id))
+	})
+	return matches
+}
+
+func tieBucket(distance, tieDistance float64) float64 {
+	if tieDistance <= 0 {
+		return distance
+	}
+	return math.Floor(distance / tieDistance)
+}
+
+func sharesSource(sites []Site, a, b []int) bool {
+	for _, i := range a {
+		for _, j := range b {
+			if sites[i].Source == sites[j].Source {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newMatch(sites []Site, idx []int, preference []api.Source) Match {
+	m := Match{NameSimilarity: 1}
+	for n, i := range idx {
+		m.Sites = append(m.Sites, sites[i])
+		for _, j := range idx[n+1:] {
+			m.DistanceMeters = max(m.DistanceMeters, Distance(sites[i], sites[j]))
+			m.NameSimilarity = min(m.NameSimilarity, NameSimilarity(sites[i].Name, sites[j].Name))
+		}
+	}
+	preferred := slices.MinFunc(m.Sites, func(x, y Site) int {
+		return cmp.Compare(rank(preference, x.Source), rank(preference, y.Source))
+	})
+	m.Preferred = preferred.Key
+	return m
+}
+
+func rank(preference []api.Source, source api.Source) int {
+	if i := slices.Index(preference, source); i >= 0 {
+		return i
+	}
+	return len(preference)
+}
+
+// Distance returns the great-circle distance between two sites in meters.
+func Distance(a, b Site) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+var diacritics = strings.NewReplacer(
+	"ą", "a", "ć", "c", "ę", "e", "ł", "l", "ń", "n", "ó", "o", "ś", "s", "ź", "z", "ż", "z",
+	"ä", "a", "ö", "o", "ü", "u", "ß", "ss",
+	"á", "a", "č", "c", "ď", "d", "é", "e", "ě", "e", "í", "i", "ň", "n", "ř", "r", "š", "s",
+	"ť", "t", "ú", "u", "ů", "u", "ý", "y", "ž", "z",
+)
+
+// NameSimilarity returns the Dice coefficient of the character bigrams of two
+// station names, ignoring case, diacritics and punctuation.
+func NameSimilarity(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+func bigrams(name string) []string {
+	var words []string
+	for word := range strings.FieldsFuncSeq(diacritics.Replace(strings.ToLower(name)), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		words = append(words, word)
+	}
+	normalized := []rune(strings.Join(words, " "))
+	var result []string
+	for i := 0; i+1 < len(normalized); i++ {
+		result = append(result, string(normalized[i:i+2]))
+	}
+	return result
+}
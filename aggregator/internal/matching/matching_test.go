@@ -0,0 +1,68 @@
+package matching
+
+import (
+	"aggregator/internal/api"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindMatches(t *testing.T) {
+	sites := []Site{
+		{Key: Key{api.OpenMeteo, 1}, Name: "Kraków, Aleja Krasińskiego", Latitude: 50.05767, Longitude: 19.92602},
+		{Key: Key{api.OpenMeteo, 2}, Name: "Kraków, ul. Bulwarowa", Latitude: 50.06923, Longitude: 20.05303},
+		{Key: Key{api.OpenAq, 10}, Name: "Kraków - Al. Krasińskiego", Latitude: 50.05770, Longitude: 19.92610},
+		{Key: Key{api.OpenAq, 11}, Name: "Kraków - Bulwarowa", Latitude: 50.06920, Longitude: 20.05300},
+		{Key: Key{api.OpenAq, 12}, Name: "Warszawa - Marszałkowska", Latitude: 52.22, Longitude: 21.01},
+	}
+	cfg := Config{MaxDistance: 300, TieDistance: 50, Preference: []api.Source{api.OpenAq, api.OpenMeteo}}
+
+	matches := FindMatches(sites, cfg)
+	assert.Len(t, matches, 2)
+	assert.Equal(t, Key{api.OpenAq, 10}, matches[0].Preferred)
+	assert.Equal(t, []Key{{api.OpenAq, 10}, {api.OpenMeteo, 1}}, matches[0].ByPreference(cfg.Preference))
+	assert.Equal(t, Key{api.OpenAq, 11}, matches[1].Preferred)
+	assert.Equal(t, []Key{{api.OpenAq, 11}, {api.OpenMeteo, 2}}, matches[1].ByPreference(cfg.Preference))
+	assert.Less(t, matches[0].DistanceMeters, 10.0)
+
+	cfg.Preference = []api.Source{api.OpenMeteo}
+	matches = FindMatches(sites, cfg)
+	assert.Equal(t, Key{api.OpenMeteo, 1}, matches[0].Preferred)
+
+	assert.Empty(t, FindMatches(sites, Config{}))
+}
+
+func TestFindMatchesUsesNameSimilarityAsTiebreaker(t *testing.T) {
+	sites := []Site{
+		{Key: Key{api.OpenMeteo, 1}, Name: "Gdańsk Wyzwolenia", Latitude: 54.40000, Longitude: 18.65000},
+		{Key: Key{api.OpenAq, 10}, Name: "Gdynia Pogórze", Latitude: 54.40010, Longitude: 18.65000},
+		{Key: Key{api.OpenAq, 11}, Name: "Gdańsk, ul. Wyzwolenia", Latitude: 54.40020, Longitude: 18.65000},
+	}
+	matches := FindMatches(sites, Config{MaxDistance: 300, TieDistance: 50})
+	assert.Len(t, matches, 1)
+	assert.Equal(t, []Site{sites[0], sites[2]}, matches[0].Sites)
+}
+
+func TestFindMatchesKeepsOneStationPerSource(t *testing.T) {
+	sites := []Site{
+		{Key: Key{api.OpenMeteo, 1}, Name: "A", Latitude: 50, Longitude: 20},
+		{Key: Key{api.OpenMeteo, 2}, Name: "A", Latitude: 50, Longitude: 20.0001},
+		{Key: Key{api.OpenAq, 10}, Name: "A", Latitude: 50, Longitude: 20.00005},
+	}
+	matches := FindMatches(sites, Config{MaxDistance: 300})
+	assert.Len(t, matches, 1)
+	assert.Len(t, matches[0].Sites, 2)
+}
+
+func TestNameSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, NameSimilarity("Łódź, Czernika", "lodz czernika"))
+	assert.Greater(t, NameSimilarity("Kraków, Aleja Krasińskiego", "Kraków - Al. Krasińskiego"), 0.7)
+	assert.Less(t, NameSimilarity("Kraków, Aleja Krasińskiego", "Warszawa - Marszałkowska"), 0.3)
+	assert.Equal(t, 0.0, NameSimilarity("", "Kraków"))
+}
+
+func TestDistance(t *testing.T) {
+	krakow := Site{Latitude: 50.0614, Longitude: 19.9366}
+	warsaw := Site{Latitude: 52.2297, Longitude: 21.0122}
+	assert.InDelta(t, 252000, Distance(krakow, warsaw), 2000)
+}
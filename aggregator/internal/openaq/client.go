@@ -5,9 +5,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
 )
 
-const defaultHostName = "http://localhost:3001"
+const (
+	defaultHostName = "http://localhost:3001"
+	// maxStationsPerRequest keeps the URLs of measurement requests short
+	// enough for the proxies and servers in between.
+	maxStationsPerRequest = 100
+)
 
 type Client struct {
 	hostname string
@@ -33,7 +43,37 @@ func (c *Client) GetParameters(ctx context.Context) ([]Parameter, error) {
 	return apiclient.FetchData[Parameter](ctx, c.hostname+"/parameters")
 }
 
-func (c *Client) GetMeasurementForStation(ctx context.Context, stationId int) ([]Measurement, error) {
-	url := fmt.Sprintf("%s/stations/%d/measurements", c.hostname, stationId)
-	return apiclient.FetchData[Measurement](ctx, url)
+// GetMeasurements returns the latest measurements of the given stations,
+// narrowed to the given parameters if there are any. The stations are
+// requested in chunks of maxStationsPerRequest, concurrently.
+func (c *Client) GetMeasurements(ctx context.Context, stationIds []int, parameterIds []int) ([]Measurement, error) {
+	var chunks [][]int
+	for chunk := range slices.Chunk(stationIds, maxStationsPerRequest) {
+		chunks = append(chunks, chunk)
+	}
+	results := make([][]Measurement, len(chunks))
+	g, ctx := errgroup.WithContext(ctx)
+	for i, chunk := range chunks {
+		g.Go(func() error {
+			url := fmt.Sprintf("%s/measurements?stationIds=%s", c.hostname, joinIds(chunk))
+			if len(parameterIds) > 0 {
+				url += "&parameterIds=" + joinIds(parameterIds)
+			}
+			m, err := apiclient.FetchData[Measurement](ctx, url)
+			results[i] = m
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+func joinIds(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
 }
@@ -0,0 +1,43 @@
+package openaq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMeasurementsInChunks(t *testing.T) {
+	var mu sync.Mutex
+	var chunkSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := strings.Split(r.URL.Query().Get("stationIds"), ",")
+		assert.Equal(t, "1,2", r.URL.Query().Get("parameterIds"))
+		mu.Lock()
+		chunkSizes = append(chunkSizes, len(ids))
+		mu.Unlock()
+		var measurements []Measurement
+		for _, id := range ids {
+			stationId, _ := strconv.Atoi(id)
+			measurements = append(measurements, Measurement{StationId: stationId, ParameterId: 1})
+		}
+		json.NewEncoder(w).Encode(measurements)
+	}))
+	defer server.Close()
+
+	stationIds := make([]int, 2*maxStationsPerRequest+1)
+	for i := range stationIds {
+		stationIds[i] = i + 1
+	}
+	measurements, err := NewClientWithURL(server.URL).GetMeasurements(t.Context(), stationIds, []int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, measurements, len(stationIds))
+	assert.ElementsMatch(t, []int{maxStationsPerRequest, maxStationsPerRequest, 1}, chunkSizes)
+	assert.Equal(t, 1, measurements[0].StationId)
+	assert.Equal(t, len(stationIds), measurements[len(measurements)-1].StationId)
+}
@@ -33,6 +33,12 @@ func (s Station) Latitude() float64 { return s.Lat }
 
 func (s Station) StationName() string { return s.Name }
 
+func (s Station) StationId() int { return s.Id }
+
 func (m Measurement) GetParameterId() int { return m.ParameterId }
 
 func (m Measurement) GetValue() float32 { return m.Value }
+
+func (m Measurement) GetStationId() int { return m.StationId }
+
+func (m Measurement) GetTimestamp() time.Time { return m.Timestamp }
@@ -1,5 +1,7 @@
 package openmeteo
 
+import "time"
+
 type Station struct {
 	Id     int     `json:"id"`
 	Name   string  `json:"name"`
@@ -38,6 +40,28 @@ func (s Station) Longitude() float64 {
 
 func (s Station) StationName() string { return s.Name }
 
+func (s Station) StationId() int { return s.Id }
+
 func (m Measurement) GetParameterId() int { return m.ParameterId }
 
 func (m Measurement) GetValue() float32 { return m.Value }
+
+func (m Measurement) GetStationId() int { return m.StationId }
+
+// localTimestampLayout matches timestamps without a zone, which older
+// open-meteo-data versions wrote. The service runs in UTC, so they are
+// interpreted as UTC.
+const localTimestampLayout = "2006-01-02T15:04:05.999999999"
+
+// GetTimestamp parses the RFC 3339 timestamps open-meteo-data writes, such as
+// 2024-01-15T10:00:00Z, falling back to timestamps without a zone.
+func (m Measurement) GetTimestamp() time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, m.Timestamp); err == nil {
+		return t.UTC()
+	}
+	t, err := time.Parse(localTimestampLayout, m.Timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
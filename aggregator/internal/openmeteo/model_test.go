@@ -0,0 +1,17 @@
+package openmeteo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasurementTimestamp(t *testing.T) {
+	expected := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, expected, Measurement{Timestamp: "2024-01-15T10:00:00Z"}.GetTimestamp())
+	assert.Equal(t, expected, Measurement{Timestamp: "2024-01-15T11:00:00+01:00"}.GetTimestamp())
+	assert.Equal(t, expected.Add(123*time.Millisecond), Measurement{Timestamp: "2024-01-15T10:00:00.123Z"}.GetTimestamp())
+	assert.Equal(t, expected, Measurement{Timestamp: "2024-01-15T10:00:00"}.GetTimestamp())
+	assert.True(t, Measurement{Timestamp: "yesterday"}.GetTimestamp().IsZero())
+}
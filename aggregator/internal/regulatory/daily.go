@@ -0,0 +1,227 @@
+package regulatory
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	dailyFile  = "daily.json"
+	// retentionYears is the number of calendar years, including the current
+	// one, the daily values are kept for.
+	retentionYears = 3
+)
+
+// DailyStorePath returns the path of the daily values file of a region set,
+// next to its hourly values.
+func DailyStorePath(regionSet string) string {
+	return filepath.Join(history.DataDir(regionSet), dailyFile)
+}
+
+// DayValues are the daily values of the metrics of one parameter: the mean of
+// the day, the highest 8-hour running mean ending within the day and the
+// highest hourly value of the day.
+type DayValues map[Metric]float32
+
+// Daily computes the daily values of the day starting at day, in the location
+// of day, from an hourly series that includes the 7 hours before the day. A
+// day has 23 or 25 hours when the clocks are changed. Metrics without enough
+// hourly values are left out.
+func Daily(series []history.Point, day time.Time) DayValues {
+	next := day.AddDate(0, 0, 1)
+	hours := int(next.Sub(day) / time.Hour)
+	last := next.Add(-time.Hour)
+	values := make(DayValues)
+	if v, _, ok := mean(series, last, hours); ok {
+		values[Mean24h] = v
+	}
+	if v, ok := maxRunningMean(series, last, hours, 8); ok {
+		values[Max8hMean] = v
+	}
+	found := false
+	for _, p := range series {
+		if p.Hour.Before(day) || p.Hour.After(last) {
+			continue
+		}
+		if !found || p.Value > values[Hourly] {
+			values[Hourly] = p.Value
+			found = true
+		}
+	}
+	return values
+}
+
+// DailyStore keeps the daily values per region and parameter and
+// persists them to a JSON file.
+type DailyStore struct {
+	path string
+	mu   sync.RWMutex
+	days map[api.Region]map[api.ParamType]map[string]DayValues
+}
+
+// OpenDailyStore loads the daily values saved at path. A missing file yields
+// an empty store.
+func OpenDailyStore(path string) (*DailyStore, error) {
+	s := &DailyStore{path: path, days: make(map[api.Region]map[api.ParamType]map[string]DayValues)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading daily values file: %w", err)
+	}
+	if err = json.Unmarshal(data, &s.days); err != nil {
+		return nil, fmt.Errorf("parsing daily values file: %w", err)
+	}
+	return s, nil
+}
+
+// Record stores the values of the day starting at day, dated in the location
+// of day, replacing the ones recorded for that day before.
+func (s *DailyStore) Record(region api.Region, paramType api.ParamType, day time.Time, values DayValues) {
+	if len(values) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.days[region] == nil {
+		s.days[region] = make(map[api.ParamType]map[string]DayValues)
+	}
+	if s.days[region][paramType] == nil {
+		s.days[region][paramType] = make(map[string]DayValues)
+	}
+	s.days[region][paramType][day.Format(dateLayout)] = values
+	s.prune(day.Year() - retentionYears + 1)
+}
+
+func (s *DailyStore) prune(firstYear int) {
+	first := fmt.Sprintf("%04d-01-01", firstYear)
+	for _, params := range s.days {
+		for _, days := range params {
+			for date := range days {
+				if date < first {
+					delete(days, date)
+				}
+			}
+		}
+	}
+}
+
+// Save writes the daily values to the file the store was opened from.
+func (s *DailyStore) Save() error {
+	s.mu.RLock()
+	data, err := json.Marshal(s.days)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding daily values: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing daily values file: %w", err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing daily values file: %w", err)
+	}
+	return nil
+}
+
+// Exceedances summarises the days of a year a limit value was exceeded on.
+type Exceedances struct {
+	Type        api.ParamType `json:"type"`
+	Metric      Metric        `json:"metric"`
+	Limit       float32       `json:"limit"`
+	Description string        `json:"description"`
+	AllowedDays *int          `json:"allowedDays,omitempty"`
+	// ExceedanceDays is the number of days the daily value was above the limit.
+	ExceedanceDays int `json:"exceedanceDays"`
+	// LongestStreak is the largest number of consecutive exceedance days.
+	LongestStreak int `json:"longestStreak"`
+	// RemainingDays is the number of exceedance days left before the yearly
+	// allowance is used up.
+	RemainingDays *int `json:"remainingDays,omitempty"`
+	// DaysWithData is the number of days the metric could be computed for.
+	DaysWithData int      `json:"daysWithData"`
+	Dates        []string `json:"dates"`
+}
+
+// ExceedanceReport lists the exceedance days of a region in a calendar year.
+type ExceedanceReport struct {
+	Region api.Region    `json:"voivodeship"`
+	Year   int           `json:"year"`
+	Limits []Exceedances `json:"limits"`
+}
+
+// ExceedanceReport counts the days of the year each limit value was exceeded
+// on. Limit values of single hours are left out, as their allowances count
+// hours rather than days.
+func (s *DailyStore) ExceedanceReport(region api.Region, year int, limits []Limit) ExceedanceReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report := ExceedanceReport{Region: region, Year: year, Limits: make([]Exceedances, 0, len(limits))}
+	prefix := fmt.Sprintf("%04d-", year)
+	for _, limit := range limits {
+		if limit.Metric == Hourly {
+			continue
+		}
+		e := Exceedances{
+			Type:        limit.Type,
+			Metric:      limit.Metric,
+			Limit:       limit.Value,
+			Description: limit.Description,
+			AllowedDays: limit.AllowedDays,
+			Dates:       []string{},
+		}
+		for date, values := range s.days[region][limit.Type] {
+			value, ok := values[limit.Metric]
+			if !ok || !strings.HasPrefix(date, prefix) {
+				continue
+			}
+			e.DaysWithData++
+			if value > limit.Value {
+				e.Dates = append(e.Dates, date)
+			}
+		}
+		slices.Sort(e.Dates)
+		e.ExceedanceDays = len(e.Dates)
+		e.LongestStreak = longestStreak(e.Dates)
+		if limit.AllowedDays != nil {
+			remaining := max(*limit.AllowedDays-e.ExceedanceDays, 0)
+			e.RemainingDays = &remaining
+		}
+		report.Limits = append(report.Limits, e)
+	}
+	return report
+}
+
+// longestStreak returns the largest number of consecutive days in the sorted dates.
+func longestStreak(dates []string) int {
+	longest, current := 0, 0
+	var previous time.Time
+	for _, date := range dates {
+		day, err := time.Parse(dateLayout, date)
+		if err != nil {
+			continue
+		}
+		if current > 0 && day.Sub(previous) == 24*time.Hour {
+			current++
+		} else {
+			current = 1
+		}
+		previous = day
+		longest = max(longest, current)
+	}
+	return longest
+}
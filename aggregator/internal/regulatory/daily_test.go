@@ -0,0 +1,102 @@
+package regulatory
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaily(t *testing.T) {
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	var series []history.Point
+	for i := -7; i < 24; i++ {
+		value := float32(40)
+		if i == 12 {
+			value = 100
+		}
+		series = append(series, history.Point{Hour: day.Add(time.Duration(i) * time.Hour), Value: value})
+	}
+
+	values := Daily(series, day)
+	assert.Equal(t, float32(42.5), values[Mean24h])
+	assert.Equal(t, float32(47.5), values[Max8hMean])
+	assert.Equal(t, float32(100), values[Hourly])
+
+	values = Daily(series[:12], day)
+	assert.NotContains(t, values, Mean24h)
+	assert.Contains(t, values, Hourly)
+}
+
+func TestDailyWhenClocksChange(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	// The day the clocks go back has 25 hours.
+	day := time.Date(2025, 10, 26, 0, 0, 0, 0, warsaw)
+	var series []history.Point
+	for i := -7; i < 25; i++ {
+		series = append(series, history.Point{Hour: day.Add(time.Duration(i) * time.Hour).UTC(), Value: float32(40)})
+	}
+	series[len(series)-1].Value = 90
+
+	values := Daily(series, day)
+	assert.Equal(t, float32(42), values[Mean24h])
+	assert.Equal(t, float32(90), values[Hourly])
+
+	path := filepath.Join(t.TempDir(), "daily.json")
+	s, err := OpenDailyStore(path)
+	assert.NoError(t, err)
+	s.Record(api.Malopolskie, api.PM10, day, values)
+	report := s.ExceedanceReport(api.Malopolskie, 2025, []Limit{{Type: api.PM10, Metric: Mean24h, Value: 41}})
+	assert.Equal(t, []string{"2025-10-26"}, report.Limits[0].Dates)
+}
+
+func TestDailyStoreExceedanceReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "daily.json")
+	s, err := OpenDailyStore(path)
+	assert.NoError(t, err)
+
+	record := func(date string, value float32) {
+		day, err := time.Parse(dateLayout, date)
+		assert.NoError(t, err)
+		s.Record(api.Malopolskie, api.PM10, day, DayValues{Mean24h: value})
+	}
+	record("2024-12-31", 80)
+	record("2025-01-01", 60)
+	record("2025-01-02", 70)
+	record("2025-01-03", 55)
+	record("2025-01-04", 45)
+	record("2025-01-06", 51)
+	assert.NoError(t, s.Save())
+
+	reopened, err := OpenDailyStore(path)
+	assert.NoError(t, err)
+
+	allowed := 35
+	limits := []Limit{
+		{Type: api.PM10, Metric: Mean24h, Value: 50, AllowedDays: &allowed},
+		{Type: api.NO2, Metric: Hourly, Value: 200},
+	}
+	report := reopened.ExceedanceReport(api.Malopolskie, 2025, limits)
+	assert.Equal(t, 2025, report.Year)
+	// The hourly limit value is left out.
+	assert.Len(t, report.Limits, 1)
+
+	pm10 := report.Limits[0]
+	assert.Equal(t, 4, pm10.ExceedanceDays)
+	assert.Equal(t, 3, pm10.LongestStreak)
+	assert.Equal(t, 31, *pm10.RemainingDays)
+	assert.Equal(t, 5, pm10.DaysWithData)
+	assert.Equal(t, []string{"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-06"}, pm10.Dates)
+}
+
+func TestDailyStorePrunesOldYears(t *testing.T) {
+	s, err := OpenDailyStore(filepath.Join(t.TempDir(), "daily.json"))
+	assert.NoError(t, err)
+	s.Record(api.Slaskie, api.PM10, time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC), DayValues{Mean24h: 80})
+	s.Record(api.Slaskie, api.PM10, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), DayValues{Mean24h: 80})
+	assert.Zero(t, s.ExceedanceReport(api.Slaskie, 2022, []Limit{{Type: api.PM10, Metric: Mean24h, Value: 50}}).Limits[0].DaysWithData)
+}
@@ -0,0 +1,164 @@
+// Package regulatory computes the rolling metrics air quality standards are
+// defined on and compares them with the limit values.
+package regulatory
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Metric string
+
+const (
+	// Hourly is the value of a single hour.
+	Hourly Metric = "1h"
+	// Mean24h is the mean of the 24 hours ending with the evaluated hour.
+	Mean24h Metric = "24h-mean"
+	// Max8hMean is the highest running 8-hour mean ending within the 24 hours
+	// up to the evaluated hour.
+	Max8hMean Metric = "max-8h-mean"
+)
+
+// minCoverage is the share of hourly values a mean needs to be valid.
+const minCoverage = 0.75
+
+// Limit is a limit value of a metric of one parameter.
+type Limit struct {
+	Type        api.ParamType `json:"type"`
+	Metric      Metric        `json:"metric"`
+	Value       float32       `json:"limit"`
+	Description string        `json:"description"`
+	// AllowedDays is the number of days per calendar year the limit value
+	// may be exceeded, if the standard defines one.
+	AllowedDays *int `json:"allowedDays,omitempty"`
+}
+
+// Result is a metric evaluated against its limit value. Value is nil when
+// there are not enough hourly values to compute the metric.
+type Result struct {
+	Type        api.ParamType `json:"type"`
+	Metric      Metric        `json:"metric"`
+	Value       *float32      `json:"value"`
+	Limit       float32       `json:"limit"`
+	Unit        string        `json:"unit"`
+	Exceeded    bool          `json:"exceeded"`
+	Description string        `json:"description"`
+	// Samples is the number of hourly values the metric is based on.
+	Samples int `json:"samples"`
+}
+
+// Report lists the metrics of a region for the hour starting at Hour.
+type Report struct {
+	Region  api.Region `json:"voivodeship"`
+	Hour    time.Time  `json:"hour"`
+	Results []Result   `json:"results"`
+}
+
+func LoadLimits(path string) ([]Limit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading limits file: %w", err)
+	}
+	var limits []Limit
+	if err = json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("parsing limits file: %w", err)
+	}
+	for i, l := range limits {
+		if l.Type == "" {
+			return nil, fmt.Errorf("limit %d has no type", i+1)
+		}
+		if Window(l.Metric) == 0 {
+			return nil, fmt.Errorf("limit %d has unknown metric: %s", i+1, l.Metric)
+		}
+	}
+	return limits, nil
+}
+
+// Window returns how many hours up to the evaluated one the metric needs, or
+// zero for an unknown metric.
+func Window(metric Metric) time.Duration {
+	switch metric {
+	case Hourly:
+		return time.Hour
+	case Mean24h:
+		return 24 * time.Hour
+	case Max8hMean:
+		return (24 + 7) * time.Hour
+	default:
+		return 0
+	}
+}
+
+// Evaluate computes the metric of the limit for the hour starting at hour
+// from the hourly series of its parameter.
+func Evaluate(limit Limit, series []history.Point, hour time.Time) Result {
+	result := Result{
+		Type:        limit.Type,
+		Metric:      limit.Metric,
+		Limit:       limit.Value,
+		Description: limit.Description,
+	}
+	var value float32
+	var ok bool
+	switch limit.Metric {
+	case Hourly:
+		value, result.Samples, ok = mean(series, hour, 1)
+	case Mean24h:
+		value, result.Samples, ok = mean(series, hour, 24)
+	case Max8hMean:
+		value, result.Samples, ok = max8hMean(series, hour)
+	}
+	if ok {
+		result.Value = &value
+		result.Exceeded = value > limit.Value
+	}
+	return result
+}
+
+// mean averages the values of the given number of hours ending with hour. It
+// fails when less than the minimal coverage of those hours has a value.
+func mean(series []history.Point, hour time.Time, hours int) (float32, int, bool) {
+	from := hour.Add(-time.Duration(hours-1) * time.Hour)
+	var sum float32
+	samples := 0
+	for _, p := range series {
+		if !p.Hour.Before(from) && !p.Hour.After(hour) {
+			sum += p.Value
+			samples++
+		}
+	}
+	if samples == 0 || float64(samples) < minCoverage*float64(hours) {
+		return 0, samples, false
+	}
+	return sum / float32(samples), samples, true
+}
+
+func max8hMean(series []history.Point, hour time.Time) (float32, int, bool) {
+	result, found := maxRunningMean(series, hour, 24, 8)
+	samples := 0
+	from := hour.Add(-Window(Max8hMean) + time.Hour)
+	for _, p := range series {
+		if !p.Hour.Before(from) && !p.Hour.After(hour) {
+			samples++
+		}
+	}
+	return result, samples, found
+}
+
+// maxRunningMean returns the highest of the running means over the given
+// window of hours that end within the count hours ending with hour.
+func maxRunningMean(series []history.Point, hour time.Time, count, window int) (float32, bool) {
+	var result float32
+	found := false
+	for i := range count {
+		value, _, ok := mean(series, hour.Add(-time.Duration(i)*time.Hour), window)
+		if ok && (!found || value > result) {
+			result, found = value, true
+		}
+	}
+	return result, found
+}
@@ -0,0 +1,84 @@
+package regulatory
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/history"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hour = time.Date(2025, 10, 1, 23, 0, 0, 0, time.UTC)
+
+func hourlySeries(values ...float32) []history.Point {
+	points := make([]history.Point, len(values))
+	for i, v := range values {
+		points[i] = history.Point{Hour: hour.Add(-time.Duration(len(values)-1-i) * time.Hour), Value: v}
+	}
+	return points
+}
+
+func TestEvaluateMean24h(t *testing.T) {
+	limit := Limit{Type: api.PM10, Metric: Mean24h, Value: 50}
+	values := make([]float32, 30)
+	for i := range values {
+		values[i] = 40
+	}
+	values[len(values)-1] = 280
+
+	result := Evaluate(limit, hourlySeries(values...), hour)
+	assert.Equal(t, float32(50), *result.Value)
+	assert.Equal(t, 24, result.Samples)
+	assert.False(t, result.Exceeded)
+
+	values[len(values)-2] = 41
+	result = Evaluate(limit, hourlySeries(values...), hour)
+	assert.True(t, result.Exceeded)
+
+	result = Evaluate(limit, hourlySeries(values[:17]...), hour)
+	assert.Nil(t, result.Value)
+	assert.Equal(t, 17, result.Samples)
+}
+
+func TestEvaluateMax8hMean(t *testing.T) {
+	limit := Limit{Type: api.O3, Metric: Max8hMean, Value: 120}
+	values := make([]float32, 31)
+	for i := range values {
+		values[i] = 60
+	}
+	for i := 10; i < 18; i++ {
+		values[i] = 140
+	}
+
+	result := Evaluate(limit, hourlySeries(values...), hour)
+	assert.Equal(t, float32(140), *result.Value)
+	assert.True(t, result.Exceeded)
+	assert.Equal(t, 31, result.Samples)
+
+	result = Evaluate(limit, hourlySeries(values[:5]...), hour)
+	assert.Nil(t, result.Value)
+}
+
+func TestEvaluateHourly(t *testing.T) {
+	limit := Limit{Type: api.NO2, Metric: Hourly, Value: 200}
+	result := Evaluate(limit, hourlySeries(250, 180), hour)
+	assert.Equal(t, float32(180), *result.Value)
+	assert.False(t, result.Exceeded)
+
+	result = Evaluate(limit, hourlySeries(250, 180), hour.Add(time.Hour))
+	assert.Nil(t, result.Value)
+}
+
+func TestLoadLimits(t *testing.T) {
+	limits, err := LoadLimits("../../config/limits.json")
+	assert.NoError(t, err)
+	assert.Len(t, limits, 6)
+	assert.Equal(t, api.PM10, limits[0].Type)
+	assert.Equal(t, Mean24h, limits[0].Metric)
+	assert.Equal(t, 35, *limits[0].AllowedDays)
+	assert.Nil(t, limits[3].AllowedDays)
+
+	_, err = LoadLimits("missing.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/aqi"
+	"aggregator/internal/locale"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type catalogKey struct{}
+
+// localeMiddleware picks the language of a request from the lang query
+// parameter or else the Accept-Language header, and returns it in the
+// Content-Language header.
+func localeMiddleware(catalogs *locale.Catalogs, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		var catalog *locale.Catalog
+		if lang := r.URL.Query().Get("lang"); lang != "" {
+			c, ok := catalogs.Catalog(lang)
+			if !ok {
+				writeBadRequest(w, r, fmt.Sprintf("Unsupported language: %s, supported are %s", lang, strings.Join(catalogs.Languages(), ", ")))
+				return
+			}
+			catalog = c
+		} else {
+			catalog = catalogs.Negotiate(r.Header.Get("Accept-Language"))
+		}
+		w.Header().Set("Content-Language", catalog.Language)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), catalogKey{}, catalog)))
+	})
+}
+
+// requestCatalog returns the catalog of the language of the request, or nil
+// when the request went through no locale middleware.
+func requestCatalog(ctx context.Context) *locale.Catalog {
+	c, _ := ctx.Value(catalogKey{}).(*locale.Catalog)
+	return c
+}
+
+// localizeData adds the display names of the regions and parameters and
+// translates the parameter descriptions.
+func localizeData(c *locale.Catalog, results ...*api.AggregatedData) {
+	if c == nil {
+		return
+	}
+	for _, result := range results {
+		result.RegionName = c.RegionName(result.Region)
+		for i := range result.Parameters {
+			p := &result.Parameters[i]
+			p.Name = c.ParamName(p.Type)
+			p.Description = c.ParamDescription(p.Type, p.Description)
+		}
+	}
+}
+
+func localizeRanking(c *locale.Catalog, ranking *aggregator.Ranking) {
+	if c == nil {
+		return
+	}
+	for i := range ranking.Regions {
+		r := &ranking.Regions[i]
+		r.Name = c.RegionName(r.Region)
+		localizeAqi(c, r.Aqi)
+	}
+}
+
+func localizeComparison(c *locale.Catalog, comparison *aggregator.Comparison) {
+	if c == nil {
+		return
+	}
+	for i := range comparison.Regions {
+		r := &comparison.Regions[i]
+		r.Name = c.RegionName(r.Region)
+		localizeAqi(c, r.Aqi)
+	}
+}
+
+func localizeAqi(c *locale.Catalog, result *aqi.Result) {
+	if result != nil {
+		result.Label = c.AqiLabel(result.Name)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/locale"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleMiddleware(t *testing.T) {
+	catalogs, err := locale.LoadCatalogs("config/locales")
+	assert.NoError(t, err)
+	var data api.AggregatedData
+	handler := localeMiddleware(catalogs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data = api.AggregatedData{Region: api.Slaskie, Parameters: []api.Parameter{{Type: api.PM2_5, Description: "upstream"}}}
+		localizeData(requestCatalog(r.Context()), &data)
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/aggregatedData", nil)
+	r.Header.Set("Accept-Language", "pl-PL,en;q=0.5")
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "pl", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	assert.Equal(t, "Śląskie", data.RegionName)
+	assert.Equal(t, "PM2,5", data.Parameters[0].Name)
+	assert.Contains(t, data.Parameters[0].Description, "Drobny pył")
+
+	r = httptest.NewRequest(http.MethodGet, "/aggregatedData?lang=en", nil)
+	r.Header.Set("Accept-Language", "pl")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Silesia", data.RegionName)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/aggregatedData?lang=fr", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unsupported language: fr, supported are en, pl")
+}
@@ -1,31 +1,197 @@
 package main
 
 import (
+	"aggregator/internal/access"
 	"aggregator/internal/aggregator"
 	"aggregator/internal/api"
+	"aggregator/internal/apiclient"
+	"aggregator/internal/gql"
+	"aggregator/internal/locale"
+	"aggregator/internal/telemetry"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	// The runtime image has no time zone database for the region sets.
+	_ "time/tzdata"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-	ctx := context.Background()
-	service := aggregator.NewService(ctx)
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := logLevelFromEnv(); err != nil {
+		slog.Error("Setting log level failed", "error", err)
+		os.Exit(1)
+	}
+	shutdownTracing, err := telemetry.Setup(ctx, "aggregator")
+	if err != nil {
+		slog.Error("Setting up tracing failed", "error", err)
+		os.Exit(1)
+	}
+	catalogs, err := locale.LoadCatalogs(locale.Dir())
+	if err != nil {
+		slog.Error("Loading locale catalogs failed", "error", err)
+		os.Exit(1)
+	}
+	accessConfig, err := access.LoadConfig(access.ConfigPath())
+	if err != nil {
+		slog.Error("Loading access config failed", "error", err)
+		os.Exit(1)
+	}
+	// The upstream mode applies to the clients of all region sets.
+	if err := apiclient.ConfigureFromEnv(); err != nil {
+		slog.Error("Configuring upstream mode failed", "error", err)
+		os.Exit(1)
+	}
+	sets, err := api.LoadRegionSets(regionSetsPath)
+	if err != nil {
+		slog.Error("Loading region sets failed", "error", err)
+		os.Exit(1)
+	}
+	services, err := newRegionServices(ctx, sets)
+	if err != nil {
+		slog.Error("Starting region set services failed", "error", err)
+		os.Exit(1)
+	}
+	service := services.defaultService()
+	graphqlLimits, err := gql.LimitsFromEnv()
+	if err != nil {
+		slog.Error("Loading GraphQL limits failed", "error", err)
+		os.Exit(1)
+	}
+	executor, err := gql.NewExecutor(graphqlLimits)
+	if err != nil {
+		slog.Error("Building GraphQL schema failed", "error", err)
+		os.Exit(1)
+	}
+	updateInterval, err := updateIntervalFromEnv()
+	if err != nil {
+		slog.Error("Loading gRPC update interval failed", "error", err)
+		os.Exit(1)
+	}
+	go watchRegionSets(ctx, services, regionSetsPath)
 
-	http.HandleFunc("/aggregatedData", getAllAggregatedData(service))
-	http.HandleFunc("/aggregatedData/{voivodeship}", getAggregatedData(service))
-	if err := http.ListenAndServe(":8082", corsMiddleware(http.DefaultServeMux)); err != nil {
+	handle("/regionSets", getRegionSets(services))
+	handle("/regionSets/{set}/aggregatedData", inRegionSet(services, getAllAggregatedData))
+	handle("/regionSets/{set}/aggregatedData/{region}", inRegionSet(services, getAggregatedData))
+	handle("/regionSets/{set}/regions/{region}/stations", inRegionSet(services, getStations))
+	handle("/regionSets/{set}/regions/{region}/regulatory", inRegionSet(services, getRegulatoryReport))
+	handle("/regionSets/{set}/reports/exceedances", inRegionSet(services, getExceedanceReports))
+	handle("/regionSets/{set}/ranking", inRegionSet(services, getRanking))
+	handle("/regionSets/{set}/comparison", inRegionSet(services, getComparison))
+	handle("/regionSets/{set}/validation", inRegionSet(services, getRegionValidation))
+	handle("/regionSets/{set}/graphql", inRegionSet(services, serveGraphQL(executor)))
+	// Routes of the default region set, kept for existing clients.
+	handle("/aggregatedData", getAllAggregatedData(service))
+	handle("/aggregatedData/{region}", getAggregatedData(service))
+	handle("/voivodeships/{region}/stations", getStations(service))
+	handle("/voivodeships/{region}/regulatory", getRegulatoryReport(service))
+	handle("/reports/exceedances", getExceedanceReports(service))
+	handle("/ranking", getRanking(service))
+	handle("/comparison", getComparison(service))
+	handle("/graphql", serveGraphQL(executor)(service))
+	handle("/stations/{source}/{id}", getStation(service))
+	handle("/stations/matches", getStationMatches(service))
+	// Admin API, only for admin API keys.
+	handle("GET /admin/status", requireAdmin(accessConfig, getAdminStatus(services)))
+	handle("POST /admin/regionSets/{set}/refresh", requireAdmin(accessConfig, inRegionSet(services, postRefresh)))
+	handle("DELETE /admin/caches", requireAdmin(accessConfig, deleteCaches(services)))
+	handle("POST /admin/regionSets/{set}/history/rebuild", requireAdmin(accessConfig, inRegionSet(services, postRebuildHistory)))
+	handle("GET /admin/regionSets/{set}/assignments", requireAdmin(accessConfig, inRegionSet(services, getAssignments)))
+	handle("GET /admin/logLevel", requireAdmin(accessConfig, getLogLevel))
+	handle("PUT /admin/logLevel", requireAdmin(accessConfig, putLogLevel))
+	handle("/", notFound(http.DefaultServeMux))
+
+	proxies, err := trustedProxiesFromEnv()
+	if err != nil {
+		slog.Error("Loading trusted proxies failed", "error", err)
+		os.Exit(1)
+	}
+	// The HTTP and gRPC APIs share the request quotas.
+	limiter := access.NewLimiter()
+	handler := accessMiddleware(accessConfig, limiter, proxies, localeMiddleware(catalogs, http.DefaultServeMux))
+	server := &http.Server{Addr: ":8082", Handler: requestIdMiddleware(corsMiddleware(corsOriginsFromEnv(), handler))}
+	grpcServer, healthServer := newGRPCServer(ctx, newAggregatorServer(services, catalogs, updateInterval), accessConfig, limiter)
+	listener, err := net.Listen("tcp", grpcAddrFromEnv())
+	if err != nil {
+		slog.Error("Listening for gRPC failed", "error", err)
+		os.Exit(1)
+	}
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("gRPC server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		stopGRPC(grpcServer, healthServer, 5*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Server shutdown failed", "error", err)
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("Server failed", "error", err)
 		os.Exit(1)
 	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Flushing traces failed", "error", err)
+	}
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
+// handle registers h for the pattern with a server span named after the
+// pattern and tagged with the request ID.
+func handle(pattern string, h http.HandlerFunc) {
+	http.Handle(pattern, otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", requestId(r.Context())))
+		h(w, r)
+	}), pattern))
+}
+
+// corsMiddleware allows the configured origins to call the API and answers
+// preflight requests, which are not rate limited.
+func corsMiddleware(origins corsOrigins, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Add("Vary", "Origin")
+		if origin := origins.allowOrigin(r.Header.Get("Origin")); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers",
+				strings.Join([]string{requestIdHeader, "ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}, ", "))
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{apiKeyHeader, "Authorization", "Content-Type", requestIdHeader, "If-None-Match"}, ", "))
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -36,15 +202,30 @@ func getAllAggregatedData(service *aggregator.Service) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
 		defer cancel()
 
-		results, err := service.AggregateAll(ctx)
+		sel, err := parseSelection(r.URL.Query(), service.RegionSet(), service.Params())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		results, err := service.AggregateAll(ctx, sel.regions, sel.options)
 		if err != nil {
-			slog.Error("Aggregating all data failed", "error", err)
-			http.Error(w, "Aggregating data failed", http.StatusInternalServerError)
+			writeError(w, r, err, "Aggregating data failed")
 			return
 		}
-		if err = json.NewEncoder(w).Encode(results); err != nil {
-			slog.Error("Encoding json response failed", "error", err)
-			http.Error(w, "Encoding json response failed", http.StatusInternalServerError)
+		var lastModified time.Time
+		for i, result := range results {
+			if t := result.LastModified(); t.After(lastModified) {
+				lastModified = t
+			}
+			localizeData(requestCatalog(r.Context()), &results[i])
+		}
+		response, err := sel.project(results)
+		if err != nil {
+			writeError(w, r, err, "Selecting response fields failed")
+			return
+		}
+		if err = writeCachedJSON(w, r, response, lastModified); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
 			return
 		}
 		slog.Info("Request to get all aggregated data finished successfully")
@@ -57,23 +238,133 @@ func getAggregatedData(service *aggregator.Service) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 		defer cancel()
 
-		voivodeshipStr := r.PathValue("voivodeship")
-		voivodeship, err := api.MapVoivodeship(voivodeshipStr)
+		region, err := service.RegionSet().Region(r.PathValue("region"))
+		if err != nil {
+			writeBadRequest(w, r, "Unknown region: "+r.PathValue("region"))
+			return
+		}
+		sel, err := parseSelection(r.URL.Query(), service.RegionSet(), service.Params())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		results, err := service.AggregateForRegion(ctx, region, sel.options)
 		if err != nil {
-			http.Error(w, "Unknown voivodeship: "+voivodeshipStr, http.StatusBadRequest)
+			writeError(w, r, err, "Aggregating data for region failed")
 			return
 		}
-		results, err := service.AggregateForVoivodeship(ctx, voivodeship)
+		localizeData(requestCatalog(r.Context()), &results)
+		response, err := sel.project(results)
 		if err != nil {
-			slog.Error("Aggregating data failed", "voivodeship", voivodeshipStr, "error", err)
-			http.Error(w, "Aggregating data for voivodeship failed", http.StatusInternalServerError)
+			writeError(w, r, err, "Selecting response fields failed")
 			return
 		}
-		if err = json.NewEncoder(w).Encode(results); err != nil {
-			slog.Error("Encoding json response failed", "error", err)
-			http.Error(w, "Encoding json response failed", http.StatusInternalServerError)
+		if err = writeCachedJSON(w, r, response, results.LastModified()); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
 			return
 		}
 		slog.Info("Request to get aggregated data finished successfully")
 	}
 }
+
+func getStations(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("Request to get stations started")
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		region, err := service.RegionSet().Region(r.PathValue("region"))
+		if err != nil {
+			writeBadRequest(w, r, "Unknown region: "+r.PathValue("region"))
+			return
+		}
+		stations, err := service.StationsForRegion(ctx, region)
+		if err != nil {
+			writeError(w, r, err, "Listing stations failed")
+			return
+		}
+		var lastModified time.Time
+		for _, station := range stations {
+			if station.LastMeasurement != nil && station.LastMeasurement.After(lastModified) {
+				lastModified = *station.LastMeasurement
+			}
+		}
+		if err = writeCachedJSON(w, r, stations, lastModified); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+		slog.Info("Request to get stations finished successfully")
+	}
+}
+
+func getRegulatoryReport(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		region, err := service.RegionSet().Region(r.PathValue("region"))
+		if err != nil {
+			writeBadRequest(w, r, "Unknown region: "+r.PathValue("region"))
+			return
+		}
+		hour, err := parseHour(r.URL.Query().Get("hour"))
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		report, err := service.RegulatoryReport(region, hour)
+		if err != nil {
+			writeError(w, r, err, "Evaluating limit values failed")
+			return
+		}
+		if err = writeCachedJSON(w, r, report, report.Hour); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
+
+func getStation(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("Request to get station started")
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		source, err := api.MapSource(r.PathValue("source"))
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeBadRequest(w, r, "Invalid station id: "+r.PathValue("id"))
+			return
+		}
+		station, err := service.Station(ctx, source, id)
+		if err != nil {
+			writeError(w, r, err, "Getting station failed")
+			return
+		}
+		var lastModified time.Time
+		if station.LastMeasurement != nil {
+			lastModified = *station.LastMeasurement
+		}
+		if err = writeCachedJSON(w, r, station, lastModified); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+		slog.Info("Request to get station finished successfully")
+	}
+}
+
+func getStationMatches(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matches, err := service.StationMatches()
+		if err != nil {
+			writeError(w, r, err, "Getting station matches failed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(matches); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
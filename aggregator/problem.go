@@ -0,0 +1,169 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/apiclient"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIdHeader = "X-Request-Id"
+
+// notReadyRetryAfter is the number of seconds clients are asked to wait
+// while the service loads station data.
+const notReadyRetryAfter = "30"
+
+// routeMethods are the methods tried when looking for the routes of a path.
+var routeMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Error codes reported in problem responses.
+const (
+	codeInvalidRequest     = "invalid_request"
+	codeNotFound           = "not_found"
+	codeInvalidApiKey      = "invalid_api_key"
+	codeForbidden          = "forbidden"
+	codeRateLimited        = "rate_limited"
+	codeStationNotFound    = "station_not_found"
+	codeServiceUnavailable = "service_unavailable"
+	codeUpstreamError      = "upstream_error"
+	codeUpstreamTimeout    = "upstream_timeout"
+	codeInternalError      = "internal_error"
+)
+
+// problem is an RFC 7807 problem details object extended with an error code,
+// the request ID and, if any, the response of the failing upstream service.
+type problem struct {
+	Type      string           `json:"type"`
+	Title     string           `json:"title"`
+	Status    int              `json:"status"`
+	Detail    string           `json:"detail"`
+	Instance  string           `json:"instance"`
+	Code      string           `json:"code"`
+	RequestId string           `json:"requestId"`
+	Upstream  *upstreamProblem `json:"upstream,omitempty"`
+}
+
+// upstreamProblem describes an upstream error response. Upstream URLs are
+// internal and never shown; the message is only shown for client errors, as
+// server error messages may leak upstream internals.
+type upstreamProblem struct {
+	Status  int    `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = r.URL.Path
+	p.RequestId = requestId(r.Context())
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Del("Cache-Control")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		slog.Error("Encoding problem response failed", "requestId", p.RequestId, "error", err)
+	}
+}
+
+// writeBadRequest reports an invalid request; detail is shown to the client.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: detail})
+}
+
+// writeError reports a failed request. The status and code follow from err;
+// detail is shown to the client for errors that have no safe message of their own.
+func writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	p := problem{Status: http.StatusInternalServerError, Code: codeInternalError, Detail: detail}
+	var upstreamErr *apiclient.UpstreamError
+	switch {
+	case errors.Is(err, aggregator.ErrStationNotFound):
+		p.Status, p.Code, p.Detail = http.StatusNotFound, codeStationNotFound, err.Error()
+	case errors.Is(err, aggregator.ErrNotReady):
+		p.Status, p.Code = http.StatusServiceUnavailable, codeServiceUnavailable
+		p.Detail = "The service is loading station data or failed to load it, retry later"
+		w.Header().Set("Retry-After", notReadyRetryAfter)
+	case errors.Is(err, context.DeadlineExceeded):
+		p.Status, p.Code = http.StatusGatewayTimeout, codeUpstreamTimeout
+	case errors.As(err, &upstreamErr):
+		p.Status, p.Code = http.StatusBadGateway, codeUpstreamError
+		p.Upstream = &upstreamProblem{Status: upstreamErr.StatusCode}
+		if upstreamErr.StatusCode < http.StatusInternalServerError {
+			p.Upstream.Message = upstreamErr.Message
+		}
+	}
+	if p.Status >= http.StatusInternalServerError {
+		span := trace.SpanFromContext(r.Context())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, detail)
+		slog.Error("Request failed", "requestId", requestId(r.Context()), "path", r.URL.Path, "status", p.Status, "error", err)
+	}
+	writeProblem(w, r, p)
+}
+
+// notFound answers the requests no other route of the mux matches. Requests
+// whose path has routes for other methods only are answered with 405, which
+// the mux would otherwise answer in plain text.
+func notFound(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(mux, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeProblem(w, r, problem{Status: http.StatusMethodNotAllowed, Code: codeInvalidRequest, Detail: r.Method + " is not allowed for " + r.URL.Path})
+			return
+		}
+		writeProblem(w, r, problem{Status: http.StatusNotFound, Code: codeNotFound, Detail: "No resource at " + r.URL.Path})
+	}
+}
+
+// allowedMethods returns the methods other than the one of the request that
+// the mux has a route for the path of the request with.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		if method == r.Method {
+			continue
+		}
+		other := r.Clone(r.Context())
+		other.Method = method
+		if _, pattern := mux.Handler(other); pattern != "" && pattern != "/" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+type requestIdKey struct{}
+
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIdMiddleware takes the request ID from the X-Request-Id header, or
+// generates one, and returns it in the response header.
+func requestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIdHeader)
+		if !validRequestId.MatchString(id) {
+			id = newRequestId()
+		}
+		w.Header().Set(requestIdHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIdKey{}, id)))
+	})
+}
+
+func newRequestId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func requestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
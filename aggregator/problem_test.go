@@ -0,0 +1,102 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/apiclient"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveProblem(t *testing.T, handler http.HandlerFunc, header http.Header) (*httptest.ResponseRecorder, problem) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/aggregatedData/malopolskie", nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	requestIdMiddleware(handler).ServeHTTP(w, r)
+	var p problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return w, p
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err      error
+		status   int
+		code     string
+		upstream *upstreamProblem
+	}{
+		{fmt.Errorf("aggregating: %w", aggregator.ErrNotReady), http.StatusServiceUnavailable, codeServiceUnavailable, nil},
+		{fmt.Errorf("station openaq/3: %w", aggregator.ErrStationNotFound), http.StatusNotFound, codeStationNotFound, nil},
+		{fmt.Errorf("fetching: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, codeUpstreamTimeout, nil},
+		{&apiclient.UpstreamError{URL: "http://internal:3000/x", StatusCode: 404, Message: "Station not found"}, http.StatusBadGateway, codeUpstreamError, &upstreamProblem{Status: 404, Message: "Station not found"}},
+		{&apiclient.UpstreamError{URL: "http://internal:3000/x", StatusCode: 500, Message: "NullPointerException"}, http.StatusBadGateway, codeUpstreamError, &upstreamProblem{Status: 500}},
+		{errors.New("boom"), http.StatusInternalServerError, codeInternalError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			w, p := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err, "Aggregating data failed")
+			}, nil)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, http.StatusText(tt.status), p.Title)
+			assert.Equal(t, tt.code, p.Code)
+			assert.Equal(t, "/aggregatedData/malopolskie", p.Instance)
+			assert.Equal(t, tt.upstream, p.Upstream)
+			assert.NotContains(t, w.Body.String(), "internal:3000")
+			if tt.status == http.StatusServiceUnavailable {
+				assert.Equal(t, notReadyRetryAfter, w.Header().Get("Retry-After"))
+			} else {
+				assert.Empty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	w, p := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		writeBadRequest(w, r, "unknown voivodeship: bavaria")
+	}, http.Header{requestIdHeader: {"abc-123"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "abc-123", w.Header().Get(requestIdHeader))
+	assert.Equal(t, "abc-123", p.RequestId)
+	assert.Equal(t, "unknown voivodeship: bavaria", p.Detail)
+
+	w, p = serveProblem(t, notFound(http.NewServeMux()), http.Header{requestIdHeader: {"not valid!"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, p.RequestId, 16)
+	assert.Equal(t, p.RequestId, w.Header().Get(requestIdHeader))
+}
+
+func TestNotFoundWithOtherMethods(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("PUT /admin/logLevel", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /admin/logLevel", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/", notFound(mux))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/logLevel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, PUT", w.Header().Get("Allow"))
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var p problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, codeInvalidRequest, p.Code)
+	assert.Equal(t, "DELETE is not allowed for /admin/logLevel", p.Detail)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Allow"))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func getRanking(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		sel, err := parseSelection(r.URL.Query(), service.RegionSet(), service.Params())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		opts, err := parseRankingOptions(r.URL.Query(), sel, service.Params())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		ranking, err := service.Rank(ctx, sel.regions, opts)
+		if err != nil {
+			writeError(w, r, err, "Ranking regions failed")
+			return
+		}
+		localizeRanking(requestCatalog(r.Context()), &ranking)
+		if err = writeCachedJSON(w, r, ranking, time.Time{}); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
+
+// parseRankingOptions reads what to rank by from the by parameter, a
+// parameter type or "aqi", and the order from the order parameter, "asc" for
+// the cleanest regions first or "desc" for the most polluted ones first.
+func parseRankingOptions(query url.Values, sel selection, registry *api.Registry) (aggregator.RankingOptions, error) {
+	opts := aggregator.RankingOptions{Options: sel.options}
+	if by := query.Get("by"); by != "" && !strings.EqualFold(by, aggregator.RankByAqi) {
+		paramType, err := registry.ParamType(by)
+		if err != nil {
+			return aggregator.RankingOptions{}, err
+		}
+		opts.Param = paramType
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return aggregator.RankingOptions{}, fmt.Errorf("invalid order: %s", order)
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return aggregator.RankingOptions{}, fmt.Errorf("invalid limit: %s", value)
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
+
+func getComparison(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		sel, err := parseSelection(r.URL.Query(), service.RegionSet(), service.Params())
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		// The first region is the baseline, so duplicates are dropped
+		// without changing the order.
+		var regions []api.Region
+		for _, region := range sel.regions {
+			if !slices.Contains(regions, region) {
+				regions = append(regions, region)
+			}
+		}
+		if len(regions) < 2 {
+			writeBadRequest(w, r, "Comparing needs at least two different regions")
+			return
+		}
+		comparison, err := service.Compare(ctx, regions, sel.options)
+		if err != nil {
+			writeError(w, r, err, "Comparing regions failed")
+			return
+		}
+		localizeComparison(requestCatalog(r.Context()), &comparison)
+		if err = writeCachedJSON(w, r, comparison, time.Time{}); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
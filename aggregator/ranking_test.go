@@ -0,0 +1,36 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRankingOptions(t *testing.T) {
+	registry, err := api.LoadRegistry("config/parameters.json")
+	assert.NoError(t, err)
+
+	opts, err := parseRankingOptions(url.Values{}, selection{}, registry)
+	assert.NoError(t, err)
+	assert.Empty(t, opts.Param)
+	assert.False(t, opts.Descending)
+
+	opts, err = parseRankingOptions(url.Values{"by": {"pm2_5"}, "order": {"desc"}, "limit": {"3"}}, selection{}, registry)
+	assert.NoError(t, err)
+	assert.Equal(t, api.PM2_5, opts.Param)
+	assert.True(t, opts.Descending)
+	assert.Equal(t, 3, opts.Limit)
+
+	opts, err = parseRankingOptions(url.Values{"by": {"AQI"}}, selection{}, registry)
+	assert.NoError(t, err)
+	assert.Empty(t, opts.Param)
+
+	_, err = parseRankingOptions(url.Values{"by": {"PM3"}}, selection{}, registry)
+	assert.ErrorContains(t, err, "unknown parameter")
+	_, err = parseRankingOptions(url.Values{"order": {"worst"}}, selection{}, registry)
+	assert.ErrorContains(t, err, "invalid order")
+	_, err = parseRankingOptions(url.Values{"limit": {"0"}}, selection{}, registry)
+	assert.ErrorContains(t, err, "invalid limit")
+}
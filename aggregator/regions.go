@@ -0,0 +1,174 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/coverage"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const regionSetsPath = "config/regions.json"
+
+// regionServices holds the aggregator service of every region set. Routes
+// outside the /regionSets namespace are served by the default set.
+type regionServices struct {
+	mu         sync.RWMutex
+	sets       []api.RegionSet
+	services   map[string]*aggregator.Service
+	sources    *aggregator.Sources
+	defaultSet string
+	// failOn is the severity of boundary findings that keeps region sets
+	// from being loaded.
+	failOn coverage.Severity
+}
+
+// newRegionServices starts a service for every region set, all fetching from
+// the same sources, and one collector for their history. The default set
+// is set by DEFAULT_REGION_SET and is the first listed set otherwise. The
+// boundaries of every set are checked first, failing on findings at least as
+// severe as REGION_VALIDATION_FAIL_ON, errors by default.
+func newRegionServices(ctx context.Context, sets []api.RegionSet) (*regionServices, error) {
+	rs := &regionServices{
+		sets:       sets,
+		services:   make(map[string]*aggregator.Service, len(sets)),
+		defaultSet: os.Getenv("DEFAULT_REGION_SET"),
+		failOn:     coverage.Error,
+	}
+	if rs.defaultSet == "" {
+		rs.defaultSet = sets[0].Id
+	}
+	if value := os.Getenv("REGION_VALIDATION_FAIL_ON"); value != "" {
+		failOn, err := coverage.ParseSeverity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REGION_VALIDATION_FAIL_ON: %w", err)
+		}
+		rs.failOn = failOn
+	}
+	if err := rs.validate(sets); err != nil {
+		return nil, err
+	}
+	rs.sources = aggregator.NewSources()
+	services := make([]*aggregator.Service, 0, len(sets))
+	for _, set := range sets {
+		service, err := aggregator.NewService(ctx, rs.sources, set)
+		if err != nil {
+			return nil, fmt.Errorf("starting service of region set %s: %w", set.Id, err)
+		}
+		rs.services[set.Id] = service
+		services = append(services, service)
+	}
+	if _, exists := rs.services[rs.defaultSet]; !exists {
+		return nil, fmt.Errorf("unknown DEFAULT_REGION_SET: %s", rs.defaultSet)
+	}
+	go aggregator.CollectHistoryLoop(ctx, rs.sources, services...)
+	return rs, nil
+}
+
+func (rs *regionServices) defaultService() *aggregator.Service {
+	return rs.services[rs.defaultSet]
+}
+
+func (rs *regionServices) regionSets() []api.RegionSet {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.sets
+}
+
+// validate checks the boundaries of the region sets and logs the findings.
+func (rs *regionServices) validate(sets []api.RegionSet) error {
+	for _, set := range sets {
+		report := coverage.Check(set, nil)
+		for _, f := range report.Findings {
+			level := slog.LevelInfo
+			if f.Severity.AtLeast(coverage.Warning) {
+				level = slog.LevelWarn
+			}
+			slog.Log(context.Background(), level, "Region boundary finding", "regionSet", set.Id, "severity", f.Severity, "kind", f.Kind, "detail", f.Detail)
+		}
+		if report.Fails(rs.failOn) {
+			return fmt.Errorf("boundaries of region set %s have findings of severity %s or above", set.Id, rs.failOn)
+		}
+	}
+	return nil
+}
+
+// reload loads the region sets file again and swaps the new boundaries in.
+// If the file is invalid or fails validation no set is changed. Region sets
+// can't be added or removed without a restart, so such changes are only
+// logged.
+func (rs *regionServices) reload(path string) error {
+	loaded, err := api.LoadRegionSets(path)
+	if err != nil {
+		return err
+	}
+	if err = rs.validate(loaded); err != nil {
+		return err
+	}
+	byId := make(map[string]api.RegionSet, len(loaded))
+	for _, set := range loaded {
+		if _, exists := rs.services[set.Id]; !exists {
+			slog.Warn("Adding a region set needs a restart", "regionSet", set.Id)
+			continue
+		}
+		byId[set.Id] = set
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	sets := make([]api.RegionSet, len(rs.sets))
+	for i, old := range rs.sets {
+		set, exists := byId[old.Id]
+		if !exists {
+			slog.Warn("Removing a region set needs a restart", "regionSet", old.Id)
+			set = old
+		}
+		if err = rs.services[set.Id].SetRegionSet(set); err != nil {
+			return err
+		}
+		sets[i] = set
+	}
+	rs.sets = sets
+	slog.Info("Reloaded region sets", "path", path)
+	return nil
+}
+
+// inRegionSet serves a request with the handler of the region set named by
+// the set path value.
+func inRegionSet(rs *regionServices, handler func(*aggregator.Service) http.HandlerFunc) http.HandlerFunc {
+	handlers := make(map[string]http.HandlerFunc, len(rs.services))
+	for id, service := range rs.services {
+		handlers[id] = handler(service)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		h, exists := handlers[r.PathValue("set")]
+		if !exists {
+			writeProblem(w, r, problem{Status: http.StatusNotFound, Code: codeNotFound, Detail: "Unknown region set: " + r.PathValue("set")})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func getRegionValidation(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := writeCachedJSON(w, r, service.ValidateRegions(), time.Time{}); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
+
+func getRegionSets(rs *regionServices) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := writeCachedJSON(w, r, rs.regionSets(), time.Time{}); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
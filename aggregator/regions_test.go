@@ -0,0 +1,41 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionSetRoutes(t *testing.T) {
+	sets, err := api.LoadRegionSets(regionSetsPath)
+	assert.NoError(t, err)
+	rs := &regionServices{sets: sets, services: map[string]*aggregator.Service{"pl": nil, "de": nil}, defaultSet: "pl"}
+	served := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/regionSets", getRegionSets(rs))
+	mux.HandleFunc("/regionSets/{set}/aggregatedData", inRegionSet(rs, func(*aggregator.Service) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { served++ }
+	}))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/regionSets", nil))
+	var listed []api.RegionSet
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Equal(t, []string{"pl", "de", "cz"}, []string{listed[0].Id, listed[1].Id, listed[2].Id})
+	assert.Contains(t, listed[1].Regions, api.Region("bayern"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/regionSets/de/aggregatedData", nil))
+	assert.Equal(t, 1, served)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/regionSets/fr/aggregatedData", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown region set: fr")
+	assert.Equal(t, 1, served)
+}
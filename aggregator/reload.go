@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets an edit finish before the files are reloaded, as editors
+// often write a file in several steps.
+const reloadDelay = 500 * time.Millisecond
+
+// watchRegionSets reloads the region sets whenever the region sets file or a
+// boundaries file changes, and on SIGHUP, until ctx is done. A reload that
+// fails keeps the previous boundaries in use.
+func watchRegionSets(ctx context.Context, rs *regionServices, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+		files  map[string]bool
+	)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("Watching region set files failed, send SIGHUP to reload them", "error", err)
+	} else {
+		defer watcher.Close()
+		events, errs = watcher.Events, watcher.Errors
+		files = watchRegionSetFiles(watcher, rs, path)
+	}
+
+	reload := func() {
+		if err := rs.reload(path); err != nil {
+			slog.Error("Reloading region sets failed, keeping the previous boundaries", "error", err)
+			return
+		}
+		if watcher != nil {
+			files = watchRegionSetFiles(watcher, rs, path)
+		}
+	}
+	var delay <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload()
+		case event := <-events:
+			if files[filepath.Clean(event.Name)] {
+				delay = time.After(reloadDelay)
+			}
+		case err := <-errs:
+			slog.Error("Watching region set files failed", "error", err)
+		case <-delay:
+			delay = nil
+			reload()
+		}
+	}
+}
+
+// watchRegionSetFiles watches the directories of the region set files and
+// returns the files. Watching directories rather than the files follows
+// files that editors replace by renaming a new file over them.
+func watchRegionSetFiles(watcher *fsnotify.Watcher, rs *regionServices, path string) map[string]bool {
+	files := map[string]bool{filepath.Clean(path): true}
+	for _, set := range rs.regionSets() {
+		if set.BoundariesFile != "" {
+			files[filepath.Clean(set.BoundariesFile)] = true
+		}
+	}
+	for file := range files {
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			slog.Error("Watching region set files failed", "path", file, "error", err)
+		}
+	}
+	return files
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRegionSets(t *testing.T, dir, bounds string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "regions.json"), []byte(`[{"id": "pl", "boundaries": "pl.json"}]`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "pl.json"), []byte(bounds), 0o644))
+}
+
+func TestReloadRegionSets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "regions.json")
+	writeRegionSets(t, dir, `{"malopolskie": {"minLat": 49, "maxLat": 50.5, "minLon": 19, "maxLon": 21}}`)
+	sets, err := api.LoadRegionSets(path)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	rs, err := newRegionServices(ctx, sets)
+	assert.NoError(t, err)
+	go watchRegionSets(ctx, rs, path)
+	time.Sleep(100 * time.Millisecond)
+
+	writeRegionSets(t, dir, `{"malopolskie": {"minLat": 49, "maxLat": 50.5, "minLon": 19, "maxLon": 21}, "slaskie": {"minLat": 49.4, "maxLat": 51, "minLon": 17.5, "maxLon": 19.5}}`)
+	assert.Eventually(t, func() bool {
+		return len(rs.defaultService().RegionSet().Regions) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, []api.Region{api.Malopolskie, api.Slaskie}, rs.regionSets()[0].Regions)
+
+	writeRegionSets(t, dir, `{"malopolskie": {"minLat": 51, "maxLat": 49}}`)
+	assert.ErrorContains(t, rs.reload(path), "empty bounding box")
+	assert.Len(t, rs.defaultService().RegionSet().Regions, 2)
+}
+
+func TestRegionValidationFailsStartup(t *testing.T) {
+	dir := t.TempDir()
+	writeRegionSets(t, dir, `{"malopolskie": {"minLat": 49, "maxLat": 50.5, "minLon": 19, "maxLon": 21}, "slaskie": {"minLat": 49, "maxLat": 50.5, "minLon": 19.1, "maxLon": 21}}`)
+	sets, err := api.LoadRegionSets(filepath.Join(dir, "regions.json"))
+	assert.NoError(t, err)
+
+	_, err = newRegionServices(t.Context(), sets)
+	assert.EqualError(t, err, "boundaries of region set pl have findings of severity error or above")
+
+	t.Setenv("REGION_VALIDATION_FAIL_ON", "none")
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = newRegionServices(ctx, sets)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"aggregator/internal/regulatory"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+func getExceedanceReports(service *aggregator.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		regions, err := parseRegions(service.RegionSet(), regionsQuery(r.URL.Query()))
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		year, err := parseYear(r.URL.Query().Get("year"), service.RegionSet().Location)
+		if err != nil {
+			writeBadRequest(w, r, err.Error())
+			return
+		}
+		reports, err := service.ExceedanceReports(regions, year)
+		if err != nil {
+			writeError(w, r, err, "Building exceedance reports failed")
+			return
+		}
+		if err = writeCachedJSON(w, r, reports, time.Time{}); err != nil {
+			writeError(w, r, err, "Encoding json response failed")
+			return
+		}
+	}
+}
+
+// parseYear parses a calendar year, defaulting to the current one in loc,
+// the time zone the days of the region set are counted in.
+func parseYear(value string, loc *time.Location) (int, error) {
+	if value == "" {
+		if loc == nil {
+			loc = time.UTC
+		}
+		return time.Now().In(loc).Year(), nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1 {
+		return 0, fmt.Errorf("invalid year: %s", value)
+	}
+	return year, nil
+}
+
+// runReportExceedances counts the days each limit value was exceeded on.
+func runReportExceedances(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("report exceedances", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yearFlag := fs.String("year", "", "calendar year, the current one by default")
+	setFlag := fs.String("set", "", "region set, the first listed one by default")
+	regionsFlag := fs.String("regions", "", "comma separated regions, all by default")
+	fs.StringVar(regionsFlag, "voivodeships", "", "alias of -regions")
+	format := fs.String("format", "text", "output format: text or json")
+	limitsPath := fs.String("limits", "config/limits.json", "limit values file")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	set, err := loadRegionSet(*setFlag)
+	if err != nil {
+		return err
+	}
+	regions, err := parseRegions(set, *regionsFlag)
+	if err != nil {
+		return err
+	}
+	if len(regions) == 0 {
+		regions = set.Regions
+	}
+	year, err := parseYear(*yearFlag, set.Location)
+	if err != nil {
+		return err
+	}
+	limits, err := regulatory.LoadLimits(*limitsPath)
+	if err != nil {
+		return err
+	}
+	daily, err := regulatory.OpenDailyStore(regulatory.DailyStorePath(set.Id))
+	if err != nil {
+		return err
+	}
+	reports := make([]regulatory.ExceedanceReport, 0, len(regions))
+	for _, v := range regions {
+		reports = append(reports, daily.ExceedanceReport(v, year, limits))
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	case "text":
+		return writeExceedanceTable(out, reports)
+	default:
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+}
+
+// loadRegionSet loads the region set with the given id, or the first listed
+// one if id is empty.
+func loadRegionSet(id string) (api.RegionSet, error) {
+	sets, err := api.LoadRegionSets(regionSetsPath)
+	if err != nil {
+		return api.RegionSet{}, err
+	}
+	if id == "" {
+		return sets[0], nil
+	}
+	for _, set := range sets {
+		if set.Id == id {
+			return set, nil
+		}
+	}
+	return api.RegionSet{}, fmt.Errorf("unknown region set: %s", id)
+}
+
+func writeExceedanceTable(out io.Writer, reports []regulatory.ExceedanceReport) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGION\tPARAMETER\tMETRIC\tLIMIT\tDAYS\tALLOWED\tREMAINING\tLONGEST STREAK\tDAYS WITH DATA")
+	for _, report := range reports {
+		for _, e := range report.Limits {
+			allowed, remaining := "-", "-"
+			if e.AllowedDays != nil {
+				allowed = strconv.Itoa(*e.AllowedDays)
+				remaining = strconv.Itoa(*e.RemainingDays)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%d\t%s\t%s\t%d\t%d\n",
+				report.Region, e.Type, e.Metric, e.Limit, e.ExceedanceDays, allowed, remaining, e.LongestStreak, e.DaysWithData)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"aggregator/internal/regulatory"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommandReportExceedances(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATA_DIR", dir)
+	daily, err := regulatory.OpenDailyStore(filepath.Join(dir, "pl", "daily.json"))
+	assert.NoError(t, err)
+	for i, value := range []float32{60, 70, 40} {
+		day := time.Date(2025, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		daily.Record(api.Malopolskie, api.PM10, day, regulatory.DayValues{regulatory.Mean24h: value})
+	}
+	assert.NoError(t, daily.Save())
+
+	var out bytes.Buffer
+	err = runCommand([]string{"report", "exceedances", "-year", "2025", "-regions", "malopolskie", "-format", "json"}, &out)
+	assert.NoError(t, err)
+	var reports []regulatory.ExceedanceReport
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &reports))
+	assert.Len(t, reports, 1)
+	assert.Equal(t, api.PM10, reports[0].Limits[0].Type)
+	assert.Equal(t, 2, reports[0].Limits[0].ExceedanceDays)
+	assert.Equal(t, 2, reports[0].Limits[0].LongestStreak)
+	assert.Equal(t, 33, *reports[0].Limits[0].RemainingDays)
+
+	out.Reset()
+	err = runCommand([]string{"report", "exceedances", "-year", "2025"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "REGION")
+	assert.Regexp(t, `malopolskie\s+PM10\s+24h-mean\s+50\s+2\s+35\s+33\s+2\s+3`, out.String())
+
+	out.Reset()
+	err = runCommand([]string{"report", "exceedances", "-set", "de", "-year", "2025", "-voivodeships", "bayern"}, &out)
+	assert.NoError(t, err)
+	assert.Regexp(t, `bayern\s+PM10\s+24h-mean\s+50\s+0`, out.String())
+	assert.NotContains(t, out.String(), "malopolskie")
+
+	assert.ErrorIs(t, runCommand([]string{"serve"}, &out), errUsage)
+	assert.ErrorContains(t, runCommand([]string{"report", "exceedances", "-set", "fr"}, &out), "unknown region set: fr")
+	assert.ErrorContains(t, runCommand([]string{"report", "exceedances", "-year", "last"}, &out), "invalid year")
+	assert.ErrorContains(t, runCommand([]string{"report", "exceedances", "-format", "xml"}, &out), "unknown format")
+}
+
+func TestParseYearInTimeZone(t *testing.T) {
+	kiritimati := time.FixedZone("LINT", 14*3600)
+	year, err := parseYear("", kiritimati)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().In(kiritimati).Year(), year)
+
+	year, err = parseYear("2024", kiritimati)
+	assert.NoError(t, err)
+	assert.Equal(t, 2024, year)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// selection holds the subset of data requested through query parameters.
+type selection struct {
+	regions []api.Region
+	options aggregator.Options
+	fields  []string
+}
+
+func parseSelection(query url.Values, set api.RegionSet, registry *api.Registry) (selection, error) {
+	var sel selection
+	regions, err := parseRegions(set, regionsQuery(query))
+	if err != nil {
+		return selection{}, err
+	}
+	sel.regions = regions
+	for _, p := range splitQueryList(query.Get("params")) {
+		paramType, err := registry.ParamType(p)
+		if err != nil {
+			return selection{}, err
+		}
+		sel.options.Params = append(sel.options.Params, paramType)
+	}
+	if m := query.Get("merge"); m != "" {
+		strategy, err := api.MapMergeStrategy(m)
+		if err != nil {
+			return selection{}, err
+		}
+		sel.options.Strategy = strategy
+	}
+	hour, err := parseHour(query.Get("hour"))
+	if err != nil {
+		return selection{}, err
+	}
+	sel.options.Hour = hour
+	validFields := api.ParameterFields()
+	for _, f := range splitQueryList(query.Get("fields")) {
+		if !slices.Contains(validFields, f) {
+			return selection{}, fmt.Errorf("unknown field: %s", f)
+		}
+		sel.fields = append(sel.fields, f)
+	}
+	return sel, nil
+}
+
+// regionsQuery returns the regions query parameter, falling back to the
+// voivodeships parameter of the routes that predate region sets.
+func regionsQuery(query url.Values) string {
+	if query.Has("regions") {
+		return query.Get("regions")
+	}
+	return query.Get("voivodeships")
+}
+
+func parseRegions(set api.RegionSet, value string) ([]api.Region, error) {
+	var regions []api.Region
+	for _, v := range splitQueryList(value) {
+		region, err := set.Region(v)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// parseHour parses an RFC 3339 time into the start of its UTC hour. An empty
+// value or "latest" selects the latest hour and yields the zero time.
+func parseHour(value string) (time.Time, error) {
+	if value == "" || value == "latest" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid hour: %s", value)
+	}
+	return t.UTC().Truncate(time.Hour), nil
+}
+
+func splitQueryList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// project removes the parameter fields that were not selected. The parameter
+// type is always kept so that clients can tell the parameters apart.
+func (sel selection) project(v any) (any, error) {
+	if len(sel.fields) == 0 {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var projected any
+	if err = json.Unmarshal(data, &projected); err != nil {
+		return nil, err
+	}
+	switch p := projected.(type) {
+	case []any:
+		for _, item := range p {
+			sel.projectParameters(item)
+		}
+	default:
+		sel.projectParameters(p)
+	}
+	return projected, nil
+}
+
+func (sel selection) projectParameters(data any) {
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return
+	}
+	params, ok := obj["parameters"].([]any)
+	if !ok {
+		return
+	}
+	for _, param := range params {
+		fields, ok := param.(map[string]any)
+		if !ok {
+			continue
+		}
+		for name := range fields {
+			if name != "type" && !slices.Contains(sel.fields, name) {
+				delete(fields, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"aggregator/internal/api"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelection(t *testing.T) {
+	query := url.Values{
+		"voivodeships": {"malopolskie, Slaskie"},
+		"params":       {"PM2_5,no2"},
+		"fields":       {"value,unit"},
+		"merge":        {"station-weighted"},
+		"hour":         {"2025-10-01T14:30:00+02:00"},
+	}
+	registry, err := api.LoadRegistry("config/parameters.json")
+	assert.NoError(t, err)
+	sets, err := api.LoadRegionSets(regionSetsPath)
+	assert.NoError(t, err)
+	pl := sets[0]
+	sel, err := parseSelection(query, pl, registry)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Region{api.Malopolskie, api.Slaskie}, sel.regions)
+	assert.Equal(t, []api.ParamType{api.PM2_5, api.NO2}, sel.options.Params)
+	assert.Equal(t, []string{"value", "unit"}, sel.fields)
+	assert.Equal(t, api.StationWeighted, sel.options.Strategy)
+	assert.Equal(t, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), sel.options.Hour)
+
+	_, err = parseSelection(url.Values{"params": {"PM3"}}, pl, registry)
+	assert.ErrorContains(t, err, "unknown parameter")
+	_, err = parseSelection(url.Values{"voivodeships": {"bayern"}}, pl, registry)
+	assert.ErrorContains(t, err, "unknown region of pl")
+	sel, err = parseSelection(url.Values{"regions": {"Bayern"}}, sets[1], registry)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Region{"bayern"}, sel.regions)
+	_, err = parseSelection(url.Values{"fields": {"colour"}}, pl, registry)
+	assert.ErrorContains(t, err, "unknown field")
+	_, err = parseSelection(url.Values{"merge": {"median"}}, pl, registry)
+	assert.ErrorContains(t, err, "unknown merge strategy")
+	_, err = parseSelection(url.Values{"hour": {"yesterday"}}, pl, registry)
+	assert.ErrorContains(t, err, "invalid hour")
+	sel, err = parseSelection(url.Values{"hour": {"latest"}}, pl, registry)
+	assert.NoError(t, err)
+	assert.True(t, sel.options.Hour.IsZero())
+}
+
+func TestProjectSelection(t *testing.T) {
+	value := float32(20)
+	data := []api.AggregatedData{{
+		Region:     api.Malopolskie,
+		Parameters: []api.Parameter{{Id: 1, Description: "PM10", Unit: "μg/m³", Value: &value, Type: api.PM10, Status: api.Available}},
+		Timestamp:  "2025-10-01T12:00:00Z",
+		Merge:      api.MergeInfo{Strategy: api.Pooled},
+	}}
+
+	projected, err := selection{fields: []string{"value"}}.project(data)
+	assert.NoError(t, err)
+	body, err := json.Marshal(projected)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"voivodeship":"malopolskie","parameters":[{"value":20,"type":"PM10"}],"timestamp":"2025-10-01T12:00:00Z","merge":{"strategy":"pooled"}}]`, string(body))
+
+	unchanged, err := selection{}.project(data)
+	assert.NoError(t, err)
+	assert.Equal(t, data, unchanged)
+}
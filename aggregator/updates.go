@@ -0,0 +1,110 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/api"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultUpdateInterval = 5 * time.Minute
+
+// updateIntervalFromEnv returns how often the update streams poll the
+// aggregated data, set by GRPC_UPDATE_INTERVAL.
+func updateIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("GRPC_UPDATE_INTERVAL")
+	if value == "" {
+		return defaultUpdateInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid GRPC_UPDATE_INTERVAL: %s", value)
+	}
+	return interval, nil
+}
+
+// updateFeed polls the aggregated data of all regions of a region set and
+// passes it on to the subscribed streams, so that streams don't query the
+// sources each. It only polls while there are subscribers. Streams that fall
+// behind only get the latest data.
+type updateFeed struct {
+	service     *aggregator.Service
+	interval    time.Duration
+	mu          sync.Mutex
+	subscribers map[chan []api.AggregatedData]bool
+	latest      []api.AggregatedData
+	stop        context.CancelFunc
+}
+
+func newUpdateFeed(service *aggregator.Service, interval time.Duration) *updateFeed {
+	return &updateFeed{service: service, interval: interval, subscribers: make(map[chan []api.AggregatedData]bool)}
+}
+
+// subscribe returns a channel receiving the data of every poll, starting with
+// the latest one, and a function ending the subscription.
+func (f *updateFeed) subscribe() (<-chan []api.AggregatedData, func()) {
+	ch := make(chan []api.AggregatedData, 1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers[ch] = true
+	if f.stop == nil {
+		var ctx context.Context
+		ctx, f.stop = context.WithCancel(context.Background())
+		go f.run(ctx)
+	} else if f.latest != nil {
+		ch <- f.latest
+	}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, ch)
+		if len(f.subscribers) == 0 && f.stop != nil {
+			f.stop()
+			f.stop, f.latest = nil, nil
+		}
+	}
+}
+
+func (f *updateFeed) run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		f.poll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (f *updateFeed) poll(ctx context.Context) {
+	pollCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	results, err := f.service.AggregateAll(pollCtx, nil, aggregator.Options{})
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, aggregator.ErrNotReady) {
+			slog.Warn("Polling data for update streams failed", "regionSet", f.service.RegionSet().Id, "error", err)
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	f.latest = results
+	for ch := range f.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- results
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"aggregator/internal/aggregator"
+	"aggregator/internal/coverage"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runValidateRegions checks the boundaries of a region set and, with
+// -stations, the assignment of the stations of the sources to its regions.
+func runValidateRegions(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("validate regions", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	setFlag := fs.String("set", "", "region set, the first listed one by default")
+	stationsFlag := fs.Bool("stations", false, "fetch the stations of the sources and check their assignment")
+	failOnFlag := fs.String("fail-on", string(coverage.Error), "severity that fails the validation: info, warning, error or none")
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	failOn, err := coverage.ParseSeverity(*failOnFlag)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+	set, err := loadRegionSet(*setFlag)
+	if err != nil {
+		return err
+	}
+	var stations []coverage.Station
+	if *stationsFlag {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if stations, err = aggregator.FetchCoverageStations(ctx); err != nil {
+			return err
+		}
+	}
+
+	report := coverage.Check(set, stations)
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = writeValidationTable(out, report)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Fails(failOn) {
+		return fmt.Errorf("validation of region set %s failed: findings of severity %s or above", set.Id, failOn)
+	}
+	return nil
+}
+
+func writeValidationTable(out io.Writer, report coverage.Report) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tKIND\tREGIONS\tDETAIL")
+	for _, f := range report.Findings {
+		regions := make([]string, len(f.Regions))
+		for i, r := range f.Regions {
+			regions[i] = string(r)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Kind, orDash(strings.Join(regions, ",")), f.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\n%s: %d errors, %d warnings, %d infos\n",
+		report.RegionSet, report.Counts[coverage.Error], report.Counts[coverage.Warning], report.Counts[coverage.Info])
+	return err
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
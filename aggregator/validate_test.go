@@ -0,0 +1,31 @@
+package main
+
+import (
+	"aggregator/internal/coverage"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommandValidateRegions(t *testing.T) {
+	var out bytes.Buffer
+	err := runCommand([]string{"validate", "regions"}, &out)
+	assert.NoError(t, err)
+	assert.Regexp(t, `warning\s+uncovered_area\s+-\s+No region covers latitude 51-52, longitude 18.5-18.9`, out.String())
+	assert.Contains(t, out.String(), "pl: 0 errors, 7 warnings, 0 infos")
+
+	out.Reset()
+	err = runCommand([]string{"validate", "regions", "-set", "cz", "-format", "json"}, &out)
+	assert.NoError(t, err)
+	var report coverage.Report
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, "cz", report.RegionSet)
+	assert.False(t, report.StationsChecked)
+
+	err = runCommand([]string{"validate", "regions", "-fail-on", "warning"}, &out)
+	assert.EqualError(t, err, "validation of region set pl failed: findings of severity warning or above")
+	assert.ErrorContains(t, runCommand([]string{"validate", "regions", "-fail-on", "fatal"}, &out), "unknown severity")
+	assert.ErrorIs(t, runCommand([]string{"validate"}, &out), errUsage)
+}
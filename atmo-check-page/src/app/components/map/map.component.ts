@@ -28,7 +28,7 @@ export class MapComponent implements OnInit {
     const map = new Map<string, number>();
     for (const item of this.data()) {
       const param = item.parameters.find(p => p.type === this.selectedParam());
-      if (param) map.set(item.voivodeship, param.value);
+      if (param && param.value !== null) map.set(item.voivodeship, param.value);
     }
     return map;
   });
@@ -1,15 +1,33 @@
 export type ParamType = 'PM10' | 'PM2_5' | 'CO' | 'CO2' | 'NO2' | 'SO2' | 'O3' | 'CH4';
 
+export type ParamStatus = 'available' | 'no_data' | 'unsupported';
+
+export type Trend = 'rising' | 'falling' | 'stable';
+
+export interface Change {
+  period: '1h' | '24h' | '7d';
+  previous: number;
+  delta: number;
+  percent: number | null;
+  trend: Trend;
+}
+
 export interface Parameter {
   id: number;
+  name?: string;
   description: string;
   unit: string;
-  value: number;
+  value: number | null;
   type: ParamType;
+  status: ParamStatus;
+  changes?: Change[];
 }
 
 export interface AggregatedData {
   voivodeship: string;
+  voivodeshipName?: string;
   parameters: Parameter[];
+  /** Time of the most recent measurement, empty when there were none. */
   timestamp: string;
+  hour?: string;
 }
@@ -0,0 +1,48 @@
+import { ParamType } from './aggregated-data.model';
+
+export interface Aqi {
+  level: number;
+  name: 'good' | 'fair' | 'moderate' | 'poor' | 'very-poor' | 'extremely-poor';
+  label?: string;
+  score: number;
+  dominant: ParamType;
+}
+
+export interface RankedRegion {
+  rank: number;
+  region: string;
+  name?: string;
+  value: number;
+  aqi: Aqi | null;
+  hour?: string;
+}
+
+export interface Ranking {
+  by: ParamType | 'aqi';
+  unit?: string;
+  order: 'asc' | 'desc';
+  regions: RankedRegion[];
+  unranked: string[];
+}
+
+export interface ComparedValue {
+  region: string;
+  value: number | null;
+  delta: number | null;
+  percent: number | null;
+}
+
+export interface ParamComparison {
+  type: ParamType;
+  unit: string;
+  values: ComparedValue[];
+  lowest?: string;
+  highest?: string;
+  spread: number | null;
+}
+
+export interface Comparison {
+  baseline: string;
+  regions: { region: string; name?: string; aqi: Aqi | null; hour?: string }[];
+  parameters: ParamComparison[];
+}
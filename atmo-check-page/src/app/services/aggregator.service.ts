@@ -1,7 +1,8 @@
 import { Injectable } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
 import { Observable } from 'rxjs';
-import { AggregatedData } from '../models/aggregated-data.model';
+import { AggregatedData, ParamType } from '../models/aggregated-data.model';
+import { Comparison, Ranking } from '../models/ranking.model';
 
 @Injectable({ providedIn: 'root' })
 export class AggregatorService {
@@ -12,4 +13,20 @@ export class AggregatorService {
   getAll(): Observable<AggregatedData[]> {
     return this.http.get<AggregatedData[]>(this.apiUrl);
   }
+
+  getRanking(by: ParamType | 'aqi' = 'aqi', order: 'asc' | 'desc' = 'asc', limit?: number): Observable<Ranking> {
+    const params: Record<string, string> = { by, order };
+    if (limit) {
+      params['limit'] = String(limit);
+    }
+    return this.http.get<Ranking>('/ranking', { params });
+  }
+
+  compare(regions: string[], params: ParamType[] = []): Observable<Comparison> {
+    const query: Record<string, string> = { regions: regions.join(',') };
+    if (params.length > 0) {
+      query['params'] = params.join(',');
+    }
+    return this.http.get<Comparison>('/comparison', { params: query });
+  }
 }
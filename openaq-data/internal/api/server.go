@@ -14,6 +14,9 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Get latest measurements of many stations
+	// (GET /measurements)
+	GetMeasurements(w http.ResponseWriter, r *http.Request, params GetMeasurementsParams)
 	// Get all parameters
 	// (GET /parameters)
 	GetParameters(w http.ResponseWriter, r *http.Request)
@@ -34,6 +37,57 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// GetMeasurements operation middleware
+func (siw *ServerInterfaceWrapper) GetMeasurements(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetMeasurementsParams
+
+	// ------------- Optional query parameter "stationIds" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "stationIds", r.URL.Query(), &params.StationIds)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "stationIds", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "parameterIds" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "parameterIds", r.URL.Query(), &params.ParameterIds)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "parameterIds", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "bbox" -------------
+
+	err = runtime.BindQueryParameter("form", false, false, "bbox", r.URL.Query(), &params.Bbox)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "bbox", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "since" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "since", r.URL.Query(), &params.Since)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "since", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetMeasurements(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // GetParameters operation middleware
 func (siw *ServerInterfaceWrapper) GetParameters(w http.ResponseWriter, r *http.Request) {
 
@@ -207,6 +261,7 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	m.HandleFunc("GET "+options.BaseURL+"/measurements", wrapper.GetMeasurements)
 	m.HandleFunc("GET "+options.BaseURL+"/parameters", wrapper.GetParameters)
 	m.HandleFunc("GET "+options.BaseURL+"/stations", wrapper.GetStations)
 	m.HandleFunc("GET "+options.BaseURL+"/stations/{id}/measurements", wrapper.GetMeasurementsByStation)
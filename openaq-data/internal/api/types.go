@@ -34,3 +34,18 @@ type Station struct {
 	ParameterIds []int32 `json:"parameterIds"`
 	Timezone     string  `json:"timezone"`
 }
+
+// GetMeasurementsParams defines parameters for GetMeasurements.
+type GetMeasurementsParams struct {
+	// StationIds Comma separated station IDs
+	StationIds *[]int32 `form:"stationIds,omitempty" json:"stationIds,omitempty"`
+
+	// ParameterIds Comma separated parameter IDs
+	ParameterIds *[]int32 `form:"parameterIds,omitempty" json:"parameterIds,omitempty"`
+
+	// Bbox Bounding box of the station coordinates as minLongitude,minLatitude,maxLongitude,maxLatitude
+	Bbox *[]float64 `form:"bbox,omitempty" json:"bbox,omitempty"`
+
+	// Since Leaves out measurements taken before this time, 24 hours before the request by default
+	Since *time.Time `form:"since,omitempty" json:"since,omitempty"`
+}
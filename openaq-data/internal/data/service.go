@@ -1,6 +1,7 @@
 package data
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"openaq-data/internal/models"
 	"openaq-data/internal/store"
 	"openaq-data/internal/util"
+	"slices"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -17,6 +20,11 @@ var (
 	ErrLocationNotFound = errors.New("location not found")
 )
 
+// defaultMeasurementsWindow is how far back measurements are returned when
+// the request doesn't say, so that sensors that stopped reporting long ago
+// are left out.
+const defaultMeasurementsWindow = 24 * time.Hour
+
 type Service struct {
 	store  store.Storer
 	logger *zap.SugaredLogger
@@ -99,6 +107,76 @@ func (s *Service) MeasurementsForStation(
 	return s.buildMeasurements(measurements, *loc), nil
 }
 
+// Measurements returns the measurements of the stations selected by ID and
+// bounding box, narrowed to the given parameters and to those taken since the
+// given time, or within the default window. Stations are selected from the
+// stored locations, so that the measurements are fetched in one query.
+func (s *Service) Measurements(ctx context.Context, params api.GetMeasurementsParams) ([]api.Measurement, error) {
+	locations, err := s.store.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int32]models.Location)
+	filter := store.MeasurementFilter{Since: time.Now().Add(-defaultMeasurementsWindow)}
+	if params.Since != nil {
+		filter.Since = *params.Since
+	}
+	for _, loc := range locations {
+		if !locationSelected(loc, params) {
+			continue
+		}
+		selected[loc.Id] = loc
+		filter.LocationIds = append(filter.LocationIds, loc.Id)
+		if params.ParameterIds == nil {
+			continue
+		}
+		for _, sensor := range loc.Sensors {
+			if slices.Contains(*params.ParameterIds, sensor.Parameter.Id) {
+				filter.SensorIds = append(filter.SensorIds, sensor.Id)
+			}
+		}
+	}
+	if len(selected) == 0 || (params.ParameterIds != nil && len(filter.SensorIds) == 0) {
+		return []api.Measurement{}, nil
+	}
+
+	measurements, err := s.store.GetMeasurements(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	apiMeasurements := []api.Measurement{}
+	for locId, group := range groupByLocation(measurements) {
+		apiMeasurements = append(apiMeasurements, s.buildMeasurements(group, selected[locId])...)
+	}
+	slices.SortStableFunc(apiMeasurements, func(a, b api.Measurement) int {
+		return cmp.Compare(a.StationId, b.StationId)
+	})
+	return apiMeasurements, nil
+}
+
+func locationSelected(loc models.Location, params api.GetMeasurementsParams) bool {
+	if params.StationIds != nil && !slices.Contains(*params.StationIds, loc.Id) {
+		return false
+	}
+	if params.Bbox != nil {
+		bbox := *params.Bbox
+		lon, lat := loc.Coordinates.Longitude, loc.Coordinates.Latitude
+		if lon < bbox[0] || lat < bbox[1] || lon > bbox[2] || lat > bbox[3] {
+			return false
+		}
+	}
+	return true
+}
+
+func groupByLocation(measurements []models.Measurement) map[int32][]models.Measurement {
+	grouped := make(map[int32][]models.Measurement)
+	for _, m := range measurements {
+		grouped[m.LocationId] = append(grouped[m.LocationId], m)
+	}
+	return grouped
+}
+
 func (s *Service) buildMeasurements(
 	measurements []models.Measurement,
 	loc models.Location,
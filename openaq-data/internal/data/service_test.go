@@ -351,3 +351,128 @@ func TestMeasurements(t *testing.T) {
 		})
 	}
 }
+
+func TestBatchMeasurements(t *testing.T) {
+	secondLocation := func() models.Location {
+		loc := initModelLocation
+		loc.Id = 2
+		loc.Coordinates.Latitude = 50.0
+		loc.Coordinates.Longitude = 19.0
+		loc.Sensors = []models.Sensor{{Id: 3, Parameter: models.Parameter{Id: 100}}}
+		return loc
+	}()
+	secondMeasurement := func() models.Measurement {
+		m := initModelMeasurement
+		m.SensorId = 3
+		m.LocationId = 2
+		return m
+	}()
+	secondApiMeasurement := func() api.Measurement {
+		am := initApiMeasurement
+		am.StationId = 2
+		return am
+	}()
+	parameterMeasurement := func() models.Measurement {
+		m := initModelMeasurement
+		m.SensorId = 2
+		return m
+	}()
+	parameterApiMeasurement := func() api.Measurement {
+		am := initApiMeasurement
+		am.ParameterId = 200
+		return am
+	}()
+
+	tests := []struct {
+		name                string
+		giveParams          api.GetMeasurementsParams
+		giveDefaultSince    bool
+		giveMeasurementsErr error
+		wantMeasurements    []api.Measurement
+		wantErr             error
+	}{
+		{
+			name:       "Stations by id",
+			giveParams: api.GetMeasurementsParams{StationIds: &[]int32{2, 1}},
+			wantMeasurements: []api.Measurement{
+				initApiMeasurement,
+				parameterApiMeasurement,
+				secondApiMeasurement,
+			},
+		},
+		{
+			name:             "Stations by bounding box",
+			giveParams:       api.GetMeasurementsParams{Bbox: &[]float64{14, 49, 24, 55}},
+			wantMeasurements: []api.Measurement{secondApiMeasurement},
+		},
+		{
+			name: "Stations by id and parameter",
+			giveParams: api.GetMeasurementsParams{
+				StationIds:   &[]int32{1, 2},
+				ParameterIds: &[]int32{200},
+			},
+			wantMeasurements: []api.Measurement{parameterApiMeasurement},
+		},
+		{
+			name:             "Unknown station",
+			giveParams:       api.GetMeasurementsParams{StationIds: &[]int32{999}},
+			wantMeasurements: []api.Measurement{},
+		},
+		{
+			name: "Parameter not measured by the stations",
+			giveParams: api.GetMeasurementsParams{
+				StationIds:   &[]int32{2},
+				ParameterIds: &[]int32{200},
+			},
+			wantMeasurements: []api.Measurement{},
+		},
+		{
+			name:             "Measurements before the default window",
+			giveParams:       api.GetMeasurementsParams{StationIds: &[]int32{1}},
+			giveDefaultSince: true,
+			wantMeasurements: []api.Measurement{},
+		},
+		{
+			name: "Measurements at since",
+			giveParams: api.GetMeasurementsParams{
+				StationIds: &[]int32{1},
+				Since:      &initApiMeasurement.Timestamp,
+			},
+			wantMeasurements: []api.Measurement{initApiMeasurement, parameterApiMeasurement},
+		},
+		{
+			name: "Measurements before since",
+			giveParams: api.GetMeasurementsParams{
+				StationIds: &[]int32{1},
+				Since:      func() *time.Time { t := initApiMeasurement.Timestamp.Add(time.Second); return &t }(),
+			},
+			wantMeasurements: []api.Measurement{},
+		},
+		{
+			name:                "GetMeasurements error is propagated",
+			giveParams:          api.GetMeasurementsParams{StationIds: &[]int32{1}},
+			giveMeasurementsErr: errStore,
+			wantMeasurements:    nil,
+			wantErr:             errStore,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := mock.Store{
+				Locations:          []models.Location{initModelLocation, secondLocation},
+				Measurements:       []models.Measurement{initModelMeasurement, secondMeasurement, parameterMeasurement},
+				GetMeasurementsErr: test.giveMeasurementsErr,
+			}
+			s := NewService(&db, zap.NewNop().Sugar())
+			// The measurements are far older than the default window.
+			if test.giveParams.Since == nil && !test.giveDefaultSince {
+				since := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+				test.giveParams.Since = &since
+			}
+
+			measurements, err := s.Measurements(t.Context(), test.giveParams)
+			assert.Equal(t, test.wantErr, err)
+			assert.Equal(t, test.wantMeasurements, measurements)
+		})
+	}
+}
@@ -3,13 +3,18 @@ package apiclient
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"openaq-data/internal/models"
+	"os"
+	"strings"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"resty.dev/v3"
 )
 
 const (
-	baseURL = "https://api.openaq.org/v3/"
+	baseURL          = "https://api.openaq.org/v3/"
+	defaultCountries = "PL"
 )
 
 var (
@@ -17,36 +22,69 @@ var (
 )
 
 type Service struct {
-	client *resty.Client
+	client    *resty.Client
+	countries []string
 }
 
+// New creates a client fetching the locations of the countries listed in
+// OPENAQ_COUNTRIES as comma separated ISO 3166-1 alpha-2 codes, Poland by
+// default.
 func New(apiKey string) (*Service, error) {
 	client, err := buildClient(apiKey)
 	if err != nil {
 		return nil, err
 	}
+	countries := os.Getenv("OPENAQ_COUNTRIES")
+	if countries == "" {
+		countries = defaultCountries
+	}
 	return &Service{
-		client: client,
+		client:    client,
+		countries: parseCountries(countries),
 	}, nil
 }
 
+func parseCountries(value string) []string {
+	var countries []string
+	for _, c := range strings.Split(value, ",") {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+			countries = append(countries, c)
+		}
+	}
+	return countries
+}
+
 func buildClient(apiKey string) (*resty.Client, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
 	}
 	return resty.New().
+			SetTransport(otelhttp.NewTransport(http.DefaultTransport)).
 			SetHeader("Accept", "application/json").
 			SetHeader("X-API-Key", apiKey),
 		nil
 }
 
+// FetchLocations fetches the locations of all configured countries.
 func (s *Service) FetchLocations(ctx context.Context) ([]models.Location, error) {
+	var locations []models.Location
+	for _, country := range s.countries {
+		countryLocations, err := s.fetchCountryLocations(ctx, country)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, countryLocations...)
+	}
+	return locations, nil
+}
+
+func (s *Service) fetchCountryLocations(ctx context.Context, country string) ([]models.Location, error) {
 	var locationResponse struct {
 		Results []models.Location `json:"results"`
 	}
 
 	queryParams := map[string]string{
-		"iso":   "PL",
+		"iso":   country,
 		"limit": "1000",
 	}
 	resp, err := s.request(
@@ -57,10 +95,10 @@ func (s *Service) FetchLocations(ctx context.Context) ([]models.Location, error)
 		queryParams,
 	).Send()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch locations: %w", err)
+		return nil, fmt.Errorf("failed to fetch locations of %s: %w", country, err)
 	}
 	if resp.IsError() {
-		return nil, fmt.Errorf("API error: %s", resp.Status())
+		return nil, fmt.Errorf("API error for locations of %s: %s", country, resp.Status())
 	}
 
 	return locationResponse.Results, nil
@@ -4,6 +4,8 @@ import (
 	"context"
 	"openaq-data/internal/models"
 	"openaq-data/internal/store"
+	"openaq-data/internal/util"
+	"slices"
 )
 
 type Store struct {
@@ -14,6 +16,7 @@ type Store struct {
 	GetLocationsErr              error
 	GetLocationByIDErr           error
 	GetMeasurementsByLocationErr error
+	GetMeasurementsErr           error
 	GetParametersErr             error
 }
 
@@ -78,6 +81,26 @@ func (s *Store) GetMeasurementsByLocation(_ context.Context, locationId int32) (
 	return result, nil
 }
 
+func (s *Store) GetMeasurements(_ context.Context, filter store.MeasurementFilter) ([]models.Measurement, error) {
+	if s.GetMeasurementsErr != nil {
+		return nil, s.GetMeasurementsErr
+	}
+	var result []models.Measurement
+	for _, m := range s.Measurements {
+		if len(filter.LocationIds) > 0 && !slices.Contains(filter.LocationIds, m.LocationId) {
+			continue
+		}
+		if len(filter.SensorIds) > 0 && !slices.Contains(filter.SensorIds, m.SensorId) {
+			continue
+		}
+		if t, err := util.StringToTime(m.Date.Utc); err == nil && t.Before(filter.Since) {
+			continue
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
 func (s *Store) StoreParameters(_ context.Context, parameters []models.Parameter) error {
 	s.Parameters = parameters
 	return nil
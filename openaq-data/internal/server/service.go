@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"openaq-data/internal"
 	"openaq-data/internal/api"
@@ -51,6 +52,36 @@ func (s *Service) GetMeasurementsByStation(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, measurements)
 }
 
+func (s *Service) GetMeasurements(w http.ResponseWriter, r *http.Request, params api.GetMeasurementsParams) {
+	if err := validateMeasurementsParams(params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	measurements, err := s.dataService.Measurements(r.Context(), params)
+	if err != nil {
+		s.logger.Errorw("Failed to fetch measurements", "error", err)
+		http.Error(w, "Failed to fetch measurements", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, measurements)
+}
+
+func validateMeasurementsParams(params api.GetMeasurementsParams) error {
+	if params.StationIds == nil && params.Bbox == nil {
+		return errors.New("stationIds or bbox is required")
+	}
+	if params.Bbox != nil {
+		bbox := *params.Bbox
+		if len(bbox) != 4 {
+			return errors.New("bbox must have 4 values: minLongitude,minLatitude,maxLongitude,maxLatitude")
+		}
+		if bbox[0] > bbox[2] || bbox[1] > bbox[3] {
+			return errors.New("bbox minimum must not exceed its maximum")
+		}
+	}
+	return nil
+}
+
 func writeJSON(rw http.ResponseWriter, status int, v any) error {
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(status)
@@ -13,4 +13,5 @@ type DataService interface {
 	Stations(ctx context.Context) ([]api.Station, error)
 	Parameters(ctx context.Context) ([]api.Parameter, error)
 	MeasurementsForStation(ctx context.Context, stationID int32) ([]api.Measurement, error)
+	Measurements(ctx context.Context, params api.GetMeasurementsParams) ([]api.Measurement, error)
 }
@@ -19,6 +19,7 @@ type Storer interface {
 
 	StoreMeasurements(ctx context.Context, m []models.Measurement) error
 	GetMeasurementsByLocation(ctx context.Context, locationId int32) ([]models.Measurement, error)
+	GetMeasurements(ctx context.Context, filter MeasurementFilter) ([]models.Measurement, error)
 	DeleteMeasurementsForLocation(ctx context.Context, locationID int32) error
 
 	StoreParameters(ctx context.Context, parameters []models.Parameter) error
@@ -67,7 +68,10 @@ func configureClient(ctx context.Context, mongoURI string) (*mongo.Client, error
 	return mongoClient, err
 }
 
-func (s *Store) StoreLocations(ctx context.Context, locations []models.Location) error {
+func (s *Store) StoreLocations(ctx context.Context, locations []models.Location) (err error) {
+	ctx, end := startOperation(ctx, "storeLocations", s.locationsColl)
+	defer func() { end(err) }()
+
 	for _, l := range locations {
 		if err := s.storeLocation(ctx, l); err != nil {
 			return err
@@ -88,7 +92,10 @@ func (s *Store) storeLocation(ctx context.Context, location models.Location) err
 	return nil
 }
 
-func (s *Store) GetLocations(ctx context.Context) ([]models.Location, error) {
+func (s *Store) GetLocations(ctx context.Context) (_ []models.Location, err error) {
+	ctx, end := startOperation(ctx, "getLocations", s.locationsColl)
+	defer func() { end(err) }()
+
 	cursor, err := s.locationsColl.Find(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch locations: %w", err)
@@ -102,9 +109,12 @@ func (s *Store) GetLocations(ctx context.Context) ([]models.Location, error) {
 	return locations, nil
 }
 
-func (s *Store) GetLocationByID(ctx context.Context, id int32) (*models.Location, error) {
+func (s *Store) GetLocationByID(ctx context.Context, id int32) (_ *models.Location, err error) {
+	ctx, end := startOperation(ctx, "getLocationByID", s.locationsColl)
+	defer func() { end(err) }()
+
 	var loc models.Location
-	err := s.locationsColl.FindOne(ctx, bson.M{"_id": id}).Decode(&loc)
+	err = s.locationsColl.FindOne(ctx, bson.M{"_id": id}).Decode(&loc)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
@@ -114,7 +124,10 @@ func (s *Store) GetLocationByID(ctx context.Context, id int32) (*models.Location
 	return &loc, nil
 }
 
-func (s *Store) StoreMeasurements(ctx context.Context, m []models.Measurement) error {
+func (s *Store) StoreMeasurements(ctx context.Context, m []models.Measurement) (err error) {
+	ctx, end := startOperation(ctx, "storeMeasurements", s.measuresColl)
+	defer func() { end(err) }()
+
 	for _, measure := range m {
 		_, err := s.measuresColl.InsertOne(ctx, measure)
 		if err != nil {
@@ -124,7 +137,10 @@ func (s *Store) StoreMeasurements(ctx context.Context, m []models.Measurement) e
 	return nil
 }
 
-func (s *Store) GetMeasurementsByLocation(ctx context.Context, locationId int32) ([]models.Measurement, error) {
+func (s *Store) GetMeasurementsByLocation(ctx context.Context, locationId int32) (_ []models.Measurement, err error) {
+	ctx, end := startOperation(ctx, "getMeasurementsByLocation", s.measuresColl)
+	defer func() { end(err) }()
+
 	filter := bson.M{"locationsId": locationId}
 	opts := options.Find().SetSort(
 		bson.D{{
@@ -145,15 +161,65 @@ func (s *Store) GetMeasurementsByLocation(ctx context.Context, locationId int32)
 	return measurements, nil
 }
 
-func (s *Store) DeleteMeasurementsForLocation(ctx context.Context, locationID int32) error {
-	_, err := s.measuresColl.DeleteMany(ctx, bson.M{"locationsId": locationID})
+// MeasurementFilter selects measurements by location, sensor and time. An
+// empty list matches any value, as does a zero Since.
+type MeasurementFilter struct {
+	LocationIds []int32
+	SensorIds   []int32
+	// Since leaves out the measurements taken before it.
+	Since time.Time
+}
+
+// GetMeasurements returns the measurements matching the filter in a single
+// query, ordered by location and newest first.
+func (s *Store) GetMeasurements(ctx context.Context, filter MeasurementFilter) (_ []models.Measurement, err error) {
+	ctx, end := startOperation(ctx, "getMeasurements", s.measuresColl)
+	defer func() { end(err) }()
+
+	query := bson.M{}
+	if len(filter.LocationIds) > 0 {
+		query["locationsId"] = bson.M{"$in": filter.LocationIds}
+	}
+	if len(filter.SensorIds) > 0 {
+		query["sensorsId"] = bson.M{"$in": filter.SensorIds}
+	}
+	if !filter.Since.IsZero() {
+		// The times are stored as RFC 3339 strings in UTC, which sort in
+		// time order.
+		query["datetime.utc"] = bson.M{"$gte": filter.Since.UTC().Format(time.RFC3339)}
+	}
+	opts := options.Find().SetSort(bson.D{
+		{Key: "locationsId", Value: 1},
+		{Key: "datetime.utc", Value: -1},
+	})
+	cursor, err := s.measuresColl.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch measurements: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var measurements []models.Measurement
+	if err := cursor.All(ctx, &measurements); err != nil {
+		return nil, fmt.Errorf("failed to decode measurements: %w", err)
+	}
+	return measurements, nil
+}
+
+func (s *Store) DeleteMeasurementsForLocation(ctx context.Context, locationID int32) (err error) {
+	ctx, end := startOperation(ctx, "deleteMeasurementsForLocation", s.measuresColl)
+	defer func() { end(err) }()
+
+	_, err = s.measuresColl.DeleteMany(ctx, bson.M{"locationsId": locationID})
 	if err != nil {
 		return fmt.Errorf("failed to delete measurements for location: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) GetParameters(ctx context.Context) ([]models.Parameter, error) {
+func (s *Store) GetParameters(ctx context.Context) (_ []models.Parameter, err error) {
+	ctx, end := startOperation(ctx, "getParameters", s.parametersColl)
+	defer func() { end(err) }()
+
 	cursor, err := s.parametersColl.Find(ctx, bson.M{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch parameters: %w", err)
@@ -167,7 +233,10 @@ func (s *Store) GetParameters(ctx context.Context) ([]models.Parameter, error) {
 	return parameters, nil
 }
 
-func (s *Store) StoreParameters(ctx context.Context, parameters []models.Parameter) error {
+func (s *Store) StoreParameters(ctx context.Context, parameters []models.Parameter) (err error) {
+	ctx, end := startOperation(ctx, "storeParameters", s.parametersColl)
+	defer func() { end(err) }()
+
 	for _, parameter := range parameters {
 		if err := s.storeParameter(ctx, parameter); err != nil {
 			return err
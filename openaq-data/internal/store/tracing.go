@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("openaq-data/internal/store")
+
+// startOperation starts a client span for an operation on the collection.
+// The returned function ends the span and records err on it when set.
+func startOperation(ctx context.Context, operation string, coll *mongo.Collection) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, "mongodb."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "mongodb"),
+			attribute.String("db.collection.name", coll.Name()),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,69 @@
+// Package telemetry exports the traces of the fetcher and the store to an
+// OpenTelemetry collector, or to stdout when testing offline.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+const serviceName = "openaq-data"
+
+// Setup installs the global tracer provider. The exporter is chosen by
+// OTEL_TRACES_EXPORTER: "otlp" sends spans to the collector set by the
+// standard OTEL_EXPORTER_OTLP_* variables, "stdout" (or "console") prints them
+// when testing offline and "none" drops them. Without the variable spans are
+// sent over OTLP only when OTEL_EXPORTER_OTLP_ENDPOINT is set. The returned
+// function flushes the pending spans.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	name := os.Getenv("OTEL_TRACES_EXPORTER")
+	if name == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		name = "otlp"
+	}
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+		}
+		return exporter, nil
+	case "stdout", "console":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER: %s", name)
+	}
+}
@@ -14,8 +14,10 @@ import (
 	"openaq-data/internal/fetcher"
 	"openaq-data/internal/server"
 	"openaq-data/internal/store"
+	"openaq-data/internal/telemetry"
 
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -63,6 +65,12 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGILL)
 	defer cancel()
 
+	shutdownTracing, err := telemetry.Setup(ctx)
+	if err != nil {
+		l.Errorw("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
 	go func() {
 		if err := f.Run(ctx); err != nil {
 			l.Errorw("fetcher service stopped with error", "error", err)
@@ -74,8 +82,10 @@ func main() {
 	dataService := data.NewService(db, l)
 	srv := server.New(dataService, l)
 	httpServer := &http.Server{
-		Addr:    listenAddr,
-		Handler: api.Handler(srv),
+		Addr: listenAddr,
+		Handler: api.HandlerWithOptions(srv, api.StdHTTPServerOptions{
+			Middlewares: []api.MiddlewareFunc{tracingMiddleware},
+		}),
 	}
 	go func() {
 		l.Infow("Starting server", "addr", listenAddr)
@@ -95,4 +105,16 @@ func main() {
 	if err := db.Close(); err != nil {
 		l.Errorw("Failed to close data service", "error", err)
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		l.Errorw("Failed to flush traces", "error", err)
+	}
+}
+
+// tracingMiddleware starts a server span for every request. It runs after
+// routing, so spans are named after the matched route.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Pattern
+	}))
 }